@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DNSRecordFinalizer makes sure the record is removed from the cluster zone
+	// before the DNSRecord is deleted.
+	DNSRecordFinalizer = "dns.giantswarm.io/dnsrecord"
+
+	// ReadyCondition reports whether the record exists in the cluster zone.
+	ReadyCondition = "Ready"
+
+	// RecordReconciledReason is used when the record exists in the cluster zone.
+	RecordReconciledReason = "RecordReconciled"
+	// ClusterNotFoundReason is used when the referenced cluster does not exist.
+	ClusterNotFoundReason = "ClusterNotFound"
+	// ClusterNotReadyReason is used when the referenced cluster is not provisioned yet.
+	ClusterNotReadyReason = "ClusterNotReady"
+	// ClusterDeletingReason is used when the referenced cluster is being deleted.
+	ClusterDeletingReason = "ClusterDeleting"
+	// RecordConflictReason is used when the record collides with a record managed by the operator.
+	RecordConflictReason = "RecordConflict"
+	// RecordNotOwnedReason is used when the record is owned by someone else, e.g. external-dns.
+	RecordNotOwnedReason = "RecordNotOwned"
+	// DryRunReason is used when the changes of the record are planned but not applied in dry-run mode.
+	DryRunReason = "DryRun"
+	// ReconciliationFailedReason is used when the DNS provider failed to apply the record.
+	ReconciliationFailedReason = "ReconciliationFailed"
+)
+
+// DNSRecordSpec defines the desired state of DNSRecord
+// +kubebuilder:validation:XValidation:rule="self.name != '@' || !(self.type in ['CNAME', 'NS'])",message="CNAME and NS records can't be created at the cluster domain itself"
+type DNSRecordSpec struct {
+	// Name of the record relative to the cluster domain, e.g. "vault" for
+	// vault.<cluster>.<base domain>. Use "@" for the cluster domain itself.
+	// +kubebuilder:validation:Pattern=`^(@|\*|(\*\.)?[a-z0-9_]([-a-z0-9_]*[a-z0-9])?(\.[a-z0-9_]([-a-z0-9_]*[a-z0-9])?)*)$`
+	Name string `json:"name"`
+
+	// Type of the record.
+	// +kubebuilder:validation:Enum=A;AAAA;CAA;CNAME;MX;NS;SRV;TXT
+	Type string `json:"type"`
+
+	// TTL of the record in seconds.
+	// +kubebuilder:default=300
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTL int64 `json:"ttl,omitempty"`
+
+	// Values of the record in zone file presentation format, e.g.
+	// `10 mail.example.com.` for MX or `"v=spf1 -all"` for TXT records.
+	// +kubebuilder:validation:MinItems=1
+	Values []string `json:"values"`
+
+	// ClusterRef references the CAPI Cluster in the same namespace whose zone contains the record.
+	ClusterRef corev1.LocalObjectReference `json:"clusterRef"`
+}
+
+// DNSRecordStatus defines the observed state of DNSRecord
+type DNSRecordStatus struct {
+	// FQDN is the fully qualified name of the record which was applied to the cluster zone.
+	// +optional
+	FQDN string `json:"fqdn,omitempty"`
+
+	// Type is the record type which was applied to the cluster zone.
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// ObservedGeneration is the latest generation observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions defines the current state of the DNSRecord.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterRef.name"
+// +kubebuilder:printcolumn:name="FQDN",type="string",JSONPath=".status.fqdn"
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// DNSRecord is the Schema for the dnsrecords API
+type DNSRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DNSRecordSpec   `json:"spec,omitempty"`
+	Status DNSRecordStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DNSRecordList contains a list of DNSRecord
+type DNSRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DNSRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DNSRecord{}, &DNSRecordList{})
+}
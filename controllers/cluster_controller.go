@@ -17,13 +17,23 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/metrics"
 	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/rfc2136"
 	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/route53"
 	"github.com/giantswarm/dns-operator-route53/pkg/key"
+	"github.com/giantswarm/dns-operator-route53/pkg/remote"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	"sigs.k8s.io/cluster-api/controllers/external"
 	"sigs.k8s.io/cluster-api/util"
@@ -31,20 +41,57 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/giantswarm/microerror"
 )
 
+const (
+	// resyncPeriod is the interval in which provisioned clusters are reconciled
+	// again, e.g. to pick up changes of the infrastructure cluster.
+	resyncPeriod = 10 * time.Minute
+	// propagationPollPeriod is the interval in which the status of pending DNS
+	// changes is polled.
+	propagationPollPeriod = 10 * time.Second
+)
+
 // ClusterReconciler reconciles a Cluster object
 type ClusterReconciler struct {
 	client.Client
 
-	BaseDomain        string
-	ManagementCluster string
-	RoleArn           string
-	StaticBastionIP   string
+	// AllowedClusterRoles are the role ARNs and AWS account IDs which clusters
+	// can select with the dns.giantswarm.io/aws-role-arn annotation.
+	AllowedClusterRoles []string
+	AWSCredentials      scope.AWSCredentials
+	// BaseDomains are the base domains which clusters can select with the
+	// base domain annotation. The first one is used for all other clusters.
+	BaseDomains    []string
+	ClusterTracker *remote.ClusterTracker
+	DNSProvider    string
+	// DryRun only plans the DNS changes of all clusters instead of applying them.
+	DryRun              bool
+	InfrastructureKinds InfrastructureKinds
+	ManagementCluster   string
+	// NamingTemplates render the cluster domain and the record names of all clusters.
+	NamingTemplates cloud.NamingTemplates
+	// Recorder emits events on the cluster, e.g. when records are owned by someone else.
+	Recorder events.EventRecorder
+	// RecordTTLs are the TTLs of the records of clusters without the TTL annotations.
+	RecordTTLs cloud.RecordTTLs
+	RFC2136    rfc2136.Config
+	RoleArn    string
+	// ServiceDiscovery selects the ingress and gateway Services of clusters
+	// without the Service discovery annotations.
+	ServiceDiscovery cloud.ServiceDiscovery
+	StaticBastionIP  string
+	// WildcardIngressService is the ingress service which backs the wildcard
+	// record of clusters without the dns.giantswarm.io/wildcard-ingress-service annotation.
+	WildcardIngressService string
+	// WaitForChangePropagation tracks the submitted DNS changes until they are
+	// served by all name servers and reports them with the DNSPropagated condition.
+	WaitForChangePropagation bool
 }
 
 func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -52,17 +99,18 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	log.WithValues("cluster", req.NamespacedName)
 
 	cluster, err := util.GetClusterByName(ctx, r, req.Namespace, req.Name)
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return reconcile.Result{}, nil
-		}
+	if apierrors.IsNotFound(err) {
+		r.ClusterTracker.Stop(req.NamespacedName)
+		return reconcile.Result{}, nil
+	} else if err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
 	}
 
 	// get the InfrastructureRef (v1.ObjectReference) from the CAPI cluster
 	infraRef := cluster.Spec.InfrastructureRef
 
 	// Return early if the infrastructure cluster type is not enabled
-	if !r.enabledInfrastructureProvider(infraRef.Kind) {
+	if !r.InfrastructureKinds.IsEnabled(infraRef.Kind) {
 		log.Info(fmt.Sprintf("Infrastructure provider %s is not enabled", infraRef.Kind))
 		return reconcile.Result{}, nil
 	}
@@ -81,16 +129,69 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
+	// Leave the zone and records of opted out clusters to other DNS tooling.
+	if dnsDisabled(cluster) {
+		log.Info("DNS management is disabled for the cluster. Won't reconcile")
+		r.ClusterTracker.Stop(client.ObjectKeyFromObject(cluster))
+		return r.releaseFinalizers(ctx, cluster, infraCluster)
+	}
+
 	// Create the cluster scope.
+	clusterRole, err := resolveClusterRole(ctx, r, r.DNSProvider, cluster, r.AllowedClusterRoles)
+	if err != nil {
+		if cluster.DeletionTimestamp.IsZero() {
+			if patchErr := r.patchDNSReadyCondition(ctx, cluster, err); patchErr != nil {
+				log.Error(patchErr, "error patching DNSReady condition")
+			}
+		}
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	// Unlike the other annotations, an invalid base domain does not fall back
+	// to the default during deletion, as that would delete the records of
+	// another zone.
+	baseDomain, err := scope.ResolveBaseDomain(ctx, r, cluster, r.BaseDomains)
+	if err != nil {
+		if cluster.DeletionTimestamp.IsZero() {
+			if patchErr := r.patchDNSReadyCondition(ctx, cluster, err); patchErr != nil {
+				log.Error(patchErr, "error patching DNSReady condition")
+			}
+		}
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	serviceDiscovery, recordTTLs, err := r.resolveClusterConfig(cluster)
+	if err != nil && cluster.DeletionTimestamp.IsZero() {
+		if patchErr := r.patchDNSReadyCondition(ctx, cluster, err); patchErr != nil {
+			log.Error(patchErr, "error patching DNSReady condition")
+		}
+		return reconcile.Result{}, microerror.Mask(err)
+	} else if err != nil {
+		// Invalid annotations must not block the deletion of the records.
+		serviceDiscovery, recordTTLs = r.ServiceDiscovery, r.RecordTTLs
+	}
+
 	clusterScope, err := scope.NewClusterScope(ctx, scope.ClusterScopeParams{
-		BaseDomain:            r.BaseDomain,
-		Cluster:               cluster,
-		InfrastructureCluster: infraCluster,
-		ManagementCluster:     r.ManagementCluster,
-		RoleArn:               r.RoleArn,
-		StaticBastionIP:       r.StaticBastionIP,
+		BaseDomain:             baseDomain,
+		Cluster:                cluster,
+		ClusterClients:         r.ClusterTracker,
+		ClusterRole:            clusterRole,
+		Credentials:            r.AWSCredentials,
+		InfrastructureCluster:  infraCluster,
+		ManagementCluster:      r.ManagementCluster,
+		NamingTemplates:        r.NamingTemplates,
+		RoleArn:                r.RoleArn,
+		RecordTTLs:             recordTTLs,
+		ServiceDiscovery:       serviceDiscovery,
+		StaticBastionIP:        r.StaticBastionIP,
+		WildcardIngressService: r.WildcardIngressService,
 	})
 	if err != nil {
+		if cluster.DeletionTimestamp.IsZero() {
+			if patchErr := r.patchDNSReadyCondition(ctx, cluster, err); patchErr != nil {
+				log.Error(patchErr, "error patching DNSReady condition")
+			}
+		}
 		return reconcile.Result{}, microerror.Mask(err)
 	}
 
@@ -104,8 +205,38 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 }
 
 func (r *ClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := validateDNSProvider(r.DNSProvider, r.RFC2136, r.AWSCredentials); err != nil {
+		return microerror.Mask(err)
+	}
+	if r.ClusterTracker == nil {
+		return microerror.Maskf(invalidConfigError, "%T.ClusterTracker must not be empty", r)
+	}
+	if r.Recorder == nil {
+		return microerror.Maskf(invalidConfigError, "%T.Recorder must not be empty", r)
+	}
+	if err := scope.ValidateBaseDomains(r.BaseDomains); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := scope.ValidateAllowedRoles(r.AllowedClusterRoles); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := scope.ValidateNamingTemplates(r.NamingTemplates); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := scope.ValidateServiceDiscovery(r.ServiceDiscovery); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := scope.ValidateRecordTTLs(r.RecordTTLs); err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Changes of the ingress and gateway Services in the workload clusters
+	// are propagated by the tracker, so clusters are only resynced rarely.
+	// Kubeconfig changes make the tracker rebuild the workload cluster client.
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&capi.Cluster{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(kubeconfigSecretToCluster)).
+		WatchesRawSource(r.ClusterTracker.Source()).
 		Complete(r)
 }
 
@@ -140,17 +271,87 @@ func (r *ClusterReconciler) reconcileNormal(ctx context.Context, clusterScope *s
 		return ctrl.Result{RequeueAfter: 2 * time.Minute}, nil
 	}
 
-	route53Service := route53.NewService(clusterScope)
-	err := route53Service.ReconcileRoute53(ctx)
-	if route53.IsIngressNotReady(err) {
+	dryRun := isDryRun(r.DryRun, cluster)
+
+	// Record the domains before the zone is created, so a later change of the
+	// selected base domain or of the cluster domain template does not create a
+	// second zone and orphan the first one.
+	if !dryRun && recordProvisionedDomains(cluster, clusterScope.BaseDomain(), clusterScope.ClusterDomain()) {
+		if err := r.Update(ctx, cluster); err != nil {
+			return reconcile.Result{}, microerror.Mask(err)
+		}
+	}
+
+	provider, err := newDNSProvider(r.DNSProvider, r.rfc2136Config(dryRun), r.route53Config(dryRun), clusterScope)
+	if err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	dnsService := dns.NewService(clusterScope, provider)
+	err = dnsService.Reconcile(ctx)
+	if !dryRun {
+		// Stale records can be deleted even if the reconciliation failed afterwards.
+		for _, recordSet := range dnsService.DeletedRecordSets() {
+			r.Recorder.Eventf(cluster, nil, corev1.EventTypeNormal, key.RecordDeletedReason, "ReconcileDNS",
+				"Deleted stale %s record %s", recordSet.Type, recordSet.Name)
+		}
+	}
+	condition := dnsReadyCondition(err)
+	if dryRun {
+		planned := dnsService.PlannedChanges()
+		reportPlannedChanges(ctx, r.Recorder, "Cluster", cluster, planned)
+		if err == nil && len(planned) > 0 {
+			condition = dryRunCondition(planned)
+		}
+	} else {
+		metrics.DeleteDryRunPlannedChanges("Cluster", cluster.Namespace, cluster.Name)
+	}
+	if patchErr := r.patchCondition(ctx, cluster, condition); patchErr != nil {
+		log.Error(patchErr, "error patching DNSReady condition")
+	}
+	if dns.IsIngressNotReady(err) {
 		log.Error(err, "ingress is not ready yet, requeuing")
 		return reconcile.Result{}, microerror.Mask(err)
+	} else if dns.IsRecordNotOwned(err) {
+		// Retrying does not help until the owner releases the records, so the
+		// conflict is only reported and checked again with the next resync.
+		log.Info(fmt.Sprintf("Not changing records owned by someone else: %s", err))
+		r.Recorder.Eventf(cluster, nil, corev1.EventTypeWarning, key.RecordNotOwnedReason, "ReconcileDNS", "%s", err)
+		return ctrl.Result{RequeueAfter: resyncPeriod}, nil
 	} else if err != nil {
-		log.Error(err, "error creating route53")
+		log.Error(err, "error reconciling DNS records")
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	if r.WaitForChangePropagation && !dryRun {
+		return r.reconcilePropagation(ctx, cluster, dnsService)
+	}
+
+	return ctrl.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// reconcilePropagation reports the status of the submitted DNS changes with
+// the DNSPropagated condition and requeues the cluster until all of them are
+// served, so the worker is not blocked while Route53 propagates the changes.
+func (r *ClusterReconciler) reconcilePropagation(ctx context.Context, cluster *capi.Cluster, dnsService *dns.Service) (reconcile.Result, error) {
+	log := log.FromContext(ctx)
+
+	pending, err := dnsService.PendingChanges(ctx)
+	if err != nil {
+		log.Error(err, "error getting status of DNS changes")
 		return reconcile.Result{}, microerror.Mask(err)
 	}
 
-	return ctrl.Result{RequeueAfter: time.Minute}, nil
+	if err := r.patchDNSPropagatedCondition(ctx, cluster, pending); err != nil {
+		log.Error(err, "error patching DNSPropagated condition")
+	}
+
+	if len(pending) > 0 {
+		log.Info(fmt.Sprintf("Waiting for %d DNS changes to propagate", len(pending)))
+		return ctrl.Result{RequeueAfter: propagationPollPeriod}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: resyncPeriod}, nil
 }
 
 func (r *ClusterReconciler) reconcileDelete(ctx context.Context, clusterScope *scope.ClusterScope) (reconcile.Result, error) {
@@ -166,13 +367,34 @@ func (r *ClusterReconciler) reconcileDelete(ctx context.Context, clusterScope *s
 		return reconcile.Result{}, nil
 	}
 
-	route53Service := route53.NewService(clusterScope)
+	dryRun := isDryRun(r.DryRun, cluster)
+	provider, err := newDNSProvider(r.DNSProvider, r.rfc2136Config(dryRun), r.route53Config(dryRun), clusterScope)
+	if err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	}
 
-	if err := route53Service.DeleteRoute53(ctx); err != nil {
-		log.Error(err, "error deleting route53")
+	dnsService := dns.NewService(clusterScope, provider)
+	err = dnsService.Delete(ctx)
+	if dns.IsForeignRecords(err) {
+		// Retrying does not help until the owner removes its records, so the
+		// zone is left behind deliberately and the deletion is not blocked.
+		log.Info(fmt.Sprintf("Not deleting zone with records owned by someone else: %s", err))
+		r.Recorder.Eventf(cluster, nil, corev1.EventTypeWarning, key.ZoneKeptReason, "DeleteDNS", "%s", err)
+	} else if err != nil {
+		log.Error(err, "error deleting DNS records")
 		return reconcile.Result{}, microerror.Mask(err)
 	}
 
+	// The finalizers are kept in dry-run mode, so the zone is deleted once
+	// dry-run mode is disabled instead of being orphaned.
+	if dryRun {
+		reportPlannedChanges(ctx, r.Recorder, "Cluster", cluster, dnsService.PlannedChanges())
+		return ctrl.Result{RequeueAfter: resyncPeriod}, nil
+	}
+	metrics.DeleteDryRunPlannedChanges("Cluster", cluster.Namespace, cluster.Name)
+
+	r.ClusterTracker.Stop(client.ObjectKeyFromObject(cluster))
+
 	// cluster is deleted so remove the finalizer.
 	controllerutil.RemoveFinalizer(cluster, key.DNSFinalizerNameNew)
 	if err := r.Update(ctx, cluster); err != nil {
@@ -191,14 +413,124 @@ func (r *ClusterReconciler) reconcileDelete(ctx context.Context, clusterScope *s
 	}, nil
 }
 
-// check if the infrastructure provider is enabled
-// for now we simply disable the AWS provider here.
-// if needed in the future we should extend this to accept a list of enabled providers from the config.
-func (r *ClusterReconciler) enabledInfrastructureProvider(kind string) bool {
-	switch kind {
-	case "AWSCluster":
+// releaseFinalizers removes the finalizers of the operator without deleting the
+// DNS zone, so the cluster deletion is not blocked by an opted out cluster.
+func (r *ClusterReconciler) releaseFinalizers(ctx context.Context, cluster *capi.Cluster, infraCluster client.Object) (reconcile.Result, error) {
+	if controllerutil.RemoveFinalizer(cluster, key.DNSFinalizerNameNew) {
+		if err := r.Update(ctx, cluster); err != nil {
+			return reconcile.Result{}, microerror.Mask(err)
+		}
+	}
+
+	if controllerutil.RemoveFinalizer(infraCluster, key.DNSFinalizerNameNew) {
+		if err := r.Update(ctx, infraCluster); err != nil {
+			return reconcile.Result{}, microerror.Mask(err)
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// recordProvisionedDomains records the base domain and the cluster domain of
+// the cluster zone in the annotations of the cluster. It returns true if the
+// annotations changed.
+func recordProvisionedDomains(cluster *capi.Cluster, baseDomain, clusterDomain string) bool {
+	annotations := cluster.GetAnnotations()
+	if annotations[key.AnnotationProvisionedBaseDomain] == baseDomain &&
+		annotations[key.AnnotationProvisionedClusterDomain] == clusterDomain {
 		return false
+	}
+
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key.AnnotationProvisionedBaseDomain] = baseDomain
+	annotations[key.AnnotationProvisionedClusterDomain] = clusterDomain
+	cluster.SetAnnotations(annotations)
+	return true
+}
+
+// kubeconfigSecretToCluster maps a <cluster>-kubeconfig secret to its Cluster.
+func kubeconfigSecretToCluster(_ context.Context, o client.Object) []reconcile.Request {
+	name, ok := strings.CutSuffix(o.GetName(), scope.KubeConfigSecretSuffix)
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: name}}}
+}
+
+// dnsProviders contains the DNS providers which can be selected with the --dns-provider flag.
+var dnsProviders = []string{route53.ProviderName, rfc2136.ProviderName}
+
+// validateDNSProvider checks that the DNS provider is known and completely configured.
+func validateDNSProvider(dnsProvider string, rfc2136Config rfc2136.Config, awsCredentials scope.AWSCredentials) error {
+	if !slices.Contains(dnsProviders, dnsProvider) {
+		return microerror.Maskf(invalidConfigError, "unknown DNS provider %q, must be one of %v", dnsProvider, dnsProviders)
+	}
+	if dnsProvider == rfc2136.ProviderName && rfc2136Config.Server == "" {
+		return microerror.Maskf(invalidConfigError, "RFC 2136 server must not be empty")
+	}
+	if dnsProvider == route53.ProviderName {
+		if err := awsCredentials.Validate(); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	return nil
+}
+
+// resolveClusterRole returns the AWS role for the cluster hosted zone. Roles
+// are only resolved for the Route53 DNS provider.
+func resolveClusterRole(ctx context.Context, ctrlClient client.Client, dnsProvider string, cluster *capi.Cluster, allowedRoles []string) (scope.AWSRole, error) {
+	if dnsProvider != route53.ProviderName {
+		return scope.AWSRole{}, nil
+	}
+
+	role, err := scope.ResolveClusterRole(ctx, ctrlClient, cluster, allowedRoles)
+	if err != nil {
+		return scope.AWSRole{}, microerror.Mask(err)
+	}
+
+	return role, nil
+}
+
+// resolveClusterConfig returns the Service discovery and the record TTLs of
+// the cluster, which can be overridden with annotations on the cluster.
+func (r *ClusterReconciler) resolveClusterConfig(cluster *capi.Cluster) (cloud.ServiceDiscovery, cloud.RecordTTLs, error) {
+	serviceDiscovery, err := scope.ResolveServiceDiscovery(cluster, r.ServiceDiscovery)
+	if err != nil {
+		return cloud.ServiceDiscovery{}, cloud.RecordTTLs{}, microerror.Mask(err)
+	}
+
+	recordTTLs, err := scope.ResolveRecordTTLs(cluster, r.RecordTTLs)
+	if err != nil {
+		return cloud.ServiceDiscovery{}, cloud.RecordTTLs{}, microerror.Mask(err)
+	}
+
+	return serviceDiscovery, recordTTLs, nil
+}
+
+func (r *ClusterReconciler) route53Config(dryRun bool) route53.Config {
+	return route53.Config{
+		WaitForChangePropagation: r.WaitForChangePropagation,
+		DryRun:                   dryRun,
+	}
+}
+
+func (r *ClusterReconciler) rfc2136Config(dryRun bool) rfc2136.Config {
+	config := r.RFC2136
+	config.DryRun = dryRun
+	return config
+}
+
+// newDNSProvider returns the configured DNS provider for the given cluster scope.
+func newDNSProvider(dnsProvider string, rfc2136Config rfc2136.Config, route53Config route53.Config, clusterScope *scope.ClusterScope) (dns.Provider, error) {
+	switch dnsProvider {
+	case route53.ProviderName:
+		return route53.NewService(clusterScope, route53Config), nil
+	case rfc2136.ProviderName:
+		return rfc2136.NewService(clusterScope, rfc2136Config), nil
 	default:
-		return true
+		return nil, microerror.Maskf(invalidConfigError, "unknown DNS provider %q", dnsProvider)
 	}
 }
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+func Test_recordProvisionedDomains(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+
+		expectedChanged     bool
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:            "case 0: domains are recorded",
+			expectedChanged: true,
+			expectedAnnotations: map[string]string{
+				key.AnnotationProvisionedBaseDomain:    "gigantic.io",
+				key.AnnotationProvisionedClusterDomain: "test.gigantic.io",
+			},
+		},
+		{
+			name: "case 1: domains are already recorded",
+			annotations: map[string]string{
+				key.AnnotationBaseDomain:               "gigantic.io",
+				key.AnnotationProvisionedBaseDomain:    "gigantic.io",
+				key.AnnotationProvisionedClusterDomain: "test.gigantic.io",
+			},
+			expectedAnnotations: map[string]string{
+				key.AnnotationBaseDomain:               "gigantic.io",
+				key.AnnotationProvisionedBaseDomain:    "gigantic.io",
+				key.AnnotationProvisionedClusterDomain: "test.gigantic.io",
+			},
+		},
+		{
+			name:            "case 2: other annotations are kept",
+			annotations:     map[string]string{key.AnnotationDNSDryRun: "false"},
+			expectedChanged: true,
+			expectedAnnotations: map[string]string{
+				key.AnnotationDNSDryRun:                "false",
+				key.AnnotationProvisionedBaseDomain:    "gigantic.io",
+				key.AnnotationProvisionedClusterDomain: "test.gigantic.io",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: tc.annotations}}
+
+			changed := recordProvisionedDomains(cluster, "gigantic.io", "test.gigantic.io")
+
+			if changed != tc.expectedChanged {
+				t.Fatalf("expected changed %t, got %t", tc.expectedChanged, changed)
+			}
+			if !maps.Equal(cluster.Annotations, tc.expectedAnnotations) {
+				t.Fatalf("expected annotations %v, got %v", tc.expectedAnnotations, cluster.Annotations)
+			}
+		})
+	}
+}
+
+func Test_ClusterReconciler_Reconcile_GetError(t *testing.T) {
+	getErr := errors.New("connection refused")
+	ctrlClient := fake.NewClientBuilder().
+		WithScheme(testScheme()).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				return getErr
+			},
+		}).
+		Build()
+
+	r := &ClusterReconciler{Client: ctrlClient}
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "org-test", Name: "test"}})
+	if !errors.Is(err, getErr) {
+		t.Fatalf("expected error %v, got %v", getErr, err)
+	}
+}
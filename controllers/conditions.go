@@ -0,0 +1,124 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/rfc2136"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/route53"
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+// patchDNSReadyCondition sets the DNSReady condition for the result of the DNS
+// reconciliation on the cluster and patches it.
+func (r *ClusterReconciler) patchDNSReadyCondition(ctx context.Context, cluster *capi.Cluster, reconcileErr error) error {
+	return r.patchCondition(ctx, cluster, dnsReadyCondition(reconcileErr))
+}
+
+// patchDNSPropagatedCondition sets the DNSPropagated condition for the given
+// pending DNS changes on the cluster and patches it.
+func (r *ClusterReconciler) patchDNSPropagatedCondition(ctx context.Context, cluster *capi.Cluster, pending []string) error {
+	return r.patchCondition(ctx, cluster, dnsPropagatedCondition(pending))
+}
+
+func (r *ClusterReconciler) patchCondition(ctx context.Context, cluster *capi.Cluster, condition metav1.Condition) error {
+	patchHelper, err := patch.NewHelper(cluster, r.Client)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	conditions.Set(cluster, condition)
+
+	err = patchHelper.Patch(ctx, cluster, patch.WithOwnedConditions{Conditions: []string{condition.Type}})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// dnsPropagatedCondition maps the pending DNS changes to the DNSPropagated condition.
+func dnsPropagatedCondition(pending []string) metav1.Condition {
+	if len(pending) == 0 {
+		return metav1.Condition{
+			Type:   key.DNSPropagatedCondition,
+			Status: metav1.ConditionTrue,
+			Reason: key.ChangesInSyncReason,
+		}
+	}
+
+	return metav1.Condition{
+		Type:    key.DNSPropagatedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  key.ChangesPendingReason,
+		Message: fmt.Sprintf("Waiting for changes %s to propagate", strings.Join(pending, ", ")),
+	}
+}
+
+// dryRunCondition reports the changes which were planned but not applied in dry-run mode.
+func dryRunCondition(changes []dns.PlannedChange) metav1.Condition {
+	return metav1.Condition{
+		Type:    key.DNSReadyCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  key.DryRunReason,
+		Message: fmt.Sprintf("%d DNS changes are planned but not applied in dry-run mode", len(changes)),
+	}
+}
+
+// dnsReadyCondition maps the error of the DNS reconciliation to the DNSReady condition.
+func dnsReadyCondition(err error) metav1.Condition {
+	condition := metav1.Condition{
+		Type:   key.DNSReadyCondition,
+		Status: metav1.ConditionFalse,
+	}
+
+	switch {
+	case err == nil:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = key.DNSReadyReason
+		return condition
+	case scope.IsAccessDenied(err) || route53.IsAccessDenied(err) || rfc2136.IsPermissionDenied(err):
+		condition.Reason = key.PermissionDeniedReason
+	case dns.IsZoneNotReady(err):
+		condition.Reason = key.HostedZoneCreatingReason
+	case dns.IsDelegationFailed(err):
+		condition.Reason = key.DelegationFailedReason
+	case dns.IsAPIEndpointMissing(err):
+		condition.Reason = key.APIEndpointMissingReason
+	case dns.IsIngressNotReady(err):
+		condition.Reason = key.IngressNotReadyReason
+	case dns.IsRecordNotOwned(err):
+		condition.Reason = key.RecordNotOwnedReason
+	case scope.IsInvalidConfig(err):
+		condition.Reason = key.InvalidConfigReason
+	default:
+		condition.Reason = key.ReconciliationFailedReason
+	}
+	condition.Message = err.Error()
+
+	return condition
+}
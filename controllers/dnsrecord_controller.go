@@ -0,0 +1,381 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/giantswarm/microerror"
+
+	dnsv1alpha1 "github.com/giantswarm/dns-operator-route53/api/v1alpha1"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/metrics"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/rfc2136"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/route53"
+)
+
+const dnsRecordClusterRefIndex = "spec.clusterRef.name"
+
+// DNSRecordReconciler reconciles a DNSRecord object
+type DNSRecordReconciler struct {
+	client.Client
+
+	// AllowedClusterRoles are the role ARNs and AWS account IDs which clusters
+	// can select with the dns.giantswarm.io/aws-role-arn annotation.
+	AllowedClusterRoles []string
+	AWSCredentials      scope.AWSCredentials
+	// BaseDomains are the base domains which clusters can select with the
+	// base domain annotation. The first one is used for all other clusters.
+	BaseDomains []string
+	DNSProvider string
+	// DryRun only plans the DNS changes of all records instead of applying them.
+	DryRun              bool
+	InfrastructureKinds InfrastructureKinds
+	ManagementCluster   string
+	// NamingTemplates render the cluster domain and the record names of all clusters.
+	NamingTemplates cloud.NamingTemplates
+	// Recorder emits events on the DNSRecord, e.g. when its record is owned by someone else.
+	Recorder        events.EventRecorder
+	RFC2136         rfc2136.Config
+	RoleArn         string
+	StaticBastionIP string
+
+	// newProvider is replaced in tests.
+	newProvider func(clusterScope *scope.ClusterScope, dryRun bool) (dns.Provider, error)
+}
+
+func (r *DNSRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithValues("dnsrecord", req.NamespacedName)
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	record := &dnsv1alpha1.DNSRecord{}
+	if err := r.Get(ctx, req.NamespacedName, record); apierrors.IsNotFound(err) {
+		return reconcile.Result{}, nil
+	} else if err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	cluster := &capi.Cluster{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: record.Namespace, Name: record.Spec.ClusterRef.Name}, cluster)
+	if apierrors.IsNotFound(err) {
+		cluster = nil
+	} else if err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	// Handle deleted records
+	if !record.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, record, cluster)
+	}
+
+	// Handle non-deleted records
+	return r.reconcileNormal(ctx, record, cluster)
+}
+
+func (r *DNSRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := validateDNSProvider(r.DNSProvider, r.RFC2136, r.AWSCredentials); err != nil {
+		return microerror.Mask(err)
+	}
+	if r.Recorder == nil {
+		return microerror.Maskf(invalidConfigError, "%T.Recorder must not be empty", r)
+	}
+	if err := scope.ValidateBaseDomains(r.BaseDomains); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := scope.ValidateAllowedRoles(r.AllowedClusterRoles); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := scope.ValidateNamingTemplates(r.NamingTemplates); err != nil {
+		return microerror.Mask(err)
+	}
+
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &dnsv1alpha1.DNSRecord{}, dnsRecordClusterRefIndex, indexDNSRecordClusterRef)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dnsv1alpha1.DNSRecord{}).
+		Watches(&capi.Cluster{}, handler.EnqueueRequestsFromMapFunc(r.clusterToDNSRecords)).
+		Complete(r)
+}
+
+func (r *DNSRecordReconciler) reconcileNormal(ctx context.Context, record *dnsv1alpha1.DNSRecord, cluster *capi.Cluster) (reconcile.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling DNSRecord normal")
+
+	// Register the finalizer immediately to avoid orphaning records on delete
+	if !controllerutil.ContainsFinalizer(record, dnsv1alpha1.DNSRecordFinalizer) {
+		controllerutil.AddFinalizer(record, dnsv1alpha1.DNSRecordFinalizer)
+		if err := r.Update(ctx, record); err != nil {
+			return reconcile.Result{}, microerror.Mask(err)
+		}
+	}
+
+	// The records are reconciled again by the Cluster watch once the cluster changes.
+	switch {
+	case cluster == nil:
+		return reconcile.Result{}, r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.ClusterNotFoundReason,
+			"Cluster "+record.Spec.ClusterRef.Name+" does not exist")
+	case !cluster.DeletionTimestamp.IsZero():
+		return reconcile.Result{}, r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.ClusterDeletingReason,
+			"Cluster "+cluster.Name+" is being deleted")
+	case !r.InfrastructureKinds.IsEnabled(cluster.Spec.InfrastructureRef.Kind):
+		return reconcile.Result{}, r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.ClusterNotReadyReason,
+			"Infrastructure provider "+cluster.Spec.InfrastructureRef.Kind+" is not enabled")
+	case dnsDisabled(cluster):
+		return reconcile.Result{}, r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.ClusterNotReadyReason,
+			"DNS management is disabled for cluster "+cluster.Name)
+	case cluster.Status.Phase != string(capi.ClusterPhaseProvisioned):
+		return reconcile.Result{}, r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.ClusterNotReadyReason,
+			"Cluster "+cluster.Name+" is in phase "+cluster.Status.Phase)
+	}
+
+	dryRun := isDryRun(r.DryRun, cluster)
+	dnsService, err := r.newDNSService(ctx, cluster, dryRun)
+	if err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	} else if dnsService == nil {
+		return reconcile.Result{}, nil
+	}
+
+	desired := dns.RecordSet{
+		Name:   dnsService.RecordSetName(record.Spec.Name),
+		Type:   record.Spec.Type,
+		TTL:    record.Spec.TTL,
+		Values: record.Spec.Values,
+	}
+	previous := &dns.RecordSet{
+		Name: record.Status.FQDN,
+		Type: record.Status.Type,
+	}
+
+	err = dnsService.ReconcileRecordSet(ctx, dns.DNSRecordResource(record.Namespace, record.Name), desired, previous)
+	if dns.IsZoneNotReady(err) {
+		log.Info(fmt.Sprintf("Waiting for the cluster zone: %s", err))
+		if statusErr := r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.ClusterNotReadyReason, err.Error()); statusErr != nil {
+			return reconcile.Result{}, microerror.Mask(statusErr)
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	} else if dns.IsRecordConflict(err) {
+		log.Error(err, "record conflicts with an operator managed record")
+		return reconcile.Result{}, r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.RecordConflictReason, err.Error())
+	} else if dns.IsRecordNotOwned(err) {
+		log.Info("Not changing record owned by someone else")
+		r.Recorder.Eventf(record, nil, corev1.EventTypeWarning, dnsv1alpha1.RecordNotOwnedReason, "ReconcileDNSRecord", "%s", err)
+		if statusErr := r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.RecordNotOwnedReason, err.Error()); statusErr != nil {
+			return reconcile.Result{}, microerror.Mask(statusErr)
+		}
+		return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+	} else if err != nil {
+		log.Error(err, "error reconciling DNS record")
+		if statusErr := r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.ReconciliationFailedReason, err.Error()); statusErr != nil {
+			log.Error(statusErr, "error updating DNSRecord status")
+		}
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	if dryRun {
+		planned := dnsService.PlannedChanges()
+		reportPlannedChanges(ctx, r.Recorder, "DNSRecord", record, planned)
+		if len(planned) > 0 {
+			// The status keeps the applied record, so it is still removed on delete.
+			err := r.setReadyCondition(ctx, record, metav1.ConditionFalse, dnsv1alpha1.DryRunReason,
+				fmt.Sprintf("%d DNS changes are planned but not applied in dry-run mode", len(planned)))
+			if err != nil {
+				return reconcile.Result{}, microerror.Mask(err)
+			}
+			return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+		}
+	} else {
+		metrics.DeleteDryRunPlannedChanges("DNSRecord", record.Namespace, record.Name)
+	}
+
+	record.Status.FQDN = desired.Name
+	record.Status.Type = desired.Type
+	if err := r.setReadyCondition(ctx, record, metav1.ConditionTrue, dnsv1alpha1.RecordReconciledReason, "Record exists in the cluster zone"); err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+}
+
+func (r *DNSRecordReconciler) reconcileDelete(ctx context.Context, record *dnsv1alpha1.DNSRecord, cluster *capi.Cluster) (reconcile.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling DNSRecord delete")
+
+	if !controllerutil.ContainsFinalizer(record, dnsv1alpha1.DNSRecordFinalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	// The cluster zone and all its records are removed together with the cluster,
+	// so the record only has to be removed if the cluster stays. The zones of
+	// clusters which are not managed by the operator are left untouched.
+	if cluster != nil && cluster.DeletionTimestamp.IsZero() && record.Status.FQDN != "" &&
+		r.InfrastructureKinds.IsEnabled(cluster.Spec.InfrastructureRef.Kind) && !dnsDisabled(cluster) {
+		dryRun := isDryRun(r.DryRun, cluster)
+		dnsService, err := r.newDNSService(ctx, cluster, dryRun)
+		if err != nil {
+			return reconcile.Result{}, microerror.Mask(err)
+		} else if dnsService == nil {
+			return reconcile.Result{}, nil
+		}
+
+		err = dnsService.DeleteRecordSet(ctx, dns.DNSRecordResource(record.Namespace, record.Name), dns.RecordSet{
+			Name: record.Status.FQDN,
+			Type: record.Status.Type,
+		})
+		if dns.IsRecordNotOwned(err) {
+			// The record was taken over by someone else, so there is nothing left to clean up.
+			log.Info("Not deleting record owned by someone else")
+		} else if err != nil {
+			log.Error(err, "error deleting DNS record")
+			return reconcile.Result{}, microerror.Mask(err)
+		}
+
+		// The finalizer is kept in dry-run mode, so the record is deleted
+		// once dry-run mode is disabled instead of being orphaned.
+		if dryRun {
+			reportPlannedChanges(ctx, r.Recorder, "DNSRecord", record, dnsService.PlannedChanges())
+			return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+		}
+	}
+	metrics.DeleteDryRunPlannedChanges("DNSRecord", record.Namespace, record.Name)
+
+	// record is deleted so remove the finalizer.
+	controllerutil.RemoveFinalizer(record, dnsv1alpha1.DNSRecordFinalizer)
+	if err := r.Update(ctx, record); err != nil {
+		return reconcile.Result{}, microerror.Mask(err)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// newDNSService returns the DNS service for the zone of the given cluster. It
+// returns nil if the cluster is paused.
+func (r *DNSRecordReconciler) newDNSService(ctx context.Context, cluster *capi.Cluster, dryRun bool) (*dns.Service, error) {
+	infraCluster, err := external.GetObjectFromContractVersionedRef(ctx, r, cluster.Spec.InfrastructureRef, cluster.Namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if annotations.IsPaused(cluster, infraCluster) {
+		log.FromContext(ctx).Info("infrastructure or core cluster is marked as paused. Won't reconcile")
+		return nil, nil
+	}
+
+	clusterRole, err := resolveClusterRole(ctx, r, r.DNSProvider, cluster, r.AllowedClusterRoles)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	baseDomain, err := scope.ResolveBaseDomain(ctx, r, cluster, r.BaseDomains)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	clusterScope, err := scope.NewClusterScope(ctx, scope.ClusterScopeParams{
+		BaseDomain:            baseDomain,
+		Cluster:               cluster,
+		ClusterRole:           clusterRole,
+		Credentials:           r.AWSCredentials,
+		InfrastructureCluster: infraCluster,
+		ManagementCluster:     r.ManagementCluster,
+		NamingTemplates:       r.NamingTemplates,
+		RoleArn:               r.RoleArn,
+		StaticBastionIP:       r.StaticBastionIP,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newProvider := r.newProvider
+	if newProvider == nil {
+		newProvider = r.newDNSProvider
+	}
+	provider, err := newProvider(clusterScope, dryRun)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return dns.NewService(clusterScope, provider), nil
+}
+
+func (r *DNSRecordReconciler) newDNSProvider(clusterScope *scope.ClusterScope, dryRun bool) (dns.Provider, error) {
+	// The propagation of DNSRecord changes is not tracked, the Ready condition
+	// reports whether the record was accepted by the DNS provider.
+	rfc2136Config := r.RFC2136
+	rfc2136Config.DryRun = dryRun
+	provider, err := newDNSProvider(r.DNSProvider, rfc2136Config, route53.Config{DryRun: dryRun}, clusterScope)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return provider, nil
+}
+
+func (r *DNSRecordReconciler) setReadyCondition(ctx context.Context, record *dnsv1alpha1.DNSRecord, status metav1.ConditionStatus, reason, message string) error {
+	record.Status.ObservedGeneration = record.Generation
+	meta.SetStatusCondition(&record.Status.Conditions, metav1.Condition{
+		Type:               dnsv1alpha1.ReadyCondition,
+		Status:             status,
+		ObservedGeneration: record.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+
+	return microerror.Mask(r.Status().Update(ctx, record))
+}
+
+// indexDNSRecordClusterRef indexes the DNSRecords by the name of the referenced Cluster.
+func indexDNSRecordClusterRef(o client.Object) []string {
+	return []string{o.(*dnsv1alpha1.DNSRecord).Spec.ClusterRef.Name}
+}
+
+// clusterToDNSRecords maps a Cluster to the DNSRecords which reference it.
+func (r *DNSRecordReconciler) clusterToDNSRecords(ctx context.Context, o client.Object) []reconcile.Request {
+	records := &dnsv1alpha1.DNSRecordList{}
+	err := r.List(ctx, records, client.InNamespace(o.GetNamespace()), client.MatchingFields{dnsRecordClusterRefIndex: o.GetName()})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list DNSRecords for cluster", "cluster", o.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(records.Items))
+	for _, record := range records.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&record)})
+	}
+	return requests
+}
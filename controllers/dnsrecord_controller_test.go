@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsroute53 "github.com/aws/aws-sdk-go/service/route53"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	dnsv1alpha1 "github.com/giantswarm/dns-operator-route53/api/v1alpha1"
+	dnscache "github.com/giantswarm/dns-operator-route53/pkg/cloud/cache"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/route53"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/route53/route53test"
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	dnscache.DNSOperatorCache, err = dnscache.NewDNSOperatorCache()
+	if err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+func testScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = apiextensionsv1.AddToScheme(scheme)
+	_ = capi.AddToScheme(scheme)
+	_ = dnsv1alpha1.AddToScheme(scheme)
+	return scheme
+}
+
+// testInfrastructure returns the infrastructure cluster of the test cluster
+// together with the CRD which maps the Cluster API contract to its version.
+func testInfrastructure() []client.Object {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "dockerclusters.infrastructure.cluster.x-k8s.io",
+			Labels: map[string]string{"cluster.x-k8s.io/v1beta2": "v1beta2"},
+		},
+	}
+
+	infraCluster := &unstructured.Unstructured{}
+	infraCluster.SetAPIVersion("infrastructure.cluster.x-k8s.io/v1beta2")
+	infraCluster.SetKind("DockerCluster")
+	infraCluster.SetNamespace("org-test")
+	infraCluster.SetName("test")
+
+	return []client.Object{crd, infraCluster}
+}
+
+func testDNSRecordCluster() *capi.Cluster {
+	return &capi.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "org-test", Name: "test", UID: "a1b2"},
+		Spec: capi.ClusterSpec{
+			InfrastructureRef: capi.ContractVersionedObjectReference{
+				APIGroup: "infrastructure.cluster.x-k8s.io",
+				Kind:     "DockerCluster",
+				Name:     "test",
+			},
+		},
+		Status: capi.ClusterStatus{Phase: string(capi.ClusterPhaseProvisioned)},
+	}
+}
+
+func testDNSRecord(name, clusterName string) *dnsv1alpha1.DNSRecord {
+	return &dnsv1alpha1.DNSRecord{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "org-test", Name: name},
+		Spec: dnsv1alpha1.DNSRecordSpec{
+			Name:       name,
+			Type:       "A",
+			TTL:        300,
+			Values:     []string{"10.0.0.1"},
+			ClusterRef: corev1.LocalObjectReference{Name: clusterName},
+		},
+	}
+}
+
+func Test_DNSRecordReconciler_Reconcile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		cluster *capi.Cluster
+		record  func(record *dnsv1alpha1.DNSRecord)
+		setup   func(fakeRoute53 *route53test.FakeRoute53, zoneID string)
+		// withoutZone does not create the zone of the cluster.
+		withoutZone bool
+
+		expectedReason    string
+		expectedFinalizer bool
+		expectedDeleted   bool
+		expectedRecord    string
+	}{
+		{
+			name:              "case 0: add finalizer and create record",
+			cluster:           testDNSRecordCluster(),
+			expectedReason:    dnsv1alpha1.RecordReconciledReason,
+			expectedFinalizer: true,
+			expectedRecord:    "10.0.0.1",
+		},
+		{
+			name:              "case 1: cluster does not exist",
+			expectedReason:    dnsv1alpha1.ClusterNotFoundReason,
+			expectedFinalizer: true,
+		},
+		{
+			name:    "case 2: record is owned by someone else",
+			cluster: testDNSRecordCluster(),
+			setup: func(fakeRoute53 *route53test.FakeRoute53, zoneID string) {
+				fakeRoute53.AddRecordSet(zoneID, &awsroute53.ResourceRecordSet{
+					Name:            aws.String("vault.test.example.com"),
+					Type:            aws.String("A"),
+					TTL:             aws.Int64(300),
+					ResourceRecords: []*awsroute53.ResourceRecord{{Value: aws.String("10.0.0.99")}},
+				})
+				fakeRoute53.AddRecordSet(zoneID, &awsroute53.ResourceRecordSet{
+					Name:            aws.String("a-vault.test.example.com"),
+					Type:            aws.String("TXT"),
+					TTL:             aws.Int64(300),
+					ResourceRecords: []*awsroute53.ResourceRecord{{Value: aws.String(`"heritage=external-dns,external-dns/owner=default"`)}},
+				})
+			},
+			expectedReason:    dnsv1alpha1.RecordNotOwnedReason,
+			expectedFinalizer: true,
+			expectedRecord:    "10.0.0.99",
+		},
+		{
+			name:    "case 3: delete record",
+			cluster: testDNSRecordCluster(),
+			record: func(record *dnsv1alpha1.DNSRecord) {
+				record.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				record.Finalizers = []string{dnsv1alpha1.DNSRecordFinalizer}
+				record.Status.FQDN = "vault.test.example.com"
+				record.Status.Type = "A"
+			},
+			setup: func(fakeRoute53 *route53test.FakeRoute53, zoneID string) {
+				fakeRoute53.AddRecordSet(zoneID, &awsroute53.ResourceRecordSet{
+					Name:            aws.String("vault.test.example.com"),
+					Type:            aws.String("A"),
+					TTL:             aws.Int64(300),
+					ResourceRecords: []*awsroute53.ResourceRecord{{Value: aws.String("10.0.0.1")}},
+				})
+			},
+			expectedDeleted: true,
+		},
+		{
+			name: "case 4: delete record of a deleted cluster",
+			record: func(record *dnsv1alpha1.DNSRecord) {
+				record.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				record.Finalizers = []string{dnsv1alpha1.DNSRecordFinalizer}
+				record.Status.FQDN = "vault.test.example.com"
+				record.Status.Type = "A"
+			},
+			setup: func(fakeRoute53 *route53test.FakeRoute53, zoneID string) {
+				fakeRoute53.AddRecordSet(zoneID, &awsroute53.ResourceRecordSet{
+					Name:            aws.String("vault.test.example.com"),
+					Type:            aws.String("A"),
+					TTL:             aws.Int64(300),
+					ResourceRecords: []*awsroute53.ResourceRecord{{Value: aws.String("10.0.0.1")}},
+				})
+			},
+			// The record is removed together with the zone of the cluster.
+			expectedDeleted: true,
+			expectedRecord:  "10.0.0.1",
+		},
+		{
+			name:              "case 5: zone of the cluster does not exist yet",
+			cluster:           testDNSRecordCluster(),
+			withoutZone:       true,
+			expectedReason:    dnsv1alpha1.ClusterNotReadyReason,
+			expectedFinalizer: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+			ctx := context.Background()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			fakeRoute53.AddHostedZone("example.com")
+			var zoneID string
+			if !tc.withoutZone {
+				zoneID = fakeRoute53.AddHostedZone("test.example.com")
+			}
+			if tc.setup != nil {
+				tc.setup(fakeRoute53, zoneID)
+			}
+			changes := fakeRoute53.Calls("ChangeResourceRecordSets")
+
+			record := testDNSRecord("vault", "test")
+			if tc.record != nil {
+				tc.record(record)
+			}
+			objects := append(testInfrastructure(),
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "org-test"}},
+				record,
+			)
+			if tc.cluster != nil {
+				objects = append(objects, tc.cluster)
+			}
+			ctrlClient := fake.NewClientBuilder().
+				WithScheme(testScheme()).
+				WithObjects(objects...).
+				WithStatusSubresource(&dnsv1alpha1.DNSRecord{}).
+				Build()
+
+			r := &DNSRecordReconciler{
+				Client:            ctrlClient,
+				BaseDomains:       []string{"example.com"},
+				DNSProvider:       route53.ProviderName,
+				ManagementCluster: "mc",
+				NamingTemplates:   scope.DefaultNamingTemplates(),
+				Recorder:          events.NewFakeRecorder(10),
+				newProvider: func(clusterScope *scope.ClusterScope, dryRun bool) (dns.Provider, error) {
+					provider := route53.NewService(clusterScope, route53.Config{DryRun: dryRun})
+					provider.Route53Client = fakeRoute53
+					provider.BaseRoute53Client = fakeRoute53
+					return provider, nil
+				},
+			}
+
+			result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(record)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.withoutZone {
+				if result.RequeueAfter == 0 {
+					t.Fatalf("expected requeue while the zone does not exist")
+				}
+				if zoneID := fakeRoute53.HostedZoneID("test.example.com"); zoneID != "" {
+					t.Fatalf("expected zone not to be created")
+				}
+			}
+
+			got := &dnsv1alpha1.DNSRecord{}
+			err = ctrlClient.Get(ctx, client.ObjectKeyFromObject(record), got)
+			if tc.expectedDeleted {
+				if !apierrors.IsNotFound(err) {
+					t.Fatalf("expected DNSRecord to be deleted, got %v", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if finalizer := slices.Contains(got.Finalizers, dnsv1alpha1.DNSRecordFinalizer); finalizer != tc.expectedFinalizer {
+					t.Fatalf("expected finalizer %t, got %v", tc.expectedFinalizer, got.Finalizers)
+				}
+				condition := meta.FindStatusCondition(got.Status.Conditions, dnsv1alpha1.ReadyCondition)
+				if condition == nil || condition.Reason != tc.expectedReason {
+					t.Fatalf("expected Ready condition with reason %s, got %v", tc.expectedReason, condition)
+				}
+			}
+
+			var value string
+			if recordSet := fakeRoute53.RecordSet(zoneID, "vault.test.example.com", "A"); recordSet != nil {
+				value = aws.StringValue(recordSet.ResourceRecords[0].Value)
+			}
+			if value != tc.expectedRecord {
+				t.Fatalf("expected record value %q, got %q", tc.expectedRecord, value)
+			}
+			if tc.cluster == nil && fakeRoute53.Calls("ChangeResourceRecordSets") != changes {
+				t.Fatalf("expected no changes without a cluster")
+			}
+		})
+	}
+}
+
+func Test_DNSRecordReconciler_clusterToDNSRecords(t *testing.T) {
+	other := testDNSRecord("consul", "other")
+	otherNamespace := testDNSRecord("vault", "test")
+	otherNamespace.Namespace = "org-other"
+
+	ctrlClient := fake.NewClientBuilder().
+		WithScheme(testScheme()).
+		WithObjects(testDNSRecord("vault", "test"), testDNSRecord("etcd", "test"), other, otherNamespace).
+		WithIndex(&dnsv1alpha1.DNSRecord{}, dnsRecordClusterRefIndex, indexDNSRecordClusterRef).
+		Build()
+
+	r := &DNSRecordReconciler{Client: ctrlClient}
+	requests := r.clusterToDNSRecords(context.Background(), testDNSRecordCluster())
+
+	expected := []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "org-test", Name: "etcd"}},
+		{NamespacedName: client.ObjectKey{Namespace: "org-test", Name: "vault"}},
+	}
+	slices.SortFunc(requests, func(a, b reconcile.Request) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	if !slices.Equal(requests, expected) {
+		t.Fatalf("expected requests %v, got %v", expected, requests)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/metrics"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+const (
+	// maxEventChanges is the number of planned changes listed in a DryRun event.
+	maxEventChanges = 8
+	// maxEventNoteLength is the limit of the API server for the note of an event.
+	maxEventNoteLength = 1024
+)
+
+// isDryRun returns true if the DNS changes of the cluster are only planned,
+// either for all clusters with the --dry-run flag or for single clusters with
+// the dns.giantswarm.io/dry-run annotation.
+func isDryRun(dryRun bool, cluster *capi.Cluster) bool {
+	return dryRun || cluster.Annotations[key.AnnotationDNSDryRun] == "true"
+}
+
+// reportPlannedChanges logs the changes planned for the object in dry-run
+// mode, emits them with a DryRun event and exposes their number with the
+// dns_dry_run_planned_changes metric.
+func reportPlannedChanges(ctx context.Context, recorder events.EventRecorder, kind string, obj client.Object, changes []dns.PlannedChange) {
+	log := log.FromContext(ctx)
+
+	var plan dns.Plan
+	plan.Add(changes...)
+	metrics.SetDryRunPlannedChanges(kind, obj.GetNamespace(), obj.GetName(), plan.CountByAction())
+
+	if len(changes) == 0 {
+		log.Info("Dry-run planned no DNS changes")
+		return
+	}
+
+	summary := make([]string, 0, min(len(changes), maxEventChanges))
+	for i, change := range changes {
+		log.Info("Dry-run planned DNS change", "action", change.Action, "zone", change.Zone,
+			"name", change.RecordSet.Name, "type", change.RecordSet.Type, "values", change.RecordSet.Values)
+		if i < maxEventChanges {
+			summary = append(summary, change.String())
+		}
+	}
+	if len(changes) > maxEventChanges {
+		summary = append(summary, fmt.Sprintf("and %d more", len(changes)-maxEventChanges))
+	}
+
+	note := fmt.Sprintf("Dry-run planned %d DNS changes: %s", len(changes), strings.Join(summary, "; "))
+	if len(note) > maxEventNoteLength {
+		note = note[:maxEventNoteLength-3] + "..."
+	}
+	recorder.Eventf(obj, nil, corev1.EventTypeNormal, key.DryRunReason, "PlanDNSChanges", "%s", note)
+}
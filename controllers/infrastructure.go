@@ -0,0 +1,47 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"slices"
+
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+// InfrastructureKinds selects the clusters which are reconciled by the kind of
+// their infrastructure cluster, e.g. OpenStackCluster.
+type InfrastructureKinds struct {
+	// Enabled contains the reconciled kinds. All kinds are reconciled if it is empty.
+	Enabled []string
+	// Disabled contains the kinds which are never reconciled. It takes
+	// precedence over Enabled.
+	Disabled []string
+}
+
+// IsEnabled returns true if clusters of the given infrastructure kind are reconciled.
+func (k InfrastructureKinds) IsEnabled(kind string) bool {
+	if slices.Contains(k.Disabled, kind) {
+		return false
+	}
+	return len(k.Enabled) == 0 || slices.Contains(k.Enabled, kind)
+}
+
+// dnsDisabled returns true if the DNS management of the cluster is turned off
+// with the dns.giantswarm.io/disabled annotation.
+func dnsDisabled(cluster *capi.Cluster) bool {
+	return cluster.Annotations[key.AnnotationDNSDisabled] == "true"
+}
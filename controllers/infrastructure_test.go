@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"testing"
+)
+
+func Test_InfrastructureKinds_IsEnabled(t *testing.T) {
+	testCases := []struct {
+		name     string
+		kinds    InfrastructureKinds
+		kind     string
+		expected bool
+	}{
+		{
+			name:     "case 0: all kinds are enabled by default",
+			kind:     "OpenStackCluster",
+			expected: true,
+		},
+		{
+			name:     "case 1: disabled kind",
+			kinds:    InfrastructureKinds{Disabled: []string{"AWSCluster", "AzureCluster"}},
+			kind:     "AzureCluster",
+			expected: false,
+		},
+		{
+			name:     "case 2: kind is not disabled",
+			kinds:    InfrastructureKinds{Disabled: []string{"AWSCluster"}},
+			kind:     "VSphereCluster",
+			expected: true,
+		},
+		{
+			name:     "case 3: enabled kind",
+			kinds:    InfrastructureKinds{Enabled: []string{"OpenStackCluster", "VCDCluster"}},
+			kind:     "VCDCluster",
+			expected: true,
+		},
+		{
+			name:     "case 4: kind is not enabled",
+			kinds:    InfrastructureKinds{Enabled: []string{"OpenStackCluster"}},
+			kind:     "VSphereCluster",
+			expected: false,
+		},
+		{
+			name:     "case 5: disabled takes precedence over enabled",
+			kinds:    InfrastructureKinds{Enabled: []string{"AWSCluster"}, Disabled: []string{"AWSCluster"}},
+			kind:     "AWSCluster",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if enabled := tc.kinds.IsEnabled(tc.kind); enabled != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, enabled)
+			}
+		})
+	}
+}
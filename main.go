@@ -19,18 +19,31 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	dnsv1alpha1 "github.com/giantswarm/dns-operator-route53/api/v1alpha1"
 	"github.com/giantswarm/dns-operator-route53/controllers"
 
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
 	dnscache "github.com/giantswarm/dns-operator-route53/pkg/cloud/cache"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/rfc2136"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/route53"
+	"github.com/giantswarm/dns-operator-route53/pkg/remote"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -43,17 +56,34 @@ func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 
 	_ = capi.AddToScheme(scheme)
+	_ = dnsv1alpha1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var (
 		baseDomain           string
+		baseDomains          string
+		dnsProvider          string
 		enableLeaderElection bool
 		managementCluster    string
 		metricsAddr          string
+		awsCredentials       scope.AWSCredentials
+		disabledInfraKinds   string
+		enabledInfraKinds    string
+		rfc2136Config        rfc2136.Config
 		roleArn              string
+		allowedClusterRoles  string
 		staticBastionIP      string
+		wildcardIngress      string
+		ingressNamespaces    string
+		ingressSelector      string
+		gatewayNamespaces    string
+		gatewaySelector      string
+		recordTTLs           cloud.RecordTTLs
+		namingTemplates      cloud.NamingTemplates
+		waitForPropagation   bool
+		dryRun               bool
 	)
 
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
@@ -61,13 +91,78 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 
+	flag.StringVar(&awsCredentials.Source, "aws-credentials-source", scope.AWSCredentialsSourceDefault,
+		"Source of the AWS credentials of the operator, one of default or web-identity. "+
+			"The default source uses the credential chain of the AWS SDK, e.g. a shared credentials file.")
+	flag.StringVar(&awsCredentials.WebIdentityRoleARN, "aws-web-identity-role-arn", "", "ARN of the role the web identity token is exchanged for.")
+	flag.StringVar(&awsCredentials.WebIdentityTokenFile, "aws-web-identity-token-file", "/var/run/secrets/eks.amazonaws.com/serviceaccount/token", "Path of the web identity token file.")
 	flag.StringVar(&baseDomain, "base-domain", "", "Domain for which to create the DNS entries, e.g. customer.gigantic.io.")
+	flag.StringVar(&baseDomains, "additional-base-domains", "",
+		"Comma separated list of further base domains which clusters can select with the dns.giantswarm.io/base-domain annotation or namespace label.")
+	flag.StringVar(&disabledInfraKinds, "disabled-infrastructure-kinds", "AWSCluster",
+		"Comma separated list of infrastructure cluster kinds whose clusters are not reconciled, e.g. AWSCluster,AzureCluster. Takes precedence over --enabled-infrastructure-kinds.")
+	flag.StringVar(&enabledInfraKinds, "enabled-infrastructure-kinds", "",
+		"Comma separated list of infrastructure cluster kinds whose clusters are reconciled, e.g. OpenStackCluster. All kinds are reconciled if empty.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Only plan the changes of the DNS zones and records and report them with logs, events and the dns_dry_run_planned_changes metric instead of applying them.")
+	flag.StringVar(&dnsProvider, "dns-provider", route53.ProviderName, "DNS provider used to manage the cluster zones, e.g. route53.")
 	flag.StringVar(&managementCluster, "management-cluster", "", "Name of the management cluster.")
+	flag.StringVar(&rfc2136Config.Server, "rfc2136-server", "", "Address of the DNS server which receives RFC 2136 updates, e.g. 10.0.0.53:53.")
+	flag.StringVar(&rfc2136Config.Zone, "rfc2136-zone", "", "Zone on the DNS server which contains the base domains. The base domain of each cluster is used if empty.")
+	flag.StringVar(&rfc2136Config.TSIGKeyName, "rfc2136-tsig-key-name", "", "Name of the TSIG key used to sign RFC 2136 updates.")
+	flag.StringVar(&rfc2136Config.TSIGAlgorithm, "rfc2136-tsig-algorithm", "hmac-sha256", "Algorithm of the TSIG key used to sign RFC 2136 updates.")
 	flag.StringVar(&roleArn, "role-arn", "", "ARN of the role to assume for the AWS API calls.")
+	flag.StringVar(&allowedClusterRoles, "allowed-cluster-roles", "",
+		"Comma separated list of role ARNs and AWS account IDs which clusters can select with the dns.giantswarm.io/aws-role-arn annotation. The annotation is rejected if empty.")
 	flag.StringVar(&staticBastionIP, "static-bastion-ip", "", "IP address of static bastion machine for all clusters.")
+	flag.StringVar(&wildcardIngress, "wildcard-ingress-service", "",
+		"Name of the ingress Service of the workload clusters which backs the wildcard record. The first ingress Service by name is used if empty.")
+	defaultDiscovery := dns.DefaultServiceDiscovery()
+	flag.StringVar(&ingressNamespaces, "ingress-namespaces", strings.Join(defaultDiscovery.IngressNamespaces, ","),
+		"Comma separated list of namespaces of the workload clusters which contain the ingress controller Services.")
+	flag.StringVar(&ingressSelector, "ingress-selector", defaultDiscovery.IngressSelector,
+		"Label selector of the ingress controller Services of the workload clusters.")
+	flag.StringVar(&gatewayNamespaces, "gateway-namespaces", strings.Join(defaultDiscovery.GatewayNamespaces, ","),
+		"Comma separated list of namespaces of the workload clusters which contain the gateway Services.")
+	flag.StringVar(&gatewaySelector, "gateway-selector", defaultDiscovery.GatewaySelector,
+		"Label selector of the gateway Services of the workload clusters. The Services also need the external-dns annotations.")
+	defaultTTLs := dns.DefaultRecordTTLs()
+	flag.Int64Var(&recordTTLs.Delegation, "ttl-delegation", defaultTTLs.Delegation, "TTL in seconds of the NS records which delegate the cluster zones.")
+	flag.Int64Var(&recordTTLs.API, "ttl-api", defaultTTLs.API, "TTL in seconds of the api records.")
+	flag.Int64Var(&recordTTLs.Bastion, "ttl-bastion", defaultTTLs.Bastion, "TTL in seconds of the bastion records.")
+	flag.Int64Var(&recordTTLs.Ingress, "ttl-ingress", defaultTTLs.Ingress, "TTL in seconds of the records of the ingress controller Services.")
+	flag.Int64Var(&recordTTLs.Wildcard, "ttl-wildcard", defaultTTLs.Wildcard, "TTL in seconds of the wildcard records.")
+	flag.Int64Var(&recordTTLs.Gateway, "ttl-gateway", defaultTTLs.Gateway, "TTL in seconds of the records of the gateway Services and the Gateway API Gateways.")
+	defaultNaming := scope.DefaultNamingTemplates()
+	flag.StringVar(&namingTemplates.ClusterDomain, "cluster-domain-template", defaultNaming.ClusterDomain,
+		"Go template of the cluster domains, e.g. {{ .Cluster.Name }}.{{ .Namespace }}.{{ .BaseDomain }}. It can use .Cluster, .Namespace, .BaseDomain and .ManagementCluster.")
+	flag.StringVar(&namingTemplates.API, "api-record-template", defaultNaming.API, "Go template of the names of the api records. It can also use .ClusterDomain.")
+	flag.StringVar(&namingTemplates.Bastion, "bastion-record-template", defaultNaming.Bastion, "Go template of the names of the bastion records. It can also use .ClusterDomain.")
+	flag.StringVar(&namingTemplates.Ingress, "ingress-record-template", defaultNaming.Ingress,
+		"Go template of the names of the records of the wildcard ingress controller Services. It can also use .ClusterDomain.")
+	flag.StringVar(&namingTemplates.Wildcard, "wildcard-record-template", defaultNaming.Wildcard, "Go template of the names of the wildcard records. It can also use .ClusterDomain.")
+	flag.BoolVar(&waitForPropagation, "wait-for-change-propagation", false,
+		"Track the submitted Route53 changes until they are INSYNC and report them with the DNSPropagated condition of the Cluster.")
 
 	flag.Parse()
 
+	// The TSIG secret is read from the environment to keep it out of the process arguments.
+	rfc2136Config.TSIGSecret = os.Getenv("RFC2136_TSIG_SECRET")
+
+	allowedBaseDomains := append([]string{baseDomain}, splitList(baseDomains)...)
+
+	infrastructureKinds := controllers.InfrastructureKinds{
+		Enabled:  splitList(enabledInfraKinds),
+		Disabled: splitList(disabledInfraKinds),
+	}
+
+	serviceDiscovery := cloud.ServiceDiscovery{
+		IngressNamespaces: splitList(ingressNamespaces),
+		IngressSelector:   ingressSelector,
+		GatewayNamespaces: splitList(gatewayNamespaces),
+		GatewaySelector:   gatewaySelector,
+	}
+
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
 	// Initialize the cache with a custom configuration
@@ -78,8 +173,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Only the kubeconfig secrets of workload clusters are cached.
+	kubeconfigSecrets, err := labels.NewRequirement(capi.ClusterNameLabel, selection.Exists, nil)
+	if err != nil {
+		setupLog.Error(err, "unable to create the kubeconfig secret selector")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Secret{}: {Label: labels.NewSelector().Add(*kubeconfigSecrets)},
+			},
+		},
 		Metrics: metricsserver.Options{
 			BindAddress: metricsAddr,
 		},
@@ -91,16 +198,68 @@ func main() {
 		os.Exit(1)
 	}
 
+	clusterTracker, err := remote.NewClusterTracker(remote.ClusterTrackerConfig{
+		SecretReader: mgr.GetClient(),
+		Namespaces:   serviceDiscovery.Namespaces(),
+		ClusterNamespaces: func(cluster *capi.Cluster) []string {
+			// Clusters with invalid annotations fall back to the default namespaces.
+			discovery, err := scope.ResolveServiceDiscovery(cluster, serviceDiscovery)
+			if err != nil {
+				return nil
+			}
+			return discovery.Namespaces()
+		},
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to create the workload cluster tracker")
+		os.Exit(1)
+	}
+	if err = mgr.Add(clusterTracker); err != nil {
+		setupLog.Error(err, "unable to add the workload cluster tracker")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.ClusterReconciler{
-		Client:            mgr.GetClient(),
-		BaseDomain:        baseDomain,
-		ManagementCluster: managementCluster,
-		RoleArn:           roleArn,
-		StaticBastionIP:   staticBastionIP,
+		Client:                   mgr.GetClient(),
+		AllowedClusterRoles:      splitList(allowedClusterRoles),
+		AWSCredentials:           awsCredentials,
+		BaseDomains:              allowedBaseDomains,
+		ClusterTracker:           clusterTracker,
+		DNSProvider:              dnsProvider,
+		DryRun:                   dryRun,
+		InfrastructureKinds:      infrastructureKinds,
+		ManagementCluster:        managementCluster,
+		NamingTemplates:          namingTemplates,
+		Recorder:                 mgr.GetEventRecorder("dns-operator-route53"),
+		RFC2136:                  rfc2136Config,
+		RecordTTLs:               recordTTLs,
+		RoleArn:                  roleArn,
+		ServiceDiscovery:         serviceDiscovery,
+		StaticBastionIP:          staticBastionIP,
+		WaitForChangePropagation: waitForPropagation,
+		WildcardIngressService:   wildcardIngress,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Cluster")
 		os.Exit(1)
 	}
+	if err = (&controllers.DNSRecordReconciler{
+		Client:              mgr.GetClient(),
+		AllowedClusterRoles: splitList(allowedClusterRoles),
+		AWSCredentials:      awsCredentials,
+		BaseDomains:         allowedBaseDomains,
+		DNSProvider:         dnsProvider,
+		DryRun:              dryRun,
+		InfrastructureKinds: infrastructureKinds,
+		ManagementCluster:   managementCluster,
+		NamingTemplates:     namingTemplates,
+		Recorder:            mgr.GetEventRecorder("dns-operator-route53"),
+		RFC2136:             rfc2136Config,
+		RoleArn:             roleArn,
+		StaticBastionIP:     staticBastionIP,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DNSRecord")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -109,3 +268,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma separated flag value and drops empty items.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
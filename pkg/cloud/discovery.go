@@ -0,0 +1,26 @@
+package cloud
+
+import (
+	"slices"
+)
+
+// ServiceDiscovery selects the ingress and gateway Services of a workload
+// cluster whose load balancers get DNS records.
+type ServiceDiscovery struct {
+	// IngressNamespaces are the namespaces of the ingress controller Services.
+	IngressNamespaces []string
+	// IngressSelector is the label selector of the ingress controller Services.
+	IngressSelector string
+	// GatewayNamespaces are the namespaces of the gateway Services.
+	GatewayNamespaces []string
+	// GatewaySelector is the label selector of the gateway Services. An empty
+	// selector selects all Services with the external-dns annotations.
+	GatewaySelector string
+}
+
+// Namespaces returns the sorted namespaces of the ingress and gateway Services.
+func (d ServiceDiscovery) Namespaces() []string {
+	namespaces := slices.Concat(d.IngressNamespaces, d.GatewayNamespaces)
+	slices.Sort(namespaces)
+	return slices.Compact(namespaces)
+}
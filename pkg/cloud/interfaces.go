@@ -43,7 +43,17 @@ type ClusterScoper interface {
 	ManagementCluster() string
 	// Name returns the CAPI cluster name.
 	Name() string
+	// RecordNames returns the names of the records of the cluster.
+	RecordNames() RecordNames
+	// RecordTTLs returns the TTLs of the records of the cluster.
+	RecordTTLs() RecordTTLs
+	// ServiceDiscovery returns the selection of the ingress and gateway Services
+	// of the workload cluster.
+	ServiceDiscovery() ServiceDiscovery
 	// WildcardCNAMETarget returns the override value for the wildcard CNAME record,
-	// or empty string to use the default ingress.<clusterdomain> value.
+	// or empty string to use the hostname of the wildcard ingress service.
 	WildcardCNAMETarget() string
+	// WildcardIngressService returns the name of the ingress service which backs
+	// the wildcard record, or empty string to use the first ingress service.
+	WildcardIngressService() string
 }
@@ -8,7 +8,9 @@ import (
 
 	"github.com/giantswarm/dns-operator-route53/pkg/cloud/awserrors"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
@@ -26,6 +28,15 @@ const (
 	metricStatusCodeLabel    = "status_code"
 	metricErrorCodeLabel     = "error_code"
 	metricCacheSubsystem     = "route53cache"
+	metricSTSSubsystem       = "sts"
+	metricRoute53Subsystem   = "route53"
+	metricRoleARNLabel       = "role_arn"
+	metricResultLabel        = "result"
+	metricDNSSubsystem       = "dns"
+	metricKindLabel          = "kind"
+	metricNamespaceLabel     = "namespace"
+	metricNameLabel          = "name"
+	metricActionLabel        = "action"
 )
 
 var (
@@ -65,6 +76,27 @@ var (
 		Name:      "size",
 		Help:      "Size of cache in bytes",
 	}, []string{metricControllerLabel})
+	stsAssumeRoleCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: metricSTSSubsystem,
+		Name:      "assume_role_total",
+		Help:      "Total number of STS AssumeRole and AssumeRoleWithWebIdentity calls",
+	}, []string{metricRoleARNLabel, metricResultLabel})
+	stsCredentialsExpiration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: metricSTSSubsystem,
+		Name:      "credentials_expiration_timestamp_seconds",
+		Help:      "Expiration time of the cached assumed role credentials",
+	}, []string{metricRoleARNLabel})
+	route53ChangePropagation = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Subsystem: metricRoute53Subsystem,
+		Name:      "change_propagation_seconds",
+		Help:      "Time from the submission of a Route53 change until it was observed as INSYNC",
+		Buckets:   []float64{5, 10, 20, 30, 45, 60, 90, 120, 180, 300, 600},
+	})
+	dnsDryRunPlannedChanges = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: metricDNSSubsystem,
+		Name:      "dry_run_planned_changes",
+		Help:      "Number of DNS changes planned but not applied in dry-run mode in the last reconciliation of an object",
+	}, []string{metricKindLabel, metricNamespaceLabel, metricNameLabel, metricActionLabel})
 )
 
 func init() {
@@ -75,6 +107,33 @@ func init() {
 	metrics.Registry.MustRegister(cacheHits)
 	metrics.Registry.MustRegister(cacheMisses)
 	metrics.Registry.MustRegister(cacheSize)
+	metrics.Registry.MustRegister(stsAssumeRoleCount)
+	metrics.Registry.MustRegister(stsCredentialsExpiration)
+	metrics.Registry.MustRegister(route53ChangePropagation)
+	metrics.Registry.MustRegister(dnsDryRunPlannedChanges)
+}
+
+// ObserveChangePropagation records the propagation time of a Route53 change.
+func ObserveChangePropagation(d time.Duration) {
+	route53ChangePropagation.Observe(d.Seconds())
+}
+
+// SetDryRunPlannedChanges records the number of planned changes per action of
+// the last dry-run reconciliation of an object.
+func SetDryRunPlannedChanges(kind, namespace, name string, counts map[string]int) {
+	for action, count := range counts {
+		dnsDryRunPlannedChanges.WithLabelValues(kind, namespace, name, action).Set(float64(count))
+	}
+}
+
+// DeleteDryRunPlannedChanges removes the planned changes of an object, e.g.
+// when it is deleted or dry-run mode was disabled.
+func DeleteDryRunPlannedChanges(kind, namespace, name string) {
+	dnsDryRunPlannedChanges.DeletePartialMatch(prometheus.Labels{
+		metricKindLabel:      kind,
+		metricNamespaceLabel: namespace,
+		metricNameLabel:      name,
+	})
 }
 
 func CaptureRequestMetrics(controller string) func(r *request.Request) {
@@ -103,6 +162,37 @@ func CaptureRequestMetrics(controller string) func(r *request.Request) {
 	}
 }
 
+// CaptureAssumeRoleMetrics counts the STS AssumeRole and AssumeRoleWithWebIdentity
+// calls and tracks the expiration of the returned credentials per role.
+func CaptureAssumeRoleMetrics(r *request.Request) {
+	var roleARN string
+	switch input := r.Params.(type) {
+	case *sts.AssumeRoleInput:
+		roleARN = aws.StringValue(input.RoleArn)
+	case *sts.AssumeRoleWithWebIdentityInput:
+		roleARN = aws.StringValue(input.RoleArn)
+	default:
+		return
+	}
+
+	if r.Error != nil {
+		stsAssumeRoleCount.WithLabelValues(roleARN, "error").Inc()
+		return
+	}
+	stsAssumeRoleCount.WithLabelValues(roleARN, "success").Inc()
+
+	var creds *sts.Credentials
+	switch output := r.Data.(type) {
+	case *sts.AssumeRoleOutput:
+		creds = output.Credentials
+	case *sts.AssumeRoleWithWebIdentityOutput:
+		creds = output.Credentials
+	}
+	if creds != nil && creds.Expiration != nil {
+		stsCredentialsExpiration.WithLabelValues(roleARN).Set(float64(creds.Expiration.Unix()))
+	}
+}
+
 func endpointToService(endpoint string) string {
 	endpointURL, err := url.Parse(endpoint)
 	// If possible extract the service name, else return entire endpoint address
@@ -0,0 +1,27 @@
+package cloud
+
+// NamingTemplates are the Go templates of the cluster domain and of the names
+// of the records published for a cluster.
+type NamingTemplates struct {
+	// ClusterDomain is the template of the cluster domain, which has to be
+	// below the base domain.
+	ClusterDomain string
+	// API is the template of the name of the API record.
+	API string
+	// Bastion is the template of the name of the bastion record.
+	Bastion string
+	// Ingress is the template of the name of the record of the wildcard
+	// ingress controller Service.
+	Ingress string
+	// Wildcard is the template of the name of the wildcard record.
+	Wildcard string
+}
+
+// RecordNames are the fully qualified names of the records published for a
+// cluster, rendered from the NamingTemplates.
+type RecordNames struct {
+	API      string
+	Bastion  string
+	Ingress  string
+	Wildcard string
+}
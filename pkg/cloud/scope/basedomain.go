@@ -0,0 +1,91 @@
+package scope
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+// ResolveBaseDomain returns the base domain of the cluster. It is selected
+// with the base domain annotation of the cluster or the base domain label of
+// its namespace, the annotation takes precedence. The first allowed base
+// domain is used if neither is set. A selected base domain which is not
+// allowed is rejected with invalidConfigError.
+//
+// Once the zone of the cluster was created, the recorded base domain is used.
+// Selecting another base domain afterwards is rejected with
+// invalidConfigError, as it would orphan the zone in the recorded base
+// domain. It is ignored during deletion, so the recorded zone is deleted.
+func ResolveBaseDomain(ctx context.Context, ctrlClient client.Client, cluster *capi.Cluster, allowed []string) (string, error) {
+	if len(allowed) == 0 {
+		return "", microerror.Maskf(invalidConfigError, "allowed base domains must not be empty")
+	}
+
+	selected := cluster.GetAnnotations()[key.AnnotationBaseDomain]
+	if selected == "" {
+		namespace := &corev1.Namespace{}
+		if err := ctrlClient.Get(ctx, client.ObjectKey{Name: cluster.GetNamespace()}, namespace); err != nil {
+			return "", microerror.Mask(err)
+		}
+		selected = namespace.GetLabels()[key.AnnotationBaseDomain]
+	}
+
+	provisioned := cluster.GetAnnotations()[key.AnnotationProvisionedBaseDomain]
+	if provisioned != "" {
+		if selected != "" && normalizeDomain(selected) != normalizeDomain(provisioned) && cluster.DeletionTimestamp.IsZero() {
+			return "", microerror.Maskf(invalidConfigError, "base domain %q can't be selected, the zone of the cluster was created in base domain %q", normalizeDomain(selected), normalizeDomain(provisioned))
+		}
+		return allowedBaseDomain(provisioned, allowed)
+	}
+
+	if selected == "" {
+		return allowed[0], nil
+	}
+
+	return allowedBaseDomain(selected, allowed)
+}
+
+func allowedBaseDomain(selected string, allowed []string) (string, error) {
+	selected = normalizeDomain(selected)
+	for _, baseDomain := range allowed {
+		if normalizeDomain(baseDomain) == selected {
+			return baseDomain, nil
+		}
+	}
+
+	return "", microerror.Maskf(invalidConfigError, "base domain %q is not one of the allowed base domains %v", selected, allowed)
+}
+
+// ValidateBaseDomains returns invalidConfigError if no base domain is allowed
+// or a base domain is allowed twice.
+func ValidateBaseDomains(allowed []string) error {
+	if len(allowed) == 0 {
+		return microerror.Maskf(invalidConfigError, "allowed base domains must not be empty")
+	}
+
+	var seen []string
+	for _, baseDomain := range allowed {
+		normalized := normalizeDomain(baseDomain)
+		if normalized == "" {
+			return microerror.Maskf(invalidConfigError, "allowed base domains must not contain empty domains")
+		}
+		if slices.Contains(seen, normalized) {
+			return microerror.Maskf(invalidConfigError, "base domain %q is allowed twice", baseDomain)
+		}
+		seen = append(seen, normalized)
+	}
+
+	return nil
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
@@ -0,0 +1,120 @@
+package scope
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+func Test_ResolveBaseDomain(t *testing.T) {
+	allowed := []string{"gigantic.io", "customer.example.com"}
+
+	testCases := []struct {
+		name               string
+		clusterAnnotations map[string]string
+		namespaceLabels    map[string]string
+		deleted            bool
+
+		expectedBaseDomain string
+		expectedError      func(error) bool
+	}{
+		{
+			name:               "case 0: default base domain",
+			expectedBaseDomain: "gigantic.io",
+		},
+		{
+			name:               "case 1: base domain from cluster annotation",
+			clusterAnnotations: map[string]string{key.AnnotationBaseDomain: "customer.example.com"},
+			namespaceLabels:    map[string]string{key.AnnotationBaseDomain: "gigantic.io"},
+			expectedBaseDomain: "customer.example.com",
+		},
+		{
+			name:               "case 2: base domain from namespace label",
+			namespaceLabels:    map[string]string{key.AnnotationBaseDomain: "customer.example.com"},
+			expectedBaseDomain: "customer.example.com",
+		},
+		{
+			name:               "case 3: base domain is normalized",
+			clusterAnnotations: map[string]string{key.AnnotationBaseDomain: "Customer.Example.com."},
+			expectedBaseDomain: "customer.example.com",
+		},
+		{
+			name:               "case 4: base domain is not allowed",
+			clusterAnnotations: map[string]string{key.AnnotationBaseDomain: "evil.example.org"},
+			expectedError:      IsInvalidConfig,
+		},
+		{
+			name:               "case 5: provisioned base domain",
+			clusterAnnotations: map[string]string{key.AnnotationProvisionedBaseDomain: "customer.example.com"},
+			expectedBaseDomain: "customer.example.com",
+		},
+		{
+			name: "case 6: provisioned base domain is selected",
+			clusterAnnotations: map[string]string{
+				key.AnnotationBaseDomain:            "customer.example.com.",
+				key.AnnotationProvisionedBaseDomain: "customer.example.com",
+			},
+			expectedBaseDomain: "customer.example.com",
+		},
+		{
+			name:               "case 7: selected base domain differs from provisioned base domain",
+			clusterAnnotations: map[string]string{key.AnnotationProvisionedBaseDomain: "customer.example.com"},
+			namespaceLabels:    map[string]string{key.AnnotationBaseDomain: "gigantic.io"},
+			expectedError:      IsInvalidConfig,
+		},
+		{
+			name: "case 8: provisioned base domain is used during deletion",
+			clusterAnnotations: map[string]string{
+				key.AnnotationBaseDomain:            "gigantic.io",
+				key.AnnotationProvisionedBaseDomain: "customer.example.com",
+			},
+			deleted:            true,
+			expectedBaseDomain: "customer.example.com",
+		},
+		{
+			name:               "case 9: provisioned base domain is no longer allowed",
+			clusterAnnotations: map[string]string{key.AnnotationProvisionedBaseDomain: "old.example.com"},
+			deleted:            true,
+			expectedError:      IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = capi.AddToScheme(scheme)
+
+			cluster := &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "org-test", Annotations: tc.clusterAnnotations}}
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "org-test", Labels: tc.namespaceLabels}}
+			ctrlClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, namespace).Build()
+			if tc.deleted {
+				cluster.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			}
+
+			baseDomain, err := ResolveBaseDomain(context.Background(), ctrlClient, cluster, allowed)
+
+			switch {
+			case tc.expectedError == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expectedError != nil && !tc.expectedError(err):
+				t.Fatalf("expected matching error, got %v", err)
+			case tc.expectedError != nil:
+				return
+			}
+
+			if baseDomain != tc.expectedBaseDomain {
+				t.Fatalf("expected base domain %q, got %q", tc.expectedBaseDomain, baseDomain)
+			}
+		})
+	}
+}
@@ -2,12 +2,12 @@ package scope
 
 import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsclient "github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/component-base/version"
 
-	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
 	awsmetrics "github.com/giantswarm/dns-operator-route53/pkg/cloud/metrics"
 	"github.com/giantswarm/dns-operator-route53/pkg/record"
 )
@@ -18,8 +18,8 @@ type AWSClients struct {
 }
 
 // NewRoute53Client creates a new Route53 API client for a given session
-func NewRoute53Client(session cloud.Session, target runtime.Object) *route53.Route53 {
-	Route53Client := route53.New(session.Session(), nil)
+func NewRoute53Client(session awsclient.ConfigProvider, target runtime.Object) *route53.Route53 {
+	Route53Client := route53.New(session, nil)
 	Route53Client.Handlers.Build.PushFrontNamed(getUserAgentHandler())
 	Route53Client.Handlers.CompleteAttempt.PushFront(awsmetrics.CaptureRequestMetrics("dns-operator-route53"))
 	Route53Client.Handlers.Complete.PushBack(recordAWSPermissionsIssue(target))
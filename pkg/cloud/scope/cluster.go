@@ -4,22 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/aws/aws-sdk-go/aws"
 	awsclient "github.com/aws/aws-sdk-go/aws/client"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/sts"
-	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/client-go/rest"
 	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	"github.com/giantswarm/k8sclient/v8/pkg/k8sclient"
-	"github.com/giantswarm/k8sclient/v8/pkg/k8srestconfig"
 	"github.com/giantswarm/microerror"
-	"github.com/giantswarm/micrologger"
 
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
 	"github.com/giantswarm/dns-operator-route53/pkg/key"
 )
 
@@ -29,14 +21,34 @@ const (
 	KubeConfigSecretKey    = "value"
 )
 
+// ClusterClientGetter returns clients for workload clusters.
+type ClusterClientGetter interface {
+	GetClient(ctx context.Context, cluster *capi.Cluster) (client.Client, error)
+}
+
 // ClusterScopeParams defines the input parameters used to create a new Scope.
+// RoleArn is assumed for the base hosted zone and ClusterRole for the cluster
+// hosted zone. The cluster hosted zone falls back to RoleArn if ClusterRole is empty.
+// Both roles are assumed with the operator Credentials. The workload cluster
+// client is taken from ClusterClients. WildcardIngressService is the default
+// for clusters without the dns.giantswarm.io/wildcard-ingress-service annotation.
+// RecordTTLs and ServiceDiscovery are resolved for the cluster. The cluster
+// domain and the record names are rendered from the NamingTemplates, the
+// provisioned cluster domain annotation takes precedence over the template.
 type ClusterScopeParams struct {
-	BaseDomain            string
-	Cluster               *capi.Cluster
-	InfrastructureCluster *unstructured.Unstructured
-	ManagementCluster     string
-	RoleArn               string
-	StaticBastionIP       string
+	BaseDomain             string
+	Cluster                *capi.Cluster
+	ClusterClients         ClusterClientGetter
+	ClusterRole            AWSRole
+	Credentials            AWSCredentials
+	InfrastructureCluster  *unstructured.Unstructured
+	ManagementCluster      string
+	NamingTemplates        cloud.NamingTemplates
+	RecordTTLs             cloud.RecordTTLs
+	RoleArn                string
+	ServiceDiscovery       cloud.ServiceDiscovery
+	StaticBastionIP        string
+	WildcardIngressService string
 }
 
 // NewClusterScope creates a new Scope from the supplied parameters.
@@ -50,59 +62,63 @@ func NewClusterScope(ctx context.Context, params ClusterScopeParams) (*ClusterSc
 		return nil, microerror.Maskf(invalidConfigError, "failed to generate new scope from nil InfrastructureCluster")
 	}
 
-	awsSession, err := session.NewSession()
+	clusterDomain, recordNames, err := renderNames(params.NamingTemplates, namingData{
+		Cluster:           params.Cluster,
+		Namespace:         params.Cluster.Namespace,
+		BaseDomain:        params.BaseDomain,
+		ClusterDomain:     params.Cluster.GetAnnotations()[key.AnnotationProvisionedClusterDomain],
+		ManagementCluster: params.ManagementCluster,
+	})
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
-	if params.RoleArn != "" {
-		// Assume Role
-		stsSvc := sts.New(awsSession)
-
-		assumeRoleOutput, err := stsSvc.AssumeRole(&sts.AssumeRoleInput{
-			RoleArn:         aws.String(params.RoleArn),
-			RoleSessionName: aws.String(fmt.Sprintf("dns-operator-route53-%s-%s", params.ManagementCluster, params.Cluster.GetName())),
-		})
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
+	baseZoneSession, err := sharedSession(ctx, params.Credentials, AWSRole{ARN: params.RoleArn}, params.ManagementCluster)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-		// Use the temporary credentials from the AssumeRole response
-		creds := credentials.NewStaticCredentials(
-			*assumeRoleOutput.Credentials.AccessKeyId,
-			*assumeRoleOutput.Credentials.SecretAccessKey,
-			*assumeRoleOutput.Credentials.SessionToken,
-		)
-
-		// Create a new session with the assumed role credentials
-		awsSession, err = session.NewSession(&aws.Config{
-			Credentials: creds,
-		})
+	awsSession := baseZoneSession
+	if params.ClusterRole.ARN != "" {
+		awsSession, err = sharedSession(ctx, params.Credentials, params.ClusterRole, params.ManagementCluster)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
 	}
 
 	return &ClusterScope{
-		session:           awsSession,
-		baseDomain:        params.BaseDomain,
-		cluster:           params.Cluster,
-		infraCluster:      params.InfrastructureCluster,
-		managementCluster: params.ManagementCluster,
-		staticBastionIP:   params.StaticBastionIP,
+		clusterClients:         params.ClusterClients,
+		session:                awsSession,
+		baseZoneSession:        baseZoneSession,
+		baseDomain:             params.BaseDomain,
+		cluster:                params.Cluster,
+		clusterDomain:          clusterDomain,
+		infraCluster:           params.InfrastructureCluster,
+		managementCluster:      params.ManagementCluster,
+		recordNames:            recordNames,
+		recordTTLs:             params.RecordTTLs,
+		serviceDiscovery:       params.ServiceDiscovery,
+		staticBastionIP:        params.StaticBastionIP,
+		wildcardIngressService: params.WildcardIngressService,
 	}, nil
 }
 
 // ClusterScope defines the basic context for an actuator to operate upon.
 type ClusterScope struct {
-	k8sClient client.Client
-	session   awsclient.ConfigProvider
-
-	baseDomain        string
-	cluster           *capi.Cluster
-	infraCluster      *unstructured.Unstructured
-	managementCluster string
-	staticBastionIP   string
+	clusterClients  ClusterClientGetter
+	session         awsclient.ConfigProvider
+	baseZoneSession awsclient.ConfigProvider
+
+	baseDomain             string
+	cluster                *capi.Cluster
+	clusterDomain          string
+	infraCluster           *unstructured.Unstructured
+	managementCluster      string
+	recordNames            cloud.RecordNames
+	recordTTLs             cloud.RecordTTLs
+	serviceDiscovery       cloud.ServiceDiscovery
+	staticBastionIP        string
+	wildcardIngressService string
 }
 
 // APIEndpoint returns the Cluster Kubernetes API endpoint.
@@ -150,20 +166,21 @@ func (s *ClusterScope) ManagementCluster() string {
 
 // ClusterK8sClient returns a client to interact with the cluster.
 func (s *ClusterScope) ClusterK8sClient(ctx context.Context) (client.Client, error) {
-	if s.k8sClient == nil {
-		var err error
-		s.k8sClient, err = s.getClusterK8sClient(ctx)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
+	if s.clusterClients == nil {
+		return nil, microerror.Maskf(invalidConfigError, "no workload cluster clients configured for cluster %s", s.Name())
+	}
+
+	k8sClient, err := s.clusterClients.GetClient(ctx, s.cluster)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
-	return s.k8sClient, nil
+	return k8sClient, nil
 }
 
-// ClusterDomain returns the cluster domain.
+// ClusterDomain returns the cluster domain rendered from the naming templates.
 func (s *ClusterScope) ClusterDomain() string {
-	return fmt.Sprintf("%s.%s", s.Name(), s.baseDomain)
+	return s.clusterDomain
 }
 
 // Name returns the cluster name.
@@ -171,6 +188,23 @@ func (s *ClusterScope) Name() string {
 	return s.cluster.Name
 }
 
+// RecordNames returns the names of the records of the cluster rendered from
+// the naming templates.
+func (s *ClusterScope) RecordNames() cloud.RecordNames {
+	return s.recordNames
+}
+
+// RecordTTLs returns the TTLs of the records of the cluster.
+func (s *ClusterScope) RecordTTLs() cloud.RecordTTLs {
+	return s.recordTTLs
+}
+
+// ServiceDiscovery returns the selection of the ingress and gateway Services
+// of the workload cluster.
+func (s *ClusterScope) ServiceDiscovery() cloud.ServiceDiscovery {
+	return s.serviceDiscovery
+}
+
 // WildcardCNAMETarget returns the override value for the wildcard CNAME record
 // from the network.giantswarm.io/wildcard-cname-target annotation,
 // or empty string if not set.
@@ -182,79 +216,22 @@ func (s *ClusterScope) WildcardCNAMETarget() string {
 	return fmt.Sprintf("%s.%s", target, s.ClusterDomain())
 }
 
-// Session returns the AWS SDK session. Used for creating cluster client.
-func (s *ClusterScope) Session() awsclient.ConfigProvider {
-	return s.session
-}
-
-func (s *ClusterScope) getClusterK8sClient(ctx context.Context) (client.Client, error) {
-	newLogger, err := micrologger.New(micrologger.Config{})
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	kubeconfig, err := s.getClusterKubeConfig(ctx, newLogger)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	config := k8srestconfig.Config{
-		Logger:     newLogger,
-		KubeConfig: kubeconfig,
+// WildcardIngressService returns the name of the ingress service which backs
+// the wildcard record from the dns.giantswarm.io/wildcard-ingress-service
+// annotation, or the configured default if the annotation is not set.
+func (s *ClusterScope) WildcardIngressService() string {
+	if name := s.cluster.Annotations[key.AnnotationWildcardIngressService]; name != "" {
+		return name
 	}
-
-	return getK8sClient(config, newLogger)
+	return s.wildcardIngressService
 }
 
-func (s *ClusterScope) getClusterKubeConfig(ctx context.Context, logger micrologger.Logger) (string, error) {
-	config := k8srestconfig.Config{
-		Logger:    logger,
-		InCluster: true,
-	}
-
-	k8sClient, err := getK8sClient(config, logger)
-	if err != nil {
-		return "", microerror.Mask(err)
-	}
-
-	var secret corev1.Secret
-
-	o := client.ObjectKey{
-		Name:      fmt.Sprintf("%s%s", s.Name(), KubeConfigSecretSuffix),
-		Namespace: s.cluster.Namespace,
-	}
-
-	if err := k8sClient.Get(ctx, o, &secret); err != nil {
-		return "", microerror.Mask(err)
-	}
-
-	return string(secret.Data[KubeConfigSecretKey]), nil
+// Session returns the AWS SDK session. Used for creating cluster client.
+func (s *ClusterScope) Session() awsclient.ConfigProvider {
+	return s.session
 }
 
-func getK8sClient(config k8srestconfig.Config, logger micrologger.Logger) (client.Client, error) {
-	var restConfig *rest.Config
-	var err error
-	{
-		restConfig, err = k8srestconfig.New(config)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-	}
-
-	var ctrlClient client.Client
-	{
-		c := k8sclient.ClientsConfig{
-			Logger:     logger,
-			RestConfig: restConfig,
-		}
-
-		k8sClients, err := k8sclient.NewClients(c)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-
-		ctrlClient = k8sClients.CtrlClient()
-	}
-
-	return ctrlClient, nil
+// BaseZoneSession returns the AWS SDK session for the account of the base hosted zone.
+func (s *ClusterScope) BaseZoneSession() awsclient.ConfigProvider {
+	return s.baseZoneSession
 }
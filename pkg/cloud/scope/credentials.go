@@ -0,0 +1,217 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsclient "github.com/aws/aws-sdk-go/aws/client"
+	awscredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/awserrors"
+	awsmetrics "github.com/giantswarm/dns-operator-route53/pkg/cloud/metrics"
+)
+
+// assumeRoleExpiryWindow makes the assumed role credentials refresh this long
+// before they actually expire.
+const assumeRoleExpiryWindow = 5 * time.Minute
+
+const (
+	// AWSCredentialsSourceDefault uses the default credential chain of the AWS
+	// SDK, e.g. environment variables or a shared credentials file.
+	AWSCredentialsSourceDefault = "default"
+	// AWSCredentialsSourceWebIdentity exchanges a projected service account
+	// token for the credentials of a role, e.g. with IRSA.
+	AWSCredentialsSourceWebIdentity = "web-identity"
+)
+
+// AWSCredentials configures the credentials of the operator itself. Roles are
+// assumed on top of these credentials.
+type AWSCredentials struct {
+	Source string
+	// WebIdentityRoleARN is the role the web identity token is exchanged for.
+	WebIdentityRoleARN string
+	// WebIdentityTokenFile is the path of the web identity token.
+	WebIdentityTokenFile string
+}
+
+// Validate returns an error if the credentials are incomplete.
+func (c AWSCredentials) Validate() error {
+	switch c.Source {
+	case "", AWSCredentialsSourceDefault:
+		return nil
+	case AWSCredentialsSourceWebIdentity:
+		if c.WebIdentityRoleARN == "" {
+			return microerror.Maskf(invalidConfigError, "web identity role ARN must not be empty")
+		}
+		if c.WebIdentityTokenFile == "" {
+			return microerror.Maskf(invalidConfigError, "web identity token file must not be empty")
+		}
+		return nil
+	default:
+		return microerror.Maskf(invalidConfigError, "unknown AWS credentials source %#q", c.Source)
+	}
+}
+
+func (c AWSCredentials) webIdentity() bool {
+	return c.Source == AWSCredentialsSourceWebIdentity
+}
+
+// AWSRole is the AWS IAM role which is assumed to manage hosted zones.
+type AWSRole struct {
+	ARN        string
+	ExternalID string
+}
+
+type sessionKey struct {
+	credentials AWSCredentials
+	role        AWSRole
+}
+
+var (
+	sessionsMu sync.Mutex
+	// sessions contains the AWS sessions shared by all clusters, keyed by the
+	// operator credentials and the assumed role. The empty role holds the
+	// session without role.
+	sessions = map[sessionKey]*session.Session{}
+
+	// newAssumeRoler returns the STS client used to assume roles.
+	newAssumeRoler = func(p awsclient.ConfigProvider) stscreds.AssumeRoler {
+		return newSTSClient(p)
+	}
+	// newWebIdentityClient returns the STS client used to exchange web identity tokens.
+	newWebIdentityClient = func(p awsclient.ConfigProvider) stsiface.STSAPI {
+		return newSTSClient(p)
+	}
+)
+
+func newSTSClient(p awsclient.ConfigProvider) *sts.STS {
+	stsClient := sts.New(p)
+	stsClient.Handlers.Build.PushFrontNamed(getUserAgentHandler())
+	stsClient.Handlers.CompleteAttempt.PushFront(awsmetrics.CaptureRequestMetrics("dns-operator-route53"))
+	stsClient.Handlers.Complete.PushBack(awsmetrics.CaptureAssumeRoleMetrics)
+	return stsClient
+}
+
+// sharedSession returns the AWS session for the given role. The session and its
+// assumed role credentials are shared by all clusters, so STS is only called
+// again shortly before the credentials expire.
+func sharedSession(ctx context.Context, credentials AWSCredentials, role AWSRole, managementCluster string) (*session.Session, error) {
+	awsSession, err := getOrCreateSession(credentials, role, managementCluster)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if role.ARN != "" || credentials.webIdentity() {
+		// Returns the cached credentials and only assumes the role if they are expired.
+		_, err = awsSession.Config.Credentials.GetWithContext(ctx)
+		if code, ok := credentialsErrorCode(err); ok && (code == "AccessDenied" || code == sts.ErrCodeInvalidIdentityTokenException) {
+			return nil, microerror.Maskf(accessDeniedError, "failed to assume role %s: %s", assumedRoleARN(credentials, role), err)
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return awsSession, nil
+}
+
+func getOrCreateSession(credentials AWSCredentials, role AWSRole, managementCluster string) (*session.Session, error) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	baseSession, err := getOrCreateBaseSession(credentials, managementCluster)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if role.ARN == "" {
+		return baseSession, nil
+	}
+
+	key := sessionKey{credentials: credentials, role: role}
+	if awsSession, ok := sessions[key]; ok {
+		return awsSession, nil
+	}
+
+	creds := stscreds.NewCredentialsWithClient(newAssumeRoler(baseSession), role.ARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = roleSessionName(managementCluster)
+		p.ExpiryWindow = assumeRoleExpiryWindow
+		if role.ExternalID != "" {
+			p.ExternalID = aws.String(role.ExternalID)
+		}
+	})
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Credentials: creds,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	sessions[key] = awsSession
+	return awsSession, nil
+}
+
+// getOrCreateBaseSession returns the session with the operator credentials.
+// Roles are assumed on top of it. sessionsMu must be held by the caller.
+func getOrCreateBaseSession(credentials AWSCredentials, managementCluster string) (*session.Session, error) {
+	key := sessionKey{credentials: credentials}
+	if awsSession, ok := sessions[key]; ok {
+		return awsSession, nil
+	}
+
+	awsSession, err := session.NewSession()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if credentials.webIdentity() {
+		provider := stscreds.NewWebIdentityRoleProviderWithOptions(
+			newWebIdentityClient(awsSession),
+			credentials.WebIdentityRoleARN,
+			roleSessionName(managementCluster),
+			stscreds.FetchTokenPath(credentials.WebIdentityTokenFile),
+			func(p *stscreds.WebIdentityRoleProvider) {
+				p.ExpiryWindow = assumeRoleExpiryWindow
+			},
+		)
+
+		awsSession, err = session.NewSession(&aws.Config{
+			Credentials: awscredentials.NewCredentials(provider),
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	sessions[key] = awsSession
+	return awsSession, nil
+}
+
+// credentialsErrorCode returns the AWS error code of a failed credentials
+// retrieval. Web identity errors wrap the error of the STS call.
+func credentialsErrorCode(err error) (string, bool) {
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == stscreds.ErrCodeWebIdentity {
+		return awserrors.Code(awsErr.OrigErr())
+	}
+	return awserrors.Code(err)
+}
+
+func assumedRoleARN(credentials AWSCredentials, role AWSRole) string {
+	if role.ARN != "" {
+		return role.ARN
+	}
+	return credentials.WebIdentityRoleARN
+}
+
+func roleSessionName(managementCluster string) string {
+	return fmt.Sprintf("dns-operator-route53-%s", managementCluster)
+}
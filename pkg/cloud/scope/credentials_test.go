@@ -0,0 +1,314 @@
+package scope
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsclient "github.com/aws/aws-sdk-go/aws/client"
+	awscredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+type fakeAssumeRoler struct {
+	mu         sync.Mutex
+	calls      int
+	err        error
+	expiration time.Duration
+}
+
+func (f *fakeAssumeRoler) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &sts.AssumeRoleOutput{
+		Credentials: &sts.Credentials{
+			AccessKeyId:     aws.String("access-key"),
+			SecretAccessKey: aws.String("secret-key"),
+			SessionToken:    aws.String(aws.StringValue(input.RoleSessionName)),
+			Expiration:      aws.Time(time.Now().Add(f.expiration)),
+		},
+	}, nil
+}
+
+// signingAssumeRoler retrieves the credentials of the session it was created
+// with before assuming a role, like the signer of the STS client does.
+type signingAssumeRoler struct {
+	*fakeAssumeRoler
+	credentials *awscredentials.Credentials
+}
+
+func (s signingAssumeRoler) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	if _, err := s.credentials.Get(); err != nil {
+		return nil, err
+	}
+	return s.fakeAssumeRoler.AssumeRole(input)
+}
+
+func withFakeAssumeRoler(t *testing.T, fake *fakeAssumeRoler) {
+	// Static credentials of the default credential chain.
+	t.Setenv("AWS_ACCESS_KEY_ID", "default-access-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "default-secret-key")
+
+	original := newAssumeRoler
+	newAssumeRoler = func(p awsclient.ConfigProvider) stscreds.AssumeRoler {
+		return signingAssumeRoler{fakeAssumeRoler: fake, credentials: p.ClientConfig(sts.EndpointsID).Config.Credentials}
+	}
+	sessions = map[sessionKey]*session.Session{}
+
+	t.Cleanup(func() {
+		newAssumeRoler = original
+		sessions = map[sessionKey]*session.Session{}
+	})
+}
+
+// fakeWebIdentity answers AssumeRoleWithWebIdentity calls without sending requests.
+type fakeWebIdentity struct {
+	mu     sync.Mutex
+	calls  int
+	err    error
+	tokens []string
+}
+
+func (f *fakeWebIdentity) client(p awsclient.ConfigProvider) stsiface.STSAPI {
+	stsClient := sts.New(p)
+	stsClient.Handlers.Clear()
+	stsClient.Handlers.Send.PushBack(func(r *request.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.calls++
+		f.tokens = append(f.tokens, aws.StringValue(r.Params.(*sts.AssumeRoleWithWebIdentityInput).WebIdentityToken))
+		if f.err != nil {
+			r.Error = f.err
+			return
+		}
+
+		r.Data.(*sts.AssumeRoleWithWebIdentityOutput).Credentials = &sts.Credentials{
+			AccessKeyId:     aws.String("web-identity-access-key"),
+			SecretAccessKey: aws.String("web-identity-secret-key"),
+			SessionToken:    aws.String("web-identity-session-token"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+		}
+	})
+	return stsClient
+}
+
+func withFakeWebIdentity(t *testing.T, fake *fakeWebIdentity) {
+	original := newWebIdentityClient
+	newWebIdentityClient = fake.client
+
+	t.Cleanup(func() {
+		newWebIdentityClient = original
+	})
+}
+
+func Test_sharedSession(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expiration  time.Duration
+		err         error
+		roleArns    []string
+		expectCalls int
+		expectError func(error) bool
+	}{
+		{
+			name:        "case 0: credentials are shared across reconciliations",
+			expiration:  time.Hour,
+			roleArns:    []string{"arn:aws:iam::123:role/dns", "arn:aws:iam::123:role/dns", "arn:aws:iam::123:role/dns"},
+			expectCalls: 1,
+		},
+		{
+			name:        "case 1: credentials are cached per role",
+			expiration:  time.Hour,
+			roleArns:    []string{"arn:aws:iam::123:role/a", "arn:aws:iam::123:role/b", "arn:aws:iam::123:role/a"},
+			expectCalls: 2,
+		},
+		{
+			name:        "case 2: credentials within the expiry window are refreshed",
+			expiration:  assumeRoleExpiryWindow - time.Minute,
+			roleArns:    []string{"arn:aws:iam::123:role/dns", "arn:aws:iam::123:role/dns"},
+			expectCalls: 2,
+		},
+		{
+			name:        "case 3: no role",
+			roleArns:    []string{"", ""},
+			expectCalls: 0,
+		},
+		{
+			name:        "case 4: access denied",
+			err:         awserr.New("AccessDenied", "not authorized", nil),
+			roleArns:    []string{"arn:aws:iam::123:role/dns"},
+			expectCalls: 1,
+			expectError: IsAccessDenied,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeAssumeRoler{err: tc.err, expiration: tc.expiration}
+			withFakeAssumeRoler(t, fake)
+
+			for _, roleArn := range tc.roleArns {
+				awsSession, err := sharedSession(context.Background(), AWSCredentials{}, AWSRole{ARN: roleArn}, "mc")
+				switch {
+				case tc.expectError == nil && err != nil:
+					t.Fatalf("unexpected error: %v", err)
+				case tc.expectError != nil && !tc.expectError(err):
+					t.Fatalf("expected matching error, got %v", err)
+				case tc.expectError == nil && awsSession == nil:
+					t.Fatalf("expected session")
+				}
+			}
+
+			if fake.calls != tc.expectCalls {
+				t.Fatalf("expected %d AssumeRole calls, got %d", tc.expectCalls, fake.calls)
+			}
+		})
+	}
+}
+
+func Test_sharedSession_WebIdentity(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("projected-token"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name                   string
+		err                    error
+		roleArns               []string
+		tokenFile              string
+		expectWebIdentityCalls int
+		expectAssumeRoleCalls  int
+		expectError            func(error) bool
+	}{
+		{
+			name:                   "case 0: web identity credentials are shared",
+			roleArns:               []string{"", ""},
+			tokenFile:              tokenFile,
+			expectWebIdentityCalls: 1,
+		},
+		{
+			name:                   "case 1: roles are assumed with the web identity credentials",
+			roleArns:               []string{"arn:aws:iam::123:role/a", "arn:aws:iam::456:role/b", "arn:aws:iam::123:role/a"},
+			tokenFile:              tokenFile,
+			expectWebIdentityCalls: 1,
+			expectAssumeRoleCalls:  2,
+		},
+		{
+			name:                   "case 2: invalid token",
+			err:                    awserr.New(sts.ErrCodeInvalidIdentityTokenException, "invalid token", nil),
+			roleArns:               []string{""},
+			tokenFile:              tokenFile,
+			expectWebIdentityCalls: 1,
+			expectError:            IsAccessDenied,
+		},
+		{
+			name:      "case 3: missing token file",
+			roleArns:  []string{""},
+			tokenFile: filepath.Join(t.TempDir(), "missing"),
+			expectError: func(err error) bool {
+				return err != nil && !IsAccessDenied(err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assumeRoler := &fakeAssumeRoler{expiration: time.Hour}
+			withFakeAssumeRoler(t, assumeRoler)
+			webIdentity := &fakeWebIdentity{err: tc.err}
+			withFakeWebIdentity(t, webIdentity)
+
+			credentials := AWSCredentials{
+				Source:               AWSCredentialsSourceWebIdentity,
+				WebIdentityRoleARN:   "arn:aws:iam::123:role/operator",
+				WebIdentityTokenFile: tc.tokenFile,
+			}
+
+			for _, roleArn := range tc.roleArns {
+				awsSession, err := sharedSession(context.Background(), credentials, AWSRole{ARN: roleArn}, "mc")
+				switch {
+				case tc.expectError == nil && err != nil:
+					t.Fatalf("unexpected error: %v", err)
+				case tc.expectError != nil && !tc.expectError(err):
+					t.Fatalf("expected matching error, got %v", err)
+				case tc.expectError == nil && awsSession == nil:
+					t.Fatalf("expected session")
+				}
+			}
+
+			if webIdentity.calls != tc.expectWebIdentityCalls {
+				t.Fatalf("expected %d AssumeRoleWithWebIdentity calls, got %d", tc.expectWebIdentityCalls, webIdentity.calls)
+			}
+			for _, token := range webIdentity.tokens {
+				if token != "projected-token" {
+					t.Fatalf("expected token from file, got %q", token)
+				}
+			}
+			if assumeRoler.calls != tc.expectAssumeRoleCalls {
+				t.Fatalf("expected %d AssumeRole calls, got %d", tc.expectAssumeRoleCalls, assumeRoler.calls)
+			}
+		})
+	}
+}
+
+func Test_AWSCredentials_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		credentials AWSCredentials
+		expectError bool
+	}{
+		{
+			name:        "case 0: default",
+			credentials: AWSCredentials{Source: AWSCredentialsSourceDefault},
+		},
+		{
+			name: "case 1: web identity",
+			credentials: AWSCredentials{
+				Source:               AWSCredentialsSourceWebIdentity,
+				WebIdentityRoleARN:   "arn:aws:iam::123:role/operator",
+				WebIdentityTokenFile: "/var/run/secrets/token",
+			},
+		},
+		{
+			name: "case 2: web identity without role",
+			credentials: AWSCredentials{
+				Source:               AWSCredentialsSourceWebIdentity,
+				WebIdentityTokenFile: "/var/run/secrets/token",
+			},
+			expectError: true,
+		},
+		{
+			name:        "case 3: unknown source",
+			credentials: AWSCredentials{Source: "static"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.credentials.Validate()
+			if tc.expectError && !IsInvalidConfig(err) {
+				t.Fatalf("expected invalid config error, got %v", err)
+			} else if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
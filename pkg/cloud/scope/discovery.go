@@ -0,0 +1,70 @@
+package scope
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+// ResolveServiceDiscovery returns the Service discovery of the cluster. The
+// namespaces and selectors of the annotations of the cluster take precedence
+// over the given defaults.
+func ResolveServiceDiscovery(cluster *capi.Cluster, defaults cloud.ServiceDiscovery) (cloud.ServiceDiscovery, error) {
+	discovery := defaults
+
+	annotations := cluster.GetAnnotations()
+	if value, ok := annotations[key.AnnotationIngressNamespaces]; ok {
+		discovery.IngressNamespaces = splitList(value)
+	}
+	if value, ok := annotations[key.AnnotationIngressSelector]; ok {
+		discovery.IngressSelector = value
+	}
+	if value, ok := annotations[key.AnnotationGatewayNamespaces]; ok {
+		discovery.GatewayNamespaces = splitList(value)
+	}
+	if value, ok := annotations[key.AnnotationGatewaySelector]; ok {
+		discovery.GatewaySelector = value
+	}
+
+	if err := ValidateServiceDiscovery(discovery); err != nil {
+		return cloud.ServiceDiscovery{}, microerror.Mask(err)
+	}
+
+	return discovery, nil
+}
+
+// ValidateServiceDiscovery returns invalidConfigError if the Service discovery
+// has no namespaces or an invalid label selector.
+func ValidateServiceDiscovery(discovery cloud.ServiceDiscovery) error {
+	if len(discovery.IngressNamespaces) == 0 {
+		return microerror.Maskf(invalidConfigError, "ingress namespaces must not be empty")
+	}
+	if len(discovery.GatewayNamespaces) == 0 {
+		return microerror.Maskf(invalidConfigError, "gateway namespaces must not be empty")
+	}
+	if _, err := labels.Parse(discovery.IngressSelector); err != nil {
+		return microerror.Maskf(invalidConfigError, "invalid ingress selector %q: %s", discovery.IngressSelector, err)
+	}
+	if _, err := labels.Parse(discovery.GatewaySelector); err != nil {
+		return microerror.Maskf(invalidConfigError, "invalid gateway selector %q: %s", discovery.GatewaySelector, err)
+	}
+
+	return nil
+}
+
+// splitList splits a comma separated value and drops empty items.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
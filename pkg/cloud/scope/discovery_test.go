@@ -0,0 +1,87 @@
+package scope
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+func Test_ResolveServiceDiscovery(t *testing.T) {
+	defaults := cloud.ServiceDiscovery{
+		IngressNamespaces: []string{"kube-system"},
+		IngressSelector:   "app.kubernetes.io/name=ingress-nginx",
+		GatewayNamespaces: []string{"envoy-gateway-system"},
+	}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+
+		expectedDiscovery cloud.ServiceDiscovery
+		expectedError     func(error) bool
+	}{
+		{
+			name:              "case 0: no annotations",
+			expectedDiscovery: defaults,
+		},
+		{
+			name: "case 1: namespaces and selectors from annotations",
+			annotations: map[string]string{
+				key.AnnotationIngressNamespaces: "traefik, kube-system",
+				key.AnnotationIngressSelector:   "app.kubernetes.io/name in (traefik,ingress-nginx)",
+				key.AnnotationGatewayNamespaces: "istio-ingress",
+				key.AnnotationGatewaySelector:   "istio=ingressgateway",
+			},
+			expectedDiscovery: cloud.ServiceDiscovery{
+				IngressNamespaces: []string{"traefik", "kube-system"},
+				IngressSelector:   "app.kubernetes.io/name in (traefik,ingress-nginx)",
+				GatewayNamespaces: []string{"istio-ingress"},
+				GatewaySelector:   "istio=ingressgateway",
+			},
+		},
+		{
+			name:        "case 2: empty selector annotation selects all Services",
+			annotations: map[string]string{key.AnnotationIngressSelector: ""},
+			expectedDiscovery: cloud.ServiceDiscovery{
+				IngressNamespaces: []string{"kube-system"},
+				GatewayNamespaces: []string{"envoy-gateway-system"},
+			},
+		},
+		{
+			name:          "case 3: invalid selector",
+			annotations:   map[string]string{key.AnnotationIngressSelector: "app in ("},
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name:          "case 4: empty namespaces",
+			annotations:   map[string]string{key.AnnotationGatewayNamespaces: " , "},
+			expectedError: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: tc.annotations}}
+
+			discovery, err := ResolveServiceDiscovery(cluster, defaults)
+
+			switch {
+			case tc.expectedError == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expectedError != nil && !tc.expectedError(err):
+				t.Fatalf("expected matching error, got %v", err)
+			case tc.expectedError != nil:
+				return
+			}
+
+			if !reflect.DeepEqual(discovery, tc.expectedDiscovery) {
+				t.Fatalf("expected %+v, got %+v", tc.expectedDiscovery, discovery)
+			}
+		})
+	}
+}
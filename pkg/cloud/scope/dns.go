@@ -0,0 +1,10 @@
+package scope
+
+import (
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+)
+
+// DNSScope is a scope for use with the provider independent DNS reconciling service
+type DNSScope interface {
+	cloud.ClusterScoper
+}
@@ -12,3 +12,12 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var accessDeniedError = &microerror.Error{
+	Kind: "accessDeniedError",
+}
+
+// IsAccessDenied asserts accessDeniedError.
+func IsAccessDenied(err error) bool {
+	return microerror.Cause(err) == accessDeniedError
+}
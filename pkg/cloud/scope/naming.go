@@ -0,0 +1,152 @@
+package scope
+
+import (
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+)
+
+// DefaultNamingTemplates returns the templates of the cluster domain
+// <cluster name>.<base domain> and of the api, bastion1, ingress and wildcard
+// records directly below it.
+func DefaultNamingTemplates() cloud.NamingTemplates {
+	return cloud.NamingTemplates{
+		ClusterDomain: "{{ .Cluster.Name }}.{{ .BaseDomain }}",
+		API:           "api.{{ .ClusterDomain }}",
+		Bastion:       "bastion1.{{ .ClusterDomain }}",
+		Ingress:       "ingress.{{ .ClusterDomain }}",
+		Wildcard:      "*.{{ .ClusterDomain }}",
+	}
+}
+
+// namingData is the data the naming templates are executed with.
+// ClusterDomain is empty while the cluster domain itself is rendered. The
+// cluster domain is not rendered if it is already set.
+type namingData struct {
+	Cluster           *capi.Cluster
+	Namespace         string
+	BaseDomain        string
+	ClusterDomain     string
+	ManagementCluster string
+}
+
+// renderNames renders the cluster domain and the record names of the cluster.
+// It returns invalidConfigError if a template fails or renders a name which
+// is not below the base domain or the cluster domain respectively.
+func renderNames(templates cloud.NamingTemplates, data namingData) (string, cloud.RecordNames, error) {
+	clusterDomain := normalizeDomain(data.ClusterDomain)
+	if clusterDomain == "" {
+		var err error
+		clusterDomain, err = renderName("cluster domain", templates.ClusterDomain, data)
+		if err != nil {
+			return "", cloud.RecordNames{}, microerror.Mask(err)
+		}
+	}
+	if err := validateName("cluster domain", clusterDomain, normalizeDomain(data.BaseDomain)); err != nil {
+		return "", cloud.RecordNames{}, microerror.Mask(err)
+	}
+	data.ClusterDomain = clusterDomain
+
+	var names cloud.RecordNames
+	seen := map[string]string{}
+	for _, field := range recordNameFields(&templates, &names) {
+		name, err := renderName(field.name, *field.template, data)
+		if err != nil {
+			return "", cloud.RecordNames{}, microerror.Mask(err)
+		}
+		if err := validateRecordName(field, name, clusterDomain); err != nil {
+			return "", cloud.RecordNames{}, microerror.Mask(err)
+		}
+		if other, ok := seen[name]; ok {
+			return "", cloud.RecordNames{}, microerror.Maskf(invalidConfigError, "%s record and %s record have the same name %q", other, field.name, name)
+		}
+		seen[name] = field.name
+		*field.value = name
+	}
+
+	return clusterDomain, names, nil
+}
+
+// ValidateNamingTemplates returns invalidConfigError if the templates can't
+// be rendered for an example cluster.
+func ValidateNamingTemplates(templates cloud.NamingTemplates) error {
+	_, _, err := renderNames(templates, namingData{
+		Cluster:           &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "org-example"}},
+		Namespace:         "org-example",
+		BaseDomain:        "example.com",
+		ManagementCluster: "example",
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func renderName(name, text string, data namingData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", microerror.Maskf(invalidConfigError, "%s template %q is invalid: %s", name, text, err)
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", microerror.Maskf(invalidConfigError, "%s template %q can't be rendered: %s", name, text, err)
+	}
+
+	return normalizeDomain(rendered.String()), nil
+}
+
+// validateRecordName returns invalidConfigError if the rendered record name is
+// not below the cluster domain. The wildcard record has to start with *. and
+// can be directly below the cluster domain.
+func validateRecordName(field recordNameField, rendered, clusterDomain string) error {
+	if !field.wildcard {
+		return validateName(field.name+" record", rendered, clusterDomain)
+	}
+
+	domain, ok := strings.CutPrefix(rendered, "*.")
+	if !ok {
+		return microerror.Maskf(invalidConfigError, "wildcard record %q must start with *.", rendered)
+	}
+	if domain == clusterDomain {
+		return nil
+	}
+	return validateName(field.name+" record", domain, clusterDomain)
+}
+
+// validateName returns invalidConfigError if the rendered name is not a valid
+// domain below the given parent domain.
+func validateName(name, rendered, parent string) error {
+	if errs := validation.IsDNS1123Subdomain(rendered); len(errs) > 0 {
+		return microerror.Maskf(invalidConfigError, "%s %q is not a valid domain: %s", name, rendered, strings.Join(errs, ", "))
+	}
+	if !strings.HasSuffix(rendered, "."+parent) {
+		return microerror.Maskf(invalidConfigError, "%s %q must be below %q", name, rendered, parent)
+	}
+	return nil
+}
+
+type recordNameField struct {
+	name     string
+	template *string
+	value    *string
+	wildcard bool
+}
+
+// recordNameFields returns the templates of the records together with their rendered names.
+func recordNameFields(templates *cloud.NamingTemplates, names *cloud.RecordNames) []recordNameField {
+	return []recordNameField{
+		{name: "api", template: &templates.API, value: &names.API},
+		{name: "bastion", template: &templates.Bastion, value: &names.Bastion},
+		{name: "ingress", template: &templates.Ingress, value: &names.Ingress},
+		{name: "wildcard", template: &templates.Wildcard, value: &names.Wildcard, wildcard: true},
+	}
+}
@@ -0,0 +1,132 @@
+package scope
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+)
+
+func Test_renderNames(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		templates                func(*cloud.NamingTemplates)
+		provisionedClusterDomain string
+
+		expectedClusterDomain string
+		expectedNames         cloud.RecordNames
+		expectedError         func(error) bool
+	}{
+		{
+			name:                  "case 0: default templates",
+			expectedClusterDomain: "test.example.com",
+			expectedNames: cloud.RecordNames{
+				API:      "api.test.example.com",
+				Bastion:  "bastion1.test.example.com",
+				Ingress:  "ingress.test.example.com",
+				Wildcard: "*.test.example.com",
+			},
+		},
+		{
+			name: "case 1: cluster domain with namespace and custom api record",
+			templates: func(templates *cloud.NamingTemplates) {
+				templates.ClusterDomain = "{{ .Cluster.Name }}.{{ .Namespace }}.{{ .BaseDomain }}"
+				templates.API = "k8s-api.{{ .ClusterDomain }}"
+				templates.Wildcard = "*.apps.{{ .ClusterDomain }}"
+			},
+			expectedClusterDomain: "test.org-test.example.com",
+			expectedNames: cloud.RecordNames{
+				API:      "k8s-api.test.org-test.example.com",
+				Bastion:  "bastion1.test.org-test.example.com",
+				Ingress:  "ingress.test.org-test.example.com",
+				Wildcard: "*.apps.test.org-test.example.com",
+			},
+		},
+		{
+			name:          "case 2: template can't be parsed",
+			templates:     func(templates *cloud.NamingTemplates) { templates.API = "api.{{ .ClusterDomain" },
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name: "case 3: template uses an unknown field",
+			templates: func(templates *cloud.NamingTemplates) {
+				templates.ClusterDomain = "{{ .Organization }}.{{ .BaseDomain }}"
+			},
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name:          "case 4: cluster domain is not below the base domain",
+			templates:     func(templates *cloud.NamingTemplates) { templates.ClusterDomain = "{{ .Cluster.Name }}.example.org" },
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name:          "case 5: record is not below the cluster domain",
+			templates:     func(templates *cloud.NamingTemplates) { templates.Bastion = "bastion.{{ .BaseDomain }}" },
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name:          "case 6: records have the same name",
+			templates:     func(templates *cloud.NamingTemplates) { templates.Ingress = "api.{{ .ClusterDomain }}" },
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name:          "case 7: wildcard record does not start with *.",
+			templates:     func(templates *cloud.NamingTemplates) { templates.Wildcard = "apps.{{ .ClusterDomain }}" },
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name: "case 8: provisioned cluster domain takes precedence over the template",
+			templates: func(templates *cloud.NamingTemplates) {
+				templates.ClusterDomain = "{{ .Cluster.Name }}.{{ .Namespace }}.{{ .BaseDomain }}"
+			},
+			provisionedClusterDomain: "test.example.com",
+			expectedClusterDomain:    "test.example.com",
+			expectedNames: cloud.RecordNames{
+				API:      "api.test.example.com",
+				Bastion:  "bastion1.test.example.com",
+				Ingress:  "ingress.test.example.com",
+				Wildcard: "*.test.example.com",
+			},
+		},
+		{
+			name:                     "case 9: provisioned cluster domain is not below the base domain",
+			provisionedClusterDomain: "test.example.org",
+			expectedError:            IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			templates := DefaultNamingTemplates()
+			if tc.templates != nil {
+				tc.templates(&templates)
+			}
+			data := namingData{
+				Cluster:       &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "org-test"}},
+				Namespace:     "org-test",
+				BaseDomain:    "example.com",
+				ClusterDomain: tc.provisionedClusterDomain,
+			}
+
+			clusterDomain, names, err := renderNames(templates, data)
+
+			switch {
+			case tc.expectedError == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expectedError != nil && !tc.expectedError(err):
+				t.Fatalf("expected matching error, got %v", err)
+			case tc.expectedError != nil:
+				return
+			}
+
+			if clusterDomain != tc.expectedClusterDomain {
+				t.Fatalf("expected cluster domain %q, got %q", tc.expectedClusterDomain, clusterDomain)
+			}
+			if names != tc.expectedNames {
+				t.Fatalf("expected %+v, got %+v", tc.expectedNames, names)
+			}
+		})
+	}
+}
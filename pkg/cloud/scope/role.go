@@ -0,0 +1,161 @@
+package scope
+
+import (
+	"context"
+	"regexp"
+	"slices"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+// awsClusterControllerIdentityKind is the kind of the CAPA identity which
+// uses the credentials of the controller itself.
+const awsClusterControllerIdentityKind = "AWSClusterControllerIdentity"
+
+var awsAccountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+var awsClusterRoleIdentityGVK = schema.GroupVersionKind{
+	Group:   "infrastructure.cluster.x-k8s.io",
+	Version: "v1beta2",
+	Kind:    "AWSClusterRoleIdentity",
+}
+
+// ResolveClusterRole returns the AWS role for the hosted zone of the cluster.
+// The role is taken from the annotations of the cluster and falls back to the
+// annotations of its namespace. An empty role means the default role is used.
+// Role ARNs from annotations have to be allowed by allowedRoles, which
+// contains role ARNs or AWS account IDs. Referenced AWSClusterRoleIdentities
+// have to allow the namespace of the cluster.
+func ResolveClusterRole(ctx context.Context, ctrlClient client.Client, cluster *capi.Cluster, allowedRoles []string) (AWSRole, error) {
+	namespace := &corev1.Namespace{}
+	if err := ctrlClient.Get(ctx, client.ObjectKey{Name: cluster.GetNamespace()}, namespace); err != nil {
+		return AWSRole{}, microerror.Mask(err)
+	}
+
+	for _, annotations := range []map[string]string{cluster.GetAnnotations(), namespace.GetAnnotations()} {
+		role, err := roleFromAnnotations(ctx, ctrlClient, namespace, annotations, allowedRoles)
+		if err != nil {
+			return AWSRole{}, microerror.Mask(err)
+		} else if role.ARN != "" {
+			return role, nil
+		}
+	}
+
+	return AWSRole{}, nil
+}
+
+func roleFromAnnotations(ctx context.Context, ctrlClient client.Client, namespace *corev1.Namespace, annotations map[string]string, allowedRoles []string) (AWSRole, error) {
+	if roleArn := annotations[key.AnnotationAWSRoleARN]; roleArn != "" {
+		if !isRoleAllowed(roleArn, allowedRoles) {
+			return AWSRole{}, microerror.Maskf(invalidConfigError, "role %s of annotation %s is not allowed by the operator", roleArn, key.AnnotationAWSRoleARN)
+		}
+		return AWSRole{
+			ARN:        roleArn,
+			ExternalID: annotations[key.AnnotationAWSExternalID],
+		}, nil
+	}
+
+	identityName := annotations[key.AnnotationAWSClusterRoleIdentity]
+	if identityName == "" {
+		return AWSRole{}, nil
+	}
+
+	identity := &unstructured.Unstructured{}
+	identity.SetGroupVersionKind(awsClusterRoleIdentityGVK)
+	if err := ctrlClient.Get(ctx, client.ObjectKey{Name: identityName}, identity); err != nil {
+		return AWSRole{}, microerror.Mask(err)
+	}
+
+	allowed, err := isNamespaceAllowed(identity, namespace)
+	if err != nil {
+		return AWSRole{}, microerror.Mask(err)
+	} else if !allowed {
+		return AWSRole{}, microerror.Maskf(invalidConfigError, "AWSClusterRoleIdentity %s does not allow namespace %s", identityName, namespace.Name)
+	}
+
+	// The role is assumed with the credentials of the operator, which act
+	// like an AWSClusterControllerIdentity. Chains of identities are not supported.
+	if sourceKind, ok, _ := unstructured.NestedString(identity.Object, "spec", "sourceIdentityRef", "kind"); ok && sourceKind != awsClusterControllerIdentityKind {
+		return AWSRole{}, microerror.Maskf(invalidConfigError, "AWSClusterRoleIdentity %s has the source identity kind %s, only %s is supported", identityName, sourceKind, awsClusterControllerIdentityKind)
+	}
+
+	roleArn, _, _ := unstructured.NestedString(identity.Object, "spec", "roleARN")
+	if roleArn == "" {
+		return AWSRole{}, microerror.Maskf(invalidConfigError, "AWSClusterRoleIdentity %s does not have a role ARN", identityName)
+	}
+	externalID, _, _ := unstructured.NestedString(identity.Object, "spec", "externalID")
+
+	return AWSRole{
+		ARN:        roleArn,
+		ExternalID: externalID,
+	}, nil
+}
+
+// isNamespaceAllowed evaluates the allowedNamespaces of the identity like
+// CAPA does. No allowedNamespaces allow no namespace and an empty
+// allowedNamespaces allows all namespaces.
+func isNamespaceAllowed(identity *unstructured.Unstructured, namespace *corev1.Namespace) (bool, error) {
+	allowedNamespaces, ok, _ := unstructured.NestedMap(identity.Object, "spec", "allowedNamespaces")
+	if !ok {
+		return false, nil
+	}
+
+	list, hasList, _ := unstructured.NestedStringSlice(allowedNamespaces, "list")
+	selectorObject, hasSelector, _ := unstructured.NestedMap(allowedNamespaces, "selector")
+	if !hasList && (!hasSelector || len(selectorObject) == 0) {
+		return true, nil
+	}
+	if slices.Contains(list, namespace.Name) {
+		return true, nil
+	}
+	if !hasSelector || len(selectorObject) == 0 {
+		return false, nil
+	}
+
+	labelSelector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selectorObject, labelSelector); err != nil {
+		return false, microerror.Maskf(invalidConfigError, "AWSClusterRoleIdentity %s has an invalid namespace selector: %s", identity.GetName(), err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return false, microerror.Maskf(invalidConfigError, "AWSClusterRoleIdentity %s has an invalid namespace selector: %s", identity.GetName(), err)
+	}
+
+	return selector.Matches(labels.Set(namespace.GetLabels())), nil
+}
+
+// isRoleAllowed returns true if the role ARN or its AWS account ID is one of
+// the allowed roles.
+func isRoleAllowed(roleArn string, allowedRoles []string) bool {
+	parsed, err := arn.Parse(roleArn)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(allowedRoles, roleArn) || slices.Contains(allowedRoles, parsed.AccountID)
+}
+
+// ValidateAllowedRoles returns invalidConfigError if an allowed role is
+// neither a role ARN nor an AWS account ID.
+func ValidateAllowedRoles(allowedRoles []string) error {
+	for _, allowed := range allowedRoles {
+		if awsAccountIDPattern.MatchString(allowed) {
+			continue
+		}
+		if _, err := arn.Parse(allowed); err != nil {
+			return microerror.Maskf(invalidConfigError, "allowed role %q must be a role ARN or an AWS account ID", allowed)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package scope
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+// newAWSClusterRoleIdentity returns an identity which allows the given
+// allowedNamespaces, nil allows no namespace.
+func newAWSClusterRoleIdentity(name, roleArn, externalID string, allowedNamespaces map[string]any) *unstructured.Unstructured {
+	identity := &unstructured.Unstructured{}
+	identity.SetGroupVersionKind(awsClusterRoleIdentityGVK)
+	identity.SetName(name)
+	_ = unstructured.SetNestedField(identity.Object, roleArn, "spec", "roleARN")
+	if externalID != "" {
+		_ = unstructured.SetNestedField(identity.Object, externalID, "spec", "externalID")
+	}
+	if allowedNamespaces != nil {
+		_ = unstructured.SetNestedMap(identity.Object, allowedNamespaces, "spec", "allowedNamespaces")
+	}
+	return identity
+}
+
+func Test_ResolveClusterRole(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		clusterAnnotations   map[string]string
+		namespaceAnnotations map[string]string
+		namespaceLabels      map[string]string
+		objects              []client.Object
+
+		expectedRole  AWSRole
+		expectedError func(error) bool
+	}{
+		{
+			name:         "case 0: no annotations",
+			expectedRole: AWSRole{},
+		},
+		{
+			name: "case 1: role from cluster annotations",
+			clusterAnnotations: map[string]string{
+				key.AnnotationAWSRoleARN:    "arn:aws:iam::111:role/dns",
+				key.AnnotationAWSExternalID: "customer-a",
+			},
+			namespaceAnnotations: map[string]string{key.AnnotationAWSRoleARN: "arn:aws:iam::222:role/dns"},
+			expectedRole:         AWSRole{ARN: "arn:aws:iam::111:role/dns", ExternalID: "customer-a"},
+		},
+		{
+			name:                 "case 2: role from namespace annotations",
+			namespaceAnnotations: map[string]string{key.AnnotationAWSRoleARN: "arn:aws:iam::222:role/dns"},
+			expectedRole:         AWSRole{ARN: "arn:aws:iam::222:role/dns"},
+		},
+		{
+			name:               "case 3: role from referenced identity",
+			clusterAnnotations: map[string]string{key.AnnotationAWSClusterRoleIdentity: "customer-b"},
+			objects:            []client.Object{newAWSClusterRoleIdentity("customer-b", "arn:aws:iam::333:role/dns", "ext", map[string]any{})},
+			expectedRole:       AWSRole{ARN: "arn:aws:iam::333:role/dns", ExternalID: "ext"},
+		},
+		{
+			name:               "case 4: referenced identity without role",
+			clusterAnnotations: map[string]string{key.AnnotationAWSClusterRoleIdentity: "customer-c"},
+			objects:            []client.Object{newAWSClusterRoleIdentity("customer-c", "", "", map[string]any{})},
+			expectedError:      IsInvalidConfig,
+		},
+		{
+			name:               "case 5: referenced identity does not allow the namespace",
+			clusterAnnotations: map[string]string{key.AnnotationAWSClusterRoleIdentity: "customer-d"},
+			objects: []client.Object{newAWSClusterRoleIdentity("customer-d", "arn:aws:iam::444:role/dns", "",
+				map[string]any{"list": []any{"org-other"}})},
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name:               "case 6: referenced identity without allowed namespaces",
+			clusterAnnotations: map[string]string{key.AnnotationAWSClusterRoleIdentity: "customer-e"},
+			objects:            []client.Object{newAWSClusterRoleIdentity("customer-e", "arn:aws:iam::444:role/dns", "", nil)},
+			expectedError:      IsInvalidConfig,
+		},
+		{
+			name:               "case 7: referenced identity allows the namespace by label",
+			clusterAnnotations: map[string]string{key.AnnotationAWSClusterRoleIdentity: "customer-f"},
+			namespaceLabels:    map[string]string{"customer": "f"},
+			objects: []client.Object{newAWSClusterRoleIdentity("customer-f", "arn:aws:iam::444:role/dns", "",
+				map[string]any{"selector": map[string]any{"matchLabels": map[string]any{"customer": "f"}}})},
+			expectedRole: AWSRole{ARN: "arn:aws:iam::444:role/dns"},
+		},
+		{
+			name:               "case 8: role ARN is not allowed",
+			clusterAnnotations: map[string]string{key.AnnotationAWSRoleARN: "arn:aws:iam::999:role/admin"},
+			expectedError:      IsInvalidConfig,
+		},
+		{
+			name:                 "case 9: role ARN of namespace is not allowed",
+			namespaceAnnotations: map[string]string{key.AnnotationAWSRoleARN: "arn:aws:iam::999:role/admin"},
+			expectedError:        IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = capi.AddToScheme(scheme)
+
+			cluster := &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "org-test", Annotations: tc.clusterAnnotations}}
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "org-test", Annotations: tc.namespaceAnnotations, Labels: tc.namespaceLabels}}
+			ctrlClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(tc.objects, cluster, namespace)...).Build()
+
+			role, err := ResolveClusterRole(context.Background(), ctrlClient, cluster, []string{"arn:aws:iam::111:role/dns", "222"})
+			switch {
+			case tc.expectedError == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expectedError != nil && !tc.expectedError(err):
+				t.Fatalf("expected matching error, got %v", err)
+			case tc.expectedError != nil:
+				return
+			}
+
+			if role != tc.expectedRole {
+				t.Fatalf("expected role %v, got %v", tc.expectedRole, role)
+			}
+		})
+	}
+}
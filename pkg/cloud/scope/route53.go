@@ -1,10 +1,15 @@
 package scope
 
 import (
+	awsclient "github.com/aws/aws-sdk-go/aws/client"
+
 	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
 )
 
 // Route53Scope is a scope for use with the Route53 reconciling service
 type Route53Scope interface {
 	cloud.ClusterScoper
+
+	// BaseZoneSession returns the AWS session for the account of the base hosted zone.
+	BaseZoneSession() awsclient.ConfigProvider
 }
@@ -0,0 +1,126 @@
+// Package scopetest provides a static cluster scope to test the DNS services
+// without a management cluster or AWS credentials.
+package scopetest
+
+import (
+	"context"
+	"fmt"
+
+	awsclient "github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+)
+
+// ClusterScope implements cloud.ClusterScoper with fixed values.
+type ClusterScope struct {
+	APIEndpointValue            string
+	BaseDomainValue             string
+	BastionIPValue              string
+	ClusterDomainValue          string
+	ClusterValue                *capi.Cluster
+	InfraClusterValue           *unstructured.Unstructured
+	K8sClient                   client.Client
+	ManagementClusterValue      string
+	RecordNamesValue            cloud.RecordNames
+	RecordTTLsValue             cloud.RecordTTLs
+	ServiceDiscoveryValue       cloud.ServiceDiscovery
+	WildcardCNAMETargetValue    string
+	WildcardIngressServiceValue string
+}
+
+// APIEndpoint returns the configured API endpoint.
+func (s *ClusterScope) APIEndpoint() string {
+	return s.APIEndpointValue
+}
+
+// BaseDomain returns the configured base domain.
+func (s *ClusterScope) BaseDomain() string {
+	return s.BaseDomainValue
+}
+
+// BastionIP returns the configured bastion IP.
+func (s *ClusterScope) BastionIP() string {
+	return s.BastionIPValue
+}
+
+// Cluster returns the configured CAPI cluster.
+func (s *ClusterScope) Cluster() *capi.Cluster {
+	return s.ClusterValue
+}
+
+// ClusterK8sClient returns the configured workload cluster client.
+func (s *ClusterScope) ClusterK8sClient(ctx context.Context) (client.Client, error) {
+	return s.K8sClient, nil
+}
+
+// ClusterDomain returns the configured cluster domain, or <cluster name>.<base
+// domain> if it is not set.
+func (s *ClusterScope) ClusterDomain() string {
+	if s.ClusterDomainValue != "" {
+		return s.ClusterDomainValue
+	}
+	return fmt.Sprintf("%s.%s", s.Name(), s.BaseDomainValue)
+}
+
+// InfrastructureCluster returns the configured infrastructure cluster.
+func (s *ClusterScope) InfrastructureCluster() *unstructured.Unstructured {
+	return s.InfraClusterValue
+}
+
+// ManagementCluster returns the configured management cluster name.
+func (s *ClusterScope) ManagementCluster() string {
+	return s.ManagementClusterValue
+}
+
+// Name returns the name of the configured CAPI cluster.
+func (s *ClusterScope) Name() string {
+	return s.ClusterValue.Name
+}
+
+// RecordNames returns the configured record names, or the api, bastion1,
+// ingress and wildcard records of the cluster domain if they are not set.
+func (s *ClusterScope) RecordNames() cloud.RecordNames {
+	if s.RecordNamesValue != (cloud.RecordNames{}) {
+		return s.RecordNamesValue
+	}
+	return cloud.RecordNames{
+		API:      "api." + s.ClusterDomain(),
+		Bastion:  "bastion1." + s.ClusterDomain(),
+		Ingress:  "ingress." + s.ClusterDomain(),
+		Wildcard: "*." + s.ClusterDomain(),
+	}
+}
+
+// RecordTTLs returns the configured record TTLs.
+func (s *ClusterScope) RecordTTLs() cloud.RecordTTLs {
+	return s.RecordTTLsValue
+}
+
+// ServiceDiscovery returns the configured Service discovery.
+func (s *ClusterScope) ServiceDiscovery() cloud.ServiceDiscovery {
+	return s.ServiceDiscoveryValue
+}
+
+// Session returns an AWS session without credentials.
+func (s *ClusterScope) Session() awsclient.ConfigProvider {
+	return session.Must(session.NewSession())
+}
+
+// BaseZoneSession returns an AWS session without credentials.
+func (s *ClusterScope) BaseZoneSession() awsclient.ConfigProvider {
+	return session.Must(session.NewSession())
+}
+
+// WildcardCNAMETarget returns the configured wildcard CNAME target.
+func (s *ClusterScope) WildcardCNAMETarget() string {
+	return s.WildcardCNAMETargetValue
+}
+
+// WildcardIngressService returns the configured wildcard ingress service.
+func (s *ClusterScope) WildcardIngressService() string {
+	return s.WildcardIngressServiceValue
+}
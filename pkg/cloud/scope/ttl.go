@@ -0,0 +1,70 @@
+package scope
+
+import (
+	"strconv"
+
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+// maxTTL is the largest TTL allowed by RFC 2181.
+const maxTTL = 1<<31 - 1
+
+// ResolveRecordTTLs returns the record TTLs of the cluster. The TTLs of the
+// annotations of the cluster take precedence over the given defaults.
+func ResolveRecordTTLs(cluster *capi.Cluster, defaults cloud.RecordTTLs) (cloud.RecordTTLs, error) {
+	ttls := defaults
+
+	annotations := cluster.GetAnnotations()
+	for _, field := range recordTTLFields(&ttls) {
+		value, ok := annotations[field.annotation]
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return cloud.RecordTTLs{}, microerror.Maskf(invalidConfigError, "annotation %s must be a number of seconds, got %q", field.annotation, value)
+		}
+		*field.ttl = parsed
+	}
+
+	if err := ValidateRecordTTLs(ttls); err != nil {
+		return cloud.RecordTTLs{}, microerror.Mask(err)
+	}
+
+	return ttls, nil
+}
+
+// ValidateRecordTTLs returns invalidConfigError if a TTL is not between one
+// second and the largest TTL allowed by RFC 2181.
+func ValidateRecordTTLs(ttls cloud.RecordTTLs) error {
+	for _, field := range recordTTLFields(&ttls) {
+		if *field.ttl < 1 || *field.ttl > maxTTL {
+			return microerror.Maskf(invalidConfigError, "%s TTL must be between 1 and %d seconds, got %d", field.name, maxTTL, *field.ttl)
+		}
+	}
+
+	return nil
+}
+
+type recordTTLField struct {
+	name       string
+	annotation string
+	ttl        *int64
+}
+
+// recordTTLFields returns the record classes of the TTLs together with their annotations.
+func recordTTLFields(ttls *cloud.RecordTTLs) []recordTTLField {
+	return []recordTTLField{
+		{name: "delegation", annotation: key.AnnotationTTLDelegation, ttl: &ttls.Delegation},
+		{name: "api", annotation: key.AnnotationTTLAPI, ttl: &ttls.API},
+		{name: "bastion", annotation: key.AnnotationTTLBastion, ttl: &ttls.Bastion},
+		{name: "ingress", annotation: key.AnnotationTTLIngress, ttl: &ttls.Ingress},
+		{name: "wildcard", annotation: key.AnnotationTTLWildcard, ttl: &ttls.Wildcard},
+		{name: "gateway", annotation: key.AnnotationTTLGateway, ttl: &ttls.Gateway},
+	}
+}
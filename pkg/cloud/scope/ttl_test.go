@@ -0,0 +1,73 @@
+package scope
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+	"github.com/giantswarm/dns-operator-route53/pkg/key"
+)
+
+func Test_ResolveRecordTTLs(t *testing.T) {
+	defaults := cloud.RecordTTLs{Delegation: 300, API: 300, Bastion: 300, Ingress: 300, Wildcard: 300, Gateway: 300}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+
+		expectedTTLs  cloud.RecordTTLs
+		expectedError func(error) bool
+	}{
+		{
+			name:         "case 0: no annotations",
+			expectedTTLs: defaults,
+		},
+		{
+			name: "case 1: TTLs from annotations",
+			annotations: map[string]string{
+				key.AnnotationTTLDelegation: "86400",
+				key.AnnotationTTLAPI:        "60",
+				key.AnnotationTTLWildcard:   "30",
+			},
+			expectedTTLs: cloud.RecordTTLs{Delegation: 86400, API: 60, Bastion: 300, Ingress: 300, Wildcard: 30, Gateway: 300},
+		},
+		{
+			name:          "case 2: TTL is not a number",
+			annotations:   map[string]string{key.AnnotationTTLIngress: "5m"},
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name:          "case 3: TTL is zero",
+			annotations:   map[string]string{key.AnnotationTTLGateway: "0"},
+			expectedError: IsInvalidConfig,
+		},
+		{
+			name:          "case 4: TTL is too large",
+			annotations:   map[string]string{key.AnnotationTTLBastion: "2147483648"},
+			expectedError: IsInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: tc.annotations}}
+
+			ttls, err := ResolveRecordTTLs(cluster, defaults)
+
+			switch {
+			case tc.expectedError == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expectedError != nil && !tc.expectedError(err):
+				t.Fatalf("expected matching error, got %v", err)
+			case tc.expectedError != nil:
+				return
+			}
+
+			if ttls != tc.expectedTTLs {
+				t.Fatalf("expected %+v, got %+v", tc.expectedTTLs, ttls)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package dns
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/giantswarm/microerror"
+)
+
+// addressRecordTypes are the record types which resolve a name to an endpoint.
+var addressRecordTypes = []string{RecordTypeA, RecordTypeAAAA, RecordTypeCNAME}
+
+// DesiredState holds the record sets a resource should have in the zone.
+type DesiredState struct {
+	// RecordSets are created or updated if they differ from the zone.
+	RecordSets []RecordSet
+	// Adopt contains record sets without owner which are taken over and
+	// deleted if they are not desired, e.g. records of an older version of
+	// the operator.
+	Adopt []RecordSet
+	// Keep contains names whose record sets are left untouched, e.g. while
+	// the load balancer of a Service has no address yet.
+	Keep []string
+}
+
+// desiredState computes the complete desired state of the records of the
+// cluster: the API and bastion records, the ingress and wildcard records and
+// the records of the gateway Services and the Gateway API Gateways. It
+// returns ingressNotReadyError together with the state if an ingress load
+// balancer has no address yet.
+func (s *Service) desiredState(ctx context.Context) (DesiredState, error) {
+	var state DesiredState
+
+	if s.scope.APIEndpoint() == "" {
+		log.FromContext(ctx).Info("API endpoint is not ready yet.")
+		return state, microerror.Mask(apiEndpointMissingError)
+	}
+	ttls := s.scope.RecordTTLs()
+	names := s.scope.RecordNames()
+	s.addEndpointRecordSets(&state, names.API, []string{s.scope.APIEndpoint()}, ttls.API)
+	s.addEndpointRecordSets(&state, names.Bastion, nonEmpty(s.scope.BastionIP()), ttls.Bastion)
+
+	var discoveryErr error
+	ingresses, err := s.getIngressServices(ctx)
+	if err != nil {
+		return state, microerror.Mask(err)
+	}
+	for _, ingress := range ingresses {
+		log.FromContext(ctx).Info("Reconciling ingress DNS records", "service", ingress.name, "ingressHostname", ingress.hostname, "ingressEndpoints", ingress.endpoints, "wildcard", ingress.wildcard)
+
+		wildcard := names.Wildcard
+		if len(ingress.endpoints) == 0 {
+			// The records of the previous load balancer stay until the new one has an address.
+			state.Keep = append(state.Keep, ingress.hostname)
+			if ingress.wildcard {
+				state.Keep = append(state.Keep, wildcard)
+			}
+			discoveryErr = microerror.Maskf(ingressNotReadyError, "ingress service %s has no load balancer address", ingress.name)
+			continue
+		}
+
+		s.addEndpointRecordSets(&state, ingress.hostname, ingress.endpoints, ttls.Ingress)
+		if ingress.wildcard {
+			wildcardCNAMETarget := s.scope.WildcardCNAMETarget()
+			if wildcardCNAMETarget == "" {
+				wildcardCNAMETarget = ingress.hostname
+			}
+			state.RecordSets = append(state.RecordSets, RecordSet{
+				Name:   wildcard,
+				Type:   RecordTypeCNAME,
+				TTL:    ttls.Wildcard,
+				Values: []string{wildcardCNAMETarget},
+			})
+		}
+	}
+
+	gatewayServices, err := s.getGatewayServices(ctx)
+	if err != nil {
+		return state, microerror.Mask(err)
+	}
+	gateways, err := s.getGateways(ctx)
+	if err != nil {
+		return state, microerror.Mask(err)
+	}
+	for _, gw := range mergeGatewayRecords(slices.Concat(gatewayServices, gateways)) {
+		if len(gw.endpoints) == 0 {
+			state.Keep = append(state.Keep, gw.hostname)
+			continue
+		}
+		s.addEndpointRecordSets(&state, gw.hostname, gw.endpoints, ttls.Gateway)
+	}
+
+	// The address records of the names managed by the operator are adopted,
+	// so e.g. an A record is replaced when the endpoint becomes a hostname.
+	adopted := s.managedRecordNames()
+	for _, recordSet := range state.RecordSets {
+		adopted = appendUnique(adopted, recordSet.Name)
+	}
+	for _, name := range adopted {
+		for _, recordType := range addressRecordTypes {
+			state.Adopt = append(state.Adopt, RecordSet{Name: name, Type: recordType})
+		}
+	}
+
+	return state, discoveryErr
+}
+
+// mergeGatewayRecords merges the endpoints of gateways which publish the same
+// hostname, e.g. two Gateways with a listener for the same hostname.
+func mergeGatewayRecords(gateways []gatewayRecord) []gatewayRecord {
+	var result []gatewayRecord
+	index := map[string]int{}
+	for _, gw := range gateways {
+		hostname := normalizeHostname(gw.hostname)
+		i, ok := index[hostname]
+		if !ok {
+			i = len(result)
+			index[hostname] = i
+			result = append(result, gatewayRecord{hostname: gw.hostname})
+		}
+		for _, endpoint := range gw.endpoints {
+			result[i].endpoints = appendUnique(result[i].endpoints, endpoint)
+		}
+	}
+	return result
+}
+
+// addEndpointRecordSets adds the record sets which resolve the given name to
+// the endpoints, e.g. the addresses or hostnames of a load balancer, with the
+// given TTL. IP addresses are published with an A record set for IPv4 and an
+// AAAA record set for IPv6. A hostname is published as alias if the provider
+// supports aliases for it and as CNAME otherwise.
+func (s *Service) addEndpointRecordSets(state *DesiredState, name string, endpoints []string, ttl int64) {
+	var ipv4, ipv6, hostnames []string
+	for _, endpoint := range endpoints {
+		if ip, err := netip.ParseAddr(strings.Trim(endpoint, "[]")); err != nil {
+			hostnames = appendUnique(hostnames, normalizeHostname(endpoint))
+		} else if ip.Unmap().Is6() {
+			ipv6 = appendUnique(ipv6, ip.String())
+		} else {
+			ipv4 = appendUnique(ipv4, ip.Unmap().String())
+		}
+	}
+
+	// The endpoint is the record itself, e.g. a control plane endpoint which
+	// already uses the api record of the cluster domain.
+	if slices.Contains(hostnames, normalizeHostname(name)) {
+		state.Keep = append(state.Keep, name)
+		return
+	}
+
+	switch {
+	case len(ipv4) > 0 || len(ipv6) > 0:
+		if len(ipv4) > 0 {
+			state.RecordSets = append(state.RecordSets, RecordSet{Name: name, Type: RecordTypeA, TTL: ttl, Values: ipv4})
+		}
+		if len(ipv6) > 0 {
+			state.RecordSets = append(state.RecordSets, RecordSet{Name: name, Type: RecordTypeAAAA, TTL: ttl, Values: ipv6})
+		}
+	case len(hostnames) > 0:
+		// A CNAME can only hold a single value.
+		target := hostnames[0]
+		if zoneID, ok := s.aliasHostedZoneID(target); ok {
+			// Dual-stack load balancers are resolved with the AAAA alias.
+			for _, recordType := range []string{RecordTypeA, RecordTypeAAAA} {
+				state.RecordSets = append(state.RecordSets, RecordSet{Name: name, Type: recordType, Values: []string{target}, AliasHostedZoneID: zoneID})
+			}
+		} else {
+			state.RecordSets = append(state.RecordSets, RecordSet{Name: name, Type: RecordTypeCNAME, TTL: ttl, Values: []string{target}})
+		}
+	}
+}
+
+// applyDesiredState diffs the desired state of the resource against the
+// zone and applies the changes of the record sets which are not owned by
+// someone else. Refused record sets are reported with recordNotOwnedError.
+func (s *Service) applyDesiredState(ctx context.Context, zoneID, resource string, desired DesiredState) error {
+	recordSets, err := s.provider.ListRecordSets(ctx, zoneID)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	registry := NewRegistry(s.scope, recordSets)
+	diff, refused := registry.Diff(resource, desired)
+	if !diff.IsEmpty() {
+		log.FromContext(ctx).Info("Applying DNS changes", "resource", resource,
+			"create", len(diff.Create), "upsert", len(diff.Upsert), "delete", len(diff.Delete))
+
+		if err := s.provider.ApplyRecordSetDiff(ctx, zoneID, diff); err != nil {
+			return microerror.Mask(err)
+		}
+
+		for _, recordSet := range diff.Delete {
+			if registry.isRegistryRecordSet(recordSet) {
+				continue
+			}
+			log.FromContext(ctx).Info("Deleted stale record", "resource", resource, "name", recordSet.Name, "type", recordSet.Type)
+			s.deleted = append(s.deleted, recordSet)
+		}
+	}
+
+	if len(refused) > 0 {
+		return microerror.Maskf(recordNotOwnedError, "refusing to change records owned by someone else: %s", strings.Join(refused, ", "))
+	}
+
+	return nil
+}
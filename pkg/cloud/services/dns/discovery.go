@@ -0,0 +1,187 @@
+package dns
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+)
+
+const (
+	defaultIngressSelector  = "app.kubernetes.io/name in (ingress-nginx,nginx-ingress-controller)"
+	defaultIngressNamespace = "kube-system"
+	defaultGatewayNamespace = "envoy-gateway-system"
+
+	externalDNSManagedAnnotation  = "giantswarm.io/external-dns"
+	externalDNSManagedValue       = "managed"
+	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+)
+
+// DefaultServiceDiscovery returns the Service discovery of the ingress-nginx
+// Services in kube-system and the gateway Services in envoy-gateway-system.
+func DefaultServiceDiscovery() cloud.ServiceDiscovery {
+	return cloud.ServiceDiscovery{
+		IngressNamespaces: []string{defaultIngressNamespace},
+		IngressSelector:   defaultIngressSelector,
+		GatewayNamespaces: []string{defaultGatewayNamespace},
+	}
+}
+
+// gatewayRecord is a hostname published for a gateway Service or a Gateway
+// API Gateway. The endpoints are empty while the gateway has no address yet.
+type gatewayRecord struct {
+	hostname  string
+	endpoints []string
+}
+
+// ingressService is a LoadBalancer Service of an ingress controller. The
+// endpoints are empty while the load balancer has no address yet.
+type ingressService struct {
+	name      string
+	hostname  string
+	endpoints []string
+	// wildcard is true if the service backs the wildcard record of the cluster domain.
+	wildcard bool
+}
+
+// getIngressServices returns the LoadBalancer Services of all ingress
+// controllers of the workload cluster which match the Service discovery of
+// the cluster ordered by name. The service selected
+// with WildcardIngressService, or the first service if none is selected, backs
+// the wildcard record and publishes the ingress record name. The other
+// services publish <service name>.<cluster domain>. The hostname of every
+// service can be set with the external-dns hostname annotation.
+func (s *Service) getIngressServices(ctx context.Context) ([]ingressService, error) {
+	k8sClient, err := s.scope.ClusterK8sClient(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	discovery := s.scope.ServiceDiscovery()
+	icServices, err := listServices(ctx, k8sClient, discovery.IngressNamespaces, discovery.IngressSelector)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var loadBalancers []corev1.Service
+	for _, icService := range icServices {
+		if icService.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			loadBalancers = append(loadBalancers, icService)
+		}
+	}
+	slices.SortFunc(loadBalancers, func(a, b corev1.Service) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.Namespace, b.Namespace))
+	})
+
+	wildcard := s.scope.WildcardIngressService()
+	if wildcard != "" && len(loadBalancers) > 0 && !slices.ContainsFunc(loadBalancers, func(svc corev1.Service) bool { return svc.Name == wildcard }) {
+		log.FromContext(ctx).Info("Wildcard ingress service not found, using the first ingress service", "service", wildcard)
+		wildcard = ""
+	}
+
+	var result []ingressService
+	for i, icService := range loadBalancers {
+		isWildcard := icService.Name == wildcard || (wildcard == "" && i == 0)
+
+		hostname := s.recordName(icService.Name)
+		if isWildcard {
+			hostname = s.scope.RecordNames().Ingress
+		}
+		if annotated, ok := icService.Annotations[externalDNSHostnameAnnotation]; ok && annotated != "" {
+			log.FromContext(ctx).Info("Using custom ingress hostname from annotation", "service", icService.Name, "annotation", externalDNSHostnameAnnotation, "hostname", annotated)
+			hostname = annotated
+		}
+
+		result = append(result, ingressService{
+			name:      icService.Name,
+			hostname:  hostname,
+			endpoints: loadBalancerEndpoints(icService),
+			wildcard:  isWildcard,
+		})
+	}
+
+	return result, nil
+}
+
+func (s *Service) getGatewayServices(ctx context.Context) ([]gatewayRecord, error) {
+	k8sClient, err := s.scope.ClusterK8sClient(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	discovery := s.scope.ServiceDiscovery()
+	services, err := listServices(ctx, k8sClient, discovery.GatewayNamespaces, discovery.GatewaySelector)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var result []gatewayRecord
+	for _, svc := range services {
+		if svc.Annotations[externalDNSManagedAnnotation] != externalDNSManagedValue {
+			continue
+		}
+		hostname, ok := svc.Annotations[externalDNSHostnameAnnotation]
+		if !ok || hostname == "" {
+			continue
+		}
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		// The endpoints are empty while the load balancer has no address yet.
+		result = append(result, gatewayRecord{
+			hostname:  hostname,
+			endpoints: loadBalancerEndpoints(svc),
+		})
+	}
+
+	return result, nil
+}
+
+// listServices returns the Services in the given namespaces which match the
+// label selector. The selector is parsed, because the cache of the workload
+// cluster client ignores raw list options.
+func listServices(ctx context.Context, k8sClient client.Client, namespaces []string, selector string) ([]corev1.Service, error) {
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var result []corev1.Service
+	for _, namespace := range namespaces {
+		var services corev1.ServiceList
+		err := k8sClient.List(ctx, &services,
+			client.InNamespace(namespace),
+			client.MatchingLabelsSelector{Selector: labelSelector},
+		)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		result = append(result, services.Items...)
+	}
+	return result, nil
+}
+
+// loadBalancerEndpoints returns the IP addresses and hostnames of the load
+// balancer of the service, e.g. an IPv4 and an IPv6 address of a dual-stack
+// load balancer or the hostname of an AWS load balancer.
+func loadBalancerEndpoints(svc corev1.Service) []string {
+	var endpoints []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		switch {
+		case ingress.IP != "":
+			endpoints = append(endpoints, ingress.IP)
+		case ingress.Hostname != "":
+			endpoints = append(endpoints, ingress.Hostname)
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,147 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+)
+
+const (
+	// defaultTTL is the TTL of the records of a cluster if no other TTL is
+	// configured and of the ownership TXT records.
+	defaultTTL = 300
+
+	RecordTypeA     = "A"
+	RecordTypeAAAA  = "AAAA"
+	RecordTypeCNAME = "CNAME"
+	RecordTypeNS    = "NS"
+	RecordTypeTXT   = "TXT"
+)
+
+// DefaultRecordTTLs returns the default TTLs of all record classes.
+func DefaultRecordTTLs() cloud.RecordTTLs {
+	return cloud.RecordTTLs{
+		Delegation: defaultTTL,
+		API:        defaultTTL,
+		Bastion:    defaultTTL,
+		Ingress:    defaultTTL,
+		Wildcard:   defaultTTL,
+		Gateway:    defaultTTL,
+	}
+}
+
+// Reconcile makes sure the cluster zone and its delegation exist and the
+// records of the cluster match its desired state. Records of the cluster
+// which are not desired anymore, e.g. of a removed gateway Service, are deleted.
+func (s *Service) Reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling hosted DNS zone")
+
+	zoneID, err := s.provider.EnsureZone(ctx)
+	if err != nil {
+		return withKind(err, zoneNotReadyError)
+	}
+
+	if err := s.provider.DelegateZone(ctx, zoneID); err != nil {
+		return withKind(err, delegationFailedError)
+	}
+
+	desired, err := s.desiredState(ctx)
+	if err != nil && !IsIngressNotReady(err) {
+		return microerror.Mask(err)
+	}
+	// The records of the cluster are applied while the ingress load balancer
+	// has no address yet, its records are kept until it has one.
+	discoveryErr := err
+
+	if err := s.applyDesiredState(ctx, zoneID, s.clusterResource(), desired); err != nil {
+		return microerror.Mask(err)
+	}
+
+	if discoveryErr != nil {
+		return microerror.Mask(discoveryErr)
+	}
+
+	return nil
+}
+
+// Delete removes the cluster zone including all its records and its delegation.
+func (s *Service) Delete(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	log.Info("Deleting hosted DNS zone")
+
+	if err := s.provider.DeleteZone(ctx); err != nil {
+		return microerror.Mask(err)
+	}
+
+	log.Info(fmt.Sprintf("Deleting hosted zone completed successfully for cluster %s", s.scope.Name()))
+	return nil
+}
+
+// PendingChanges returns the identifiers of the changes which did not
+// propagate to all authoritative name servers yet.
+func (s *Service) PendingChanges(ctx context.Context) ([]string, error) {
+	pending, err := s.provider.PendingChanges(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return pending, nil
+}
+
+// DeletedRecordSets returns the record sets which were deleted because they
+// are not desired anymore, e.g. the records of a removed gateway Service. The
+// ownership TXT records are not included. In dry-run mode the deletions are
+// only planned.
+func (s *Service) DeletedRecordSets() []RecordSet {
+	return s.deleted
+}
+
+// PlannedChanges returns the changes which were not applied because the
+// provider is in dry-run mode.
+func (s *Service) PlannedChanges() []PlannedChange {
+	return s.provider.PlannedChanges()
+}
+
+func (s *Service) recordName(name string) string {
+	return fmt.Sprintf("%s.%s", name, s.scope.ClusterDomain())
+}
+
+// clusterResource returns the resource which owns the records of the cluster.
+func (s *Service) clusterResource() string {
+	return ClusterResource(s.scope.Cluster().Namespace, s.scope.Cluster().Name)
+}
+
+func (s *Service) aliasHostedZoneID(hostname string) (string, bool) {
+	aliasProvider, ok := s.provider.(AliasProvider)
+	if !ok {
+		return "", false
+	}
+	return aliasProvider.AliasHostedZoneID(hostname)
+}
+
+func normalizeHostname(hostname string) string {
+	return strings.ToLower(strings.TrimSuffix(hostname, "."))
+}
+
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// nonEmpty returns the given value as list or nil if it is empty.
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
@@ -0,0 +1,84 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+)
+
+// IsAPIEndpointMissing asserts apiEndpointMissingError.
+func IsAPIEndpointMissing(err error) bool {
+	return microerror.Cause(err) == apiEndpointMissingError
+}
+
+var apiEndpointMissingError = &microerror.Error{
+	Kind: "apiEndpointMissingError",
+}
+
+// IsIngressNotReady asserts ingressNotReadyError.
+func IsIngressNotReady(err error) bool {
+	return microerror.Cause(err) == ingressNotReadyError
+}
+
+var ingressNotReadyError = &microerror.Error{
+	Kind: "ingressNotReadyError",
+}
+
+// IsRecordConflict asserts recordConflictError.
+func IsRecordConflict(err error) bool {
+	return microerror.Cause(err) == recordConflictError
+}
+
+var recordConflictError = &microerror.Error{
+	Kind: "recordConflictError",
+}
+
+// IsRecordNotOwned asserts recordNotOwnedError.
+func IsRecordNotOwned(err error) bool {
+	return microerror.Cause(err) == recordNotOwnedError
+}
+
+var recordNotOwnedError = &microerror.Error{
+	Kind: "recordNotOwnedError",
+}
+
+// IsForeignRecords asserts foreignRecordsError.
+func IsForeignRecords(err error) bool {
+	return microerror.Cause(err) == foreignRecordsError
+}
+
+var foreignRecordsError = &microerror.Error{
+	Kind: "foreignRecordsError",
+}
+
+// NewForeignRecordsError returns foreignRecordsError for a cluster zone which
+// a provider keeps on deletion because it contains record sets owned by
+// someone else.
+func NewForeignRecordsError(zone string, recordSets int) error {
+	return microerror.Maskf(foreignRecordsError, "keeping zone %s and its delegation, it contains %d record sets owned by someone else", zone, recordSets)
+}
+
+// IsZoneNotReady asserts zoneNotReadyError.
+func IsZoneNotReady(err error) bool {
+	return errors.Is(err, zoneNotReadyError)
+}
+
+var zoneNotReadyError = &microerror.Error{
+	Kind: "zoneNotReadyError",
+}
+
+// IsDelegationFailed asserts delegationFailedError.
+func IsDelegationFailed(err error) bool {
+	return errors.Is(err, delegationFailedError)
+}
+
+var delegationFailedError = &microerror.Error{
+	Kind: "delegationFailedError",
+}
+
+// withKind annotates err with the given error kind. The cause of err is kept,
+// so the provider specific error matchers still work on the returned error.
+func withKind(err error, kind *microerror.Error) error {
+	return microerror.Mask(fmt.Errorf("%w: %w", err, kind))
+}
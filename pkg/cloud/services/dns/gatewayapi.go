@@ -0,0 +1,172 @@
+package dns
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/microerror"
+)
+
+// getGateways returns the hostnames of the Gateway API Gateways of the
+// workload cluster together with the addresses of the Gateway. The hostnames
+// are taken from the listeners and from the HTTPRoutes accepted by the
+// Gateway. Hostnames outside of the cluster domain and the names managed for
+// the API, the bastion host and the ingress are skipped. It returns nil if
+// the Gateway API CRDs are not installed.
+func (s *Service) getGateways(ctx context.Context) ([]gatewayRecord, error) {
+	k8sClient, err := s.scope.ClusterK8sClient(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var gateways gatewayv1.GatewayList
+	if err := k8sClient.List(ctx, &gateways); isGatewayAPIMissing(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if len(gateways.Items) == 0 {
+		return nil, nil
+	}
+
+	var routes gatewayv1.HTTPRouteList
+	if err := k8sClient.List(ctx, &routes); err != nil && !isGatewayAPIMissing(err) {
+		return nil, microerror.Mask(err)
+	}
+
+	var result []gatewayRecord
+	for _, gw := range gateways.Items {
+		endpoints := gatewayEndpoints(gw)
+		for _, hostname := range gatewayHostnames(gw, routes.Items) {
+			if !s.isGatewayHostname(hostname) {
+				log.FromContext(ctx).Info("Skipping Gateway hostname outside of the cluster domain or managed by the operator",
+					"gateway", gw.Namespace+"/"+gw.Name, "hostname", hostname)
+				continue
+			}
+			result = append(result, gatewayRecord{hostname: hostname, endpoints: endpoints})
+		}
+	}
+
+	return result, nil
+}
+
+// isGatewayHostname returns true if records for the hostname of a Gateway can
+// be published in the cluster zone.
+func (s *Service) isGatewayHostname(hostname string) bool {
+	clusterDomain := normalizeHostname(s.scope.ClusterDomain())
+	if hostname != clusterDomain && !strings.HasSuffix(hostname, "."+clusterDomain) {
+		return false
+	}
+	for _, name := range s.managedRecordNames() {
+		if hostname == normalizeHostname(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// gatewayEndpoints returns the IP addresses and hostnames of the Gateway.
+func gatewayEndpoints(gw gatewayv1.Gateway) []string {
+	var endpoints []string
+	for _, address := range gw.Status.Addresses {
+		if address.Type != nil && *address.Type != gatewayv1.IPAddressType && *address.Type != gatewayv1.HostnameAddressType {
+			continue
+		}
+		endpoints = appendUnique(endpoints, address.Value)
+	}
+	return endpoints
+}
+
+// gatewayHostnames returns the hostnames of the listeners of the Gateway and
+// of the HTTPRoutes which are accepted by the Gateway. The hostnames of an
+// HTTPRoute are intersected with the hostnames of the listeners it is
+// attached to, as the Gateway doesn't serve the others.
+func gatewayHostnames(gw gatewayv1.Gateway, routes []gatewayv1.HTTPRoute) []string {
+	var hostnames []string
+	for _, listener := range gw.Spec.Listeners {
+		if listener.Hostname != nil && *listener.Hostname != "" {
+			hostnames = appendUnique(hostnames, normalizeHostname(string(*listener.Hostname)))
+		}
+	}
+	for _, route := range routes {
+		for _, listener := range attachedListeners(route, gw) {
+			listenerHostname := ""
+			if listener.Hostname != nil {
+				listenerHostname = normalizeHostname(string(*listener.Hostname))
+			}
+			for _, hostname := range route.Spec.Hostnames {
+				if intersection, ok := intersectHostnames(listenerHostname, normalizeHostname(string(hostname))); ok {
+					hostnames = appendUnique(hostnames, intersection)
+				}
+			}
+		}
+	}
+	return hostnames
+}
+
+// attachedListeners returns the listeners of the Gateway which accepted the
+// HTTPRoute. A parent reference with a section name or port only attaches
+// the route to the matching listeners.
+func attachedListeners(route gatewayv1.HTTPRoute, gw gatewayv1.Gateway) []gatewayv1.Listener {
+	var listeners []gatewayv1.Listener
+	for _, parent := range route.Status.Parents {
+		if !refersToGateway(parent.ParentRef, route.Namespace, gw) ||
+			!meta.IsStatusConditionTrue(parent.Conditions, string(gatewayv1.RouteConditionAccepted)) {
+			continue
+		}
+		for _, listener := range gw.Spec.Listeners {
+			if parent.ParentRef.SectionName != nil && *parent.ParentRef.SectionName != listener.Name {
+				continue
+			}
+			if parent.ParentRef.Port != nil && *parent.ParentRef.Port != listener.Port {
+				continue
+			}
+			listeners = append(listeners, listener)
+		}
+	}
+	return listeners
+}
+
+// intersectHostnames returns the hostname matched by both the hostname of a
+// listener and of an HTTPRoute as defined by the Gateway API. A listener
+// without hostname matches every hostname, and a wildcard matches the
+// hostnames with at least one more label. It returns false if the hostnames
+// don't intersect.
+func intersectHostnames(listener, route string) (string, bool) {
+	switch {
+	case listener == "" || listener == route:
+		return route, true
+	case strings.HasPrefix(listener, "*.") && strings.HasSuffix(route, listener[1:]):
+		return route, true
+	case strings.HasPrefix(route, "*.") && strings.HasSuffix(listener, route[1:]):
+		return listener, true
+	}
+	return "", false
+}
+
+// refersToGateway returns true if the parent reference of a route in the
+// given namespace points at the Gateway.
+func refersToGateway(ref gatewayv1.ParentReference, routeNamespace string, gw gatewayv1.Gateway) bool {
+	if ref.Group != nil && *ref.Group != gatewayv1.GroupName {
+		return false
+	}
+	if ref.Kind != nil && *ref.Kind != "Gateway" {
+		return false
+	}
+	namespace := routeNamespace
+	if ref.Namespace != nil {
+		namespace = string(*ref.Namespace)
+	}
+	return namespace == gw.Namespace && string(ref.Name) == gw.Name
+}
+
+// isGatewayAPIMissing returns true if the Gateway API CRDs are not installed
+// in the workload cluster or its types are not known to the client.
+func isGatewayAPIMissing(err error) bool {
+	return meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err)
+}
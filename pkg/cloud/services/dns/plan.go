@@ -0,0 +1,64 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Actions of the changes which are planned in dry-run mode.
+const (
+	ActionCreateZone = "CREATE_ZONE"
+	ActionDeleteZone = "DELETE_ZONE"
+	ActionCreate     = "CREATE"
+	ActionUpsert     = "UPSERT"
+	ActionDelete     = "DELETE"
+)
+
+// PlanActions contains all actions a planned change can have.
+var PlanActions = []string{ActionCreateZone, ActionDeleteZone, ActionCreate, ActionUpsert, ActionDelete}
+
+// PlannedChange is a change a DNS provider in dry-run mode did not apply.
+// Zone is the name or identifier of the zone the change targets. RecordSet is
+// empty for the creation and deletion of zones.
+type PlannedChange struct {
+	Action    string
+	Zone      string
+	RecordSet RecordSet
+}
+
+func (c PlannedChange) String() string {
+	if c.RecordSet.Name == "" {
+		return fmt.Sprintf("%s %s", c.Action, c.Zone)
+	}
+	if len(c.RecordSet.Values) == 0 {
+		return fmt.Sprintf("%s %s %s", c.Action, c.RecordSet.Type, c.RecordSet.Name)
+	}
+	return fmt.Sprintf("%s %s %s %s", c.Action, c.RecordSet.Type, c.RecordSet.Name, strings.Join(c.RecordSet.Values, ","))
+}
+
+// Plan collects the changes of a DNS provider in dry-run mode.
+type Plan struct {
+	changes []PlannedChange
+}
+
+// Add appends the given changes to the plan.
+func (p *Plan) Add(changes ...PlannedChange) {
+	p.changes = append(p.changes, changes...)
+}
+
+// Changes returns all planned changes in the order they would have been applied.
+func (p *Plan) Changes() []PlannedChange {
+	return p.changes
+}
+
+// CountByAction returns the number of planned changes for every action in PlanActions.
+func (p *Plan) CountByAction() map[string]int {
+	counts := make(map[string]int, len(PlanActions))
+	for _, action := range PlanActions {
+		counts[action] = 0
+	}
+	for _, change := range p.changes {
+		counts[change.Action]++
+	}
+	return counts
+}
@@ -0,0 +1,98 @@
+package dns
+
+import (
+	"context"
+	"slices"
+)
+
+// RecordSet is a provider independent representation of a DNS resource record set.
+// Name is the fully qualified record name without trailing dot.
+type RecordSet struct {
+	Name   string
+	Type   string
+	TTL    int64
+	Values []string
+	// AliasHostedZoneID turns the record set into an alias for the load
+	// balancer hostname in Values, which is served by the given hosted zone.
+	// It is only set for providers which implement AliasProvider.
+	AliasHostedZoneID string
+}
+
+// Equal returns true if both record sets have the same name, type, TTL and
+// values. The order of the values does not matter and the TTL of aliases is ignored.
+func (r RecordSet) Equal(other RecordSet) bool {
+	if normalizeHostname(r.Name) != normalizeHostname(other.Name) || r.Type != other.Type ||
+		r.AliasHostedZoneID != other.AliasHostedZoneID {
+		return false
+	}
+	if r.AliasHostedZoneID == "" && r.TTL != other.TTL {
+		return false
+	}
+	return slices.Equal(r.normalizedValues(), other.normalizedValues())
+}
+
+// normalizedValues returns the sorted values. Values other than TXT strings
+// are compared in lower case and without trailing dot, as servers return
+// hostnames fully qualified.
+func (r RecordSet) normalizedValues() []string {
+	values := slices.Clone(r.Values)
+	if r.Type != RecordTypeTXT {
+		for i, value := range values {
+			values[i] = normalizeHostname(value)
+		}
+	}
+	slices.Sort(values)
+	return values
+}
+
+// RecordSetDiff holds the record sets which have to be created, updated or
+// removed to bring a zone into its desired state.
+type RecordSetDiff struct {
+	Create []RecordSet
+	Upsert []RecordSet
+	Delete []RecordSet
+}
+
+// IsEmpty returns true if the diff does not contain any change.
+func (d RecordSetDiff) IsEmpty() bool {
+	return len(d.Create) == 0 && len(d.Upsert) == 0 && len(d.Delete) == 0
+}
+
+// Provider is the interface a DNS backend has to implement to be used by the Service.
+type Provider interface {
+	// EnsureZone returns the identifier of the cluster zone and creates the zone if it does not exist yet.
+	EnsureZone(ctx context.Context) (string, error)
+	// FindZone returns the identifier of the cluster zone without creating it.
+	// It returns an empty identifier if the zone does not exist.
+	FindZone(ctx context.Context) (string, error)
+	// DelegateZone creates or updates the NS delegation of the cluster zone in the base zone.
+	DelegateZone(ctx context.Context, zoneID string) error
+	// ApplyRecordSetDiff applies the given changes to the cluster zone as
+	// they are, the diff is computed by the Registry. Deletions have to be
+	// applied before the creations and upserts, so a record can change its
+	// type, e.g. from A to CNAME.
+	ApplyRecordSetDiff(ctx context.Context, zoneID string, diff RecordSetDiff) error
+	// ListRecordSets returns the record sets of the cluster in the zone
+	// without the NS and SOA records at the apex of the zone.
+	ListRecordSets(ctx context.Context, zoneID string) ([]RecordSet, error)
+	// DeleteZone removes all records of the cluster zone, its delegation in the
+	// base zone and the zone itself. It returns nil if the zone does not exist.
+	// Records owned by someone else according to the Registry are kept, the
+	// zone and its delegation are kept as long as it contains such records.
+	DeleteZone(ctx context.Context) error
+	// PendingChanges returns the identifiers of the submitted changes which are
+	// not yet served by all authoritative name servers. Providers which apply
+	// changes synchronously return nil.
+	PendingChanges(ctx context.Context) ([]string, error)
+	// PlannedChanges returns the changes which were computed but not applied
+	// because the provider is in dry-run mode.
+	PlannedChanges() []PlannedChange
+}
+
+// AliasProvider is implemented by DNS providers which can resolve a load
+// balancer hostname with alias records instead of a CNAME.
+type AliasProvider interface {
+	// AliasHostedZoneID returns the hosted zone which serves the given load
+	// balancer hostname and false if no alias can point at the hostname.
+	AliasHostedZoneID(hostname string) (string, bool)
+}
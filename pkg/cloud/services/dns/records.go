@@ -0,0 +1,100 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/giantswarm/microerror"
+)
+
+// ZoneApex is the relative record name which refers to the cluster domain itself.
+const ZoneApex = "@"
+
+// apexRecordTypes can't be declared at the cluster domain itself, which holds
+// the NS records of the cluster zone and its delegation.
+var apexRecordTypes = []string{RecordTypeCNAME, RecordTypeNS}
+
+// RecordSetName returns the fully qualified name of a record relative to the cluster domain.
+func (s *Service) RecordSetName(name string) string {
+	if name == ZoneApex || name == "" {
+		return s.scope.ClusterDomain()
+	}
+	return fmt.Sprintf("%s.%s", name, s.scope.ClusterDomain())
+}
+
+// ReconcileRecordSet makes sure the given user declared record set of the
+// resource exists in the cluster zone. A previously applied record set with a
+// different name or type is removed. The cluster zone is not created, it
+// returns zoneNotReadyError until Reconcile created it.
+func (s *Service) ReconcileRecordSet(ctx context.Context, resource string, desired RecordSet, previous *RecordSet) error {
+	if s.isManagedRecordSet(desired) {
+		return microerror.Maskf(recordConflictError, "record %s %s is managed by the operator", desired.Name, desired.Type)
+	}
+	if s.isApexRecordSet(desired) {
+		return microerror.Maskf(recordConflictError, "%s records can't be created at the cluster domain %s", desired.Type, desired.Name)
+	}
+
+	zoneID, err := s.provider.FindZone(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	} else if zoneID == "" {
+		return microerror.Maskf(zoneNotReadyError, "zone of cluster domain %s does not exist yet", s.scope.ClusterDomain())
+	}
+
+	state := DesiredState{
+		RecordSets: []RecordSet{desired},
+		Adopt:      []RecordSet{desired},
+	}
+	if previous != nil && previous.Name != "" {
+		state.Adopt = append(state.Adopt, *previous)
+	}
+
+	log.FromContext(ctx).Info("Reconciling DNS record", "name", desired.Name, "type", desired.Type)
+
+	return s.applyDesiredState(ctx, zoneID, resource, state)
+}
+
+// DeleteRecordSet removes the given user declared record set of the resource
+// from the cluster zone. Nothing is deleted if the cluster zone does not exist.
+func (s *Service) DeleteRecordSet(ctx context.Context, resource string, recordSet RecordSet) error {
+	if s.isManagedRecordSet(recordSet) || s.isApexRecordSet(recordSet) {
+		return nil
+	}
+
+	zoneID, err := s.provider.FindZone(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	} else if zoneID == "" {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("Deleting DNS record", "name", recordSet.Name, "type", recordSet.Type)
+
+	return s.applyDesiredState(ctx, zoneID, resource, DesiredState{Adopt: []RecordSet{recordSet}})
+}
+
+// managedRecordNames returns the fully qualified names of the records which
+// are managed by Reconcile and can't be declared by users.
+func (s *Service) managedRecordNames() []string {
+	names := s.scope.RecordNames()
+	return []string{names.API, names.Bastion, names.Ingress, names.Wildcard}
+}
+
+// isManagedRecordSet returns true if the record set collides with one of the
+// A, AAAA or CNAME records managed by Reconcile.
+func (s *Service) isManagedRecordSet(recordSet RecordSet) bool {
+	if !slices.Contains(s.managedRecordNames(), recordSet.Name) {
+		return false
+	}
+	// The wildcard is a CNAME which can't coexist with any other record type.
+	return recordSet.Name == s.scope.RecordNames().Wildcard || slices.Contains(addressRecordTypes, recordSet.Type)
+}
+
+// isApexRecordSet returns true if the record set is a CNAME or NS record at the
+// cluster domain itself.
+func (s *Service) isApexRecordSet(recordSet RecordSet) bool {
+	return recordSet.Name == s.scope.ClusterDomain() && slices.Contains(apexRecordTypes, recordSet.Type)
+}
@@ -0,0 +1,267 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+)
+
+// registryHeritage identifies the TXT records of the ownership registry of the operator.
+const registryHeritage = "dns-operator-route53"
+
+// ownerID returns the owner of the records of a cluster, which is unique per
+// management cluster and cluster.
+func ownerID(clusterScope scope.DNSScope) string {
+	return fmt.Sprintf("%s/%s", clusterScope.ManagementCluster(), clusterScope.Cluster().UID)
+}
+
+// ClusterResource returns the resource which owns the records published for
+// the endpoints and load balancers of the cluster.
+func ClusterResource(namespace, name string) string {
+	return fmt.Sprintf("cluster/%s/%s", namespace, name)
+}
+
+// DNSRecordResource returns the resource which owns the record of a DNSRecord.
+func DNSRecordResource(namespace, name string) string {
+	return fmt.Sprintf("dnsrecord/%s/%s", namespace, name)
+}
+
+// Registry resolves the owners of the record sets of a zone. The owner of a
+// record set is stored in a TXT record next to it, which follows the TXT
+// registry of external-dns, so records of external-dns are recognized as well.
+// Every record set is owned by a cluster and a resource of the cluster, e.g.
+// the cluster itself or a DNSRecord. Record sets without an owner are treated
+// as created by an older version of the operator and can be adopted.
+type Registry struct {
+	clusterDomain string
+	ownerID       string
+	// recordSets keeps the order of the listed record sets, so the computed
+	// changes are stable.
+	recordSets []RecordSet
+	index      map[recordSetKey]RecordSet
+}
+
+type recordSetKey struct {
+	name       string
+	recordType string
+}
+
+type recordOwner struct {
+	heritage string
+	id       string
+	resource string
+}
+
+func (o recordOwner) String() string {
+	switch {
+	case o.id == "":
+		return o.heritage
+	case o.resource == "":
+		return fmt.Sprintf("%s owner %s", o.heritage, o.id)
+	default:
+		return fmt.Sprintf("%s owner %s resource %s", o.heritage, o.id, o.resource)
+	}
+}
+
+// NewRegistry returns the registry of the cluster for the given record sets of its zone.
+func NewRegistry(clusterScope scope.DNSScope, recordSets []RecordSet) *Registry {
+	r := &Registry{
+		clusterDomain: normalizeHostname(clusterScope.ClusterDomain()),
+		ownerID:       ownerID(clusterScope),
+		recordSets:    recordSets,
+		index:         map[recordSetKey]RecordSet{},
+	}
+	for _, recordSet := range recordSets {
+		r.index[newRecordSetKey(recordSet.Name, recordSet.Type)] = recordSet
+	}
+	return r
+}
+
+// IsForeign returns true if the record set is owned by someone else than the cluster.
+func (r *Registry) IsForeign(name, recordType string) bool {
+	owner, ok := r.owner(name, recordType)
+	return ok && !r.ownedByCluster(owner)
+}
+
+// Diff computes the minimal changes which turn the record sets of the given
+// resource into the desired state, including the TXT records which mark the
+// record sets as owned by the resource. Record sets of the resource which are
+// not desired anymore are deleted. Desired record sets which are owned by
+// someone else are not changed, Diff returns a description of every refused
+// record set.
+func (r *Registry) Diff(resource string, desired DesiredState) (RecordSetDiff, []string) {
+	var diff RecordSetDiff
+	var refused []string
+
+	wanted := map[recordSetKey]bool{}
+	for _, recordSet := range desired.RecordSets {
+		if owner, ok := r.owner(recordSet.Name, recordSet.Type); ok && !r.ownedByResource(owner, resource) {
+			refused = append(refused, fmt.Sprintf("%s %s is owned by %s", recordSet.Type, recordSet.Name, owner))
+			continue
+		}
+
+		for _, want := range []RecordSet{recordSet, r.registryRecordSet(resource, recordSet)} {
+			key := newRecordSetKey(want.Name, want.Type)
+			if wanted[key] {
+				continue
+			}
+			wanted[key] = true
+
+			current, ok := r.index[key]
+			switch {
+			case !ok:
+				diff.Create = append(diff.Create, want)
+			case !current.Equal(want):
+				diff.Upsert = append(diff.Upsert, want)
+			}
+		}
+	}
+
+	keep := map[string]bool{}
+	for _, name := range desired.Keep {
+		keep[normalizeHostname(name)] = true
+		for _, recordType := range addressRecordTypes {
+			keep[r.registryName(name, recordType)] = true
+		}
+	}
+	adopt := map[recordSetKey]bool{}
+	for _, recordSet := range desired.Adopt {
+		key := newRecordSetKey(recordSet.Name, recordSet.Type)
+		adopt[key] = true
+
+		// A record set which should be replaced, e.g. an AAAA record next to
+		// the desired A record, but is owned by someone else.
+		current, ok := r.index[key]
+		if !ok || wanted[key] || keep[key.name] {
+			continue
+		}
+		if owner, ok := r.owner(current.Name, current.Type); ok && !r.ownedByResource(owner, resource) {
+			refused = append(refused, fmt.Sprintf("%s %s is owned by %s", current.Type, current.Name, owner))
+		}
+	}
+
+	deleted := map[recordSetKey]bool{}
+	for _, current := range r.recordSets {
+		key := newRecordSetKey(current.Name, current.Type)
+		if wanted[key] || deleted[key] || keep[key.name] || !r.belongsTo(resource, current, adopt) {
+			continue
+		}
+		deleted[key] = true
+		diff.Delete = append(diff.Delete, current)
+
+		// The registry records of adopted record sets may not name the resource yet.
+		registryKey := newRecordSetKey(r.registryName(current.Name, current.Type), RecordTypeTXT)
+		if registry, ok := r.index[registryKey]; ok && !wanted[registryKey] && !deleted[registryKey] && !r.IsForeign(registry.Name, registry.Type) {
+			deleted[registryKey] = true
+			diff.Delete = append(diff.Delete, registry)
+		}
+	}
+
+	return diff, refused
+}
+
+// belongsTo returns true if the record set is owned by the resource or is an
+// adoptable record set without owner.
+func (r *Registry) belongsTo(resource string, recordSet RecordSet, adopt map[recordSetKey]bool) bool {
+	owner, ok := r.owner(recordSet.Name, recordSet.Type)
+	switch {
+	case ok && owner.resource == resource && r.ownedByCluster(owner):
+		return true
+	case ok && !r.ownedByResource(owner, resource):
+		return false
+	default:
+		return adopt[newRecordSetKey(recordSet.Name, recordSet.Type)]
+	}
+}
+
+func (r *Registry) ownedByCluster(owner recordOwner) bool {
+	return owner.heritage == registryHeritage && owner.id == r.ownerID
+}
+
+// ownedByResource returns true if the owner is the resource or the cluster
+// without a resource, which may be adopted by every resource of the cluster.
+func (r *Registry) ownedByResource(owner recordOwner, resource string) bool {
+	return r.ownedByCluster(owner) && (owner.resource == "" || owner.resource == resource)
+}
+
+// isRegistryRecordSet returns true if the record set is a TXT record of the
+// registry of the operator.
+func (r *Registry) isRegistryRecordSet(recordSet RecordSet) bool {
+	if recordSet.Type != RecordTypeTXT {
+		return false
+	}
+	owner, ok := parseOwner(recordSet.Values)
+	return ok && owner.heritage == registryHeritage
+}
+
+// owner returns the owner of the record set and false if no owner is registered.
+func (r *Registry) owner(name, recordType string) (recordOwner, bool) {
+	candidates := []recordSetKey{
+		// The record set is a TXT record of a registry itself.
+		newRecordSetKey(name, recordType),
+		newRecordSetKey(r.registryName(name, recordType), RecordTypeTXT),
+		// The former external-dns registry kept the TXT record at the same name.
+		newRecordSetKey(name, RecordTypeTXT),
+	}
+	for _, key := range candidates {
+		recordSet, ok := r.index[key]
+		if !ok || recordSet.Type != RecordTypeTXT {
+			continue
+		}
+		if owner, ok := parseOwner(recordSet.Values); ok {
+			return owner, true
+		}
+	}
+	return recordOwner{}, false
+}
+
+// registryRecordSet returns the TXT record set which marks the given record
+// set as owned by the resource.
+func (r *Registry) registryRecordSet(resource string, recordSet RecordSet) RecordSet {
+	return RecordSet{
+		Name: r.registryName(recordSet.Name, recordSet.Type),
+		Type: RecordTypeTXT,
+		TTL:  defaultTTL,
+		Values: []string{fmt.Sprintf(`"heritage=%s,%s/owner=%s,%s/resource=%s"`,
+			registryHeritage, registryHeritage, r.ownerID, registryHeritage, resource)},
+	}
+}
+
+// registryName returns the name of the TXT record which holds the owner of
+// the record set, e.g. a-api.test.example.com for the A record of api.test.example.com.
+func (r *Registry) registryName(name, recordType string) string {
+	name = normalizeHostname(name)
+	if name == r.clusterDomain {
+		// The registry record of the cluster domain itself has to stay inside the zone.
+		return fmt.Sprintf("_%s-owner.%s", strings.ToLower(recordType), name)
+	}
+	return fmt.Sprintf("%s-%s", strings.ToLower(recordType), name)
+}
+
+// parseOwner parses a TXT registry value like
+// "heritage=external-dns,external-dns/owner=default,external-dns/resource=service/default/nginx".
+func parseOwner(values []string) (recordOwner, bool) {
+	for _, value := range values {
+		fields := map[string]string{}
+		for field := range strings.SplitSeq(strings.Trim(value, `"`), ",") {
+			key, fieldValue, _ := strings.Cut(field, "=")
+			fields[key] = fieldValue
+		}
+
+		heritage := fields["heritage"]
+		if heritage == "" {
+			continue
+		}
+		return recordOwner{
+			heritage: heritage,
+			id:       fields[heritage+"/owner"],
+			resource: fields[heritage+"/resource"],
+		}, true
+	}
+	return recordOwner{}, false
+}
+
+func newRecordSetKey(name, recordType string) recordSetKey {
+	return recordSetKey{name: normalizeHostname(name), recordType: recordType}
+}
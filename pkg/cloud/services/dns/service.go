@@ -0,0 +1,21 @@
+package dns
+
+import (
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+)
+
+// Service reconciles the DNS records of a cluster through a DNS provider.
+type Service struct {
+	scope    scope.DNSScope
+	provider Provider
+	// deleted collects the record sets deleted by the reconciliation.
+	deleted []RecordSet
+}
+
+// NewService returns a new service given the cluster scope and the DNS provider.
+func NewService(clusterScope scope.DNSScope, provider Provider) *Service {
+	return &Service{
+		scope:    clusterScope,
+		provider: provider,
+	}
+}
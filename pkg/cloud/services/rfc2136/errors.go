@@ -0,0 +1,32 @@
+package rfc2136
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+// IsUpdateFailed asserts updateFailedError.
+func IsUpdateFailed(err error) bool {
+	return microerror.Cause(err) == updateFailedError
+}
+
+var updateFailedError = &microerror.Error{
+	Kind: "updateFailedError",
+}
+
+// IsQueryFailed asserts queryFailedError.
+func IsQueryFailed(err error) bool {
+	return microerror.Cause(err) == queryFailedError
+}
+
+var queryFailedError = &microerror.Error{
+	Kind: "queryFailedError",
+}
+
+// IsPermissionDenied asserts permissionDeniedError.
+func IsPermissionDenied(err error) bool {
+	return microerror.Cause(err) == permissionDeniedError
+}
+
+var permissionDeniedError = &microerror.Error{
+	Kind: "permissionDeniedError",
+}
@@ -0,0 +1,537 @@
+package rfc2136
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/miekg/dns"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/giantswarm/microerror"
+
+	dnscache "github.com/giantswarm/dns-operator-route53/pkg/cloud/cache"
+	dnsservice "github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+)
+
+const (
+	tsigFudge = 300
+)
+
+// EnsureZone returns the zone which holds the cluster records. Zones cannot be
+// created through dynamic updates, so a dedicated cluster zone is only used if
+// the server is already authoritative for it. Otherwise the records are written
+// into the configured zone.
+func (s *Service) EnsureZone(ctx context.Context) (string, error) {
+	return s.FindZone(ctx)
+}
+
+// FindZone returns the zone which holds the cluster records, see EnsureZone.
+// The configured zone always exists, so a zone is found for every cluster.
+func (s *Service) FindZone(ctx context.Context) (string, error) {
+	clusterZone := dns.Fqdn(s.scope.ClusterDomain())
+
+	soa, err := s.lookup(ctx, clusterZone, dns.TypeSOA)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if len(soa) > 0 {
+		return clusterZone, nil
+	}
+
+	log.FromContext(ctx).V(4).Info(fmt.Sprintf("no dedicated zone found for cluster %s, using zone %s", s.scope.Name(), s.zone()))
+
+	return s.zone(), nil
+}
+
+// DelegateZone upserts the NS records of a dedicated cluster zone in the configured zone.
+func (s *Service) DelegateZone(ctx context.Context, zoneID string) error {
+	if zoneID == s.zone() {
+		// Records live in the configured zone, there is nothing to delegate.
+		return nil
+	}
+
+	nameservers, err := s.lookup(ctx, zoneID, dns.TypeNS)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	delegation := dnsservice.RecordSet{
+		Name: s.scope.ClusterDomain(),
+		Type: dnsservice.RecordTypeNS,
+		TTL:  s.scope.RecordTTLs().Delegation,
+	}
+	for _, rr := range nameservers {
+		delegation.Values = append(delegation.Values, rr.(*dns.NS).Ns)
+	}
+
+	rrs, err := buildRRs(delegation)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Queries for the delegation are answered from the cluster zone, so the
+	// delegation in the configured zone is checked with an update prerequisite.
+	upToDate, err := s.rrsetExists(ctx, s.zone(), rrs)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if upToDate {
+		return nil
+	}
+
+	update := new(dns.Msg)
+	update.SetUpdate(s.zone())
+	update.RemoveRRset(rrs[:1])
+	update.Insert(rrs)
+
+	return s.exchangeUpdate(ctx, update)
+}
+
+// ApplyRecordSetDiff sends a single signed update message for all record sets
+// of the diff. RFC 2136 can't create a record set without replacing it, so
+// creations are sent like upserts.
+func (s *Service) ApplyRecordSetDiff(ctx context.Context, zoneID string, diff dnsservice.RecordSetDiff) error {
+	log := log.FromContext(ctx)
+
+	update := new(dns.Msg)
+	update.SetUpdate(zoneID)
+
+	// Removals go first, servers ignore a CNAME which is added next to other records.
+	for _, obsolete := range diff.Delete {
+		recordType, ok := dns.StringToType[obsolete.Type]
+		if !ok {
+			return microerror.Maskf(updateFailedError, "unknown record type %q", obsolete.Type)
+		}
+
+		log.Info("Deleting record", "name", obsolete.Name, "type", obsolete.Type)
+		update.RemoveRRset([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: dns.Fqdn(obsolete.Name), Rrtype: recordType, Class: dns.ClassINET}}})
+	}
+
+	for _, desired := range slices.Concat(diff.Create, diff.Upsert) {
+		rrs, err := buildRRs(desired)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		update.RemoveRRset(rrs[:1])
+		update.Insert(rrs)
+	}
+
+	if len(update.Ns) == 0 {
+		return nil
+	}
+
+	return s.exchangeUpdate(ctx, update)
+}
+
+// DeleteZone removes all records below the cluster domain including the
+// delegation. A dedicated cluster zone itself is left on the server, only its
+// apex records are kept. The delegation is kept if the cluster zone contains
+// records owned by someone else, which is reported with an error matched by
+// dnsservice.IsForeignRecords.
+func (s *Service) DeleteZone(ctx context.Context) error {
+	zoneID, err := s.EnsureZone(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if zoneID != s.zone() {
+		foreign, err := s.deleteClusterRecords(ctx, zoneID)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if foreign > 0 {
+			return dnsservice.NewForeignRecordsError(strings.TrimSuffix(zoneID, "."), foreign)
+		}
+	}
+
+	// The configured zone either contains the delegation or all cluster records.
+	_, err = s.deleteClusterRecords(ctx, s.zone())
+	return microerror.Mask(err)
+}
+
+// ListRecordSets returns the record sets below the cluster domain in the zone.
+func (s *Service) ListRecordSets(ctx context.Context, zoneID string) ([]dnsservice.RecordSet, error) {
+	records, err := s.cachedTransferZone(ctx, zoneID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return toRecordSets(s.clusterRecords(zoneID, records)), nil
+}
+
+// PendingChanges always returns nil because dynamic updates are applied
+// synchronously by the server.
+func (s *Service) PendingChanges(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// deleteClusterRecords deletes all records below the cluster domain in the
+// zone which are not owned by someone else and returns the number of kept
+// record sets.
+func (s *Service) deleteClusterRecords(ctx context.Context, zoneID string) (int, error) {
+	records, err := s.transferZone(ctx, zoneID)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+	records = s.clusterRecords(zoneID, records)
+
+	registry := dnsservice.NewRegistry(s.scope, toRecordSets(records))
+
+	update := new(dns.Msg)
+	update.SetUpdate(zoneID)
+
+	seen := map[rrsetKey]bool{}
+	var foreign int
+	for _, rr := range records {
+		key := newRRsetKey(rr)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if registry.IsForeign(key.name, dns.TypeToString[key.rrtype]) {
+			foreign++
+			continue
+		}
+
+		update.RemoveRRset([]dns.RR{rr})
+	}
+
+	if len(update.Ns) == 0 {
+		return foreign, nil
+	}
+
+	return foreign, s.exchangeUpdate(ctx, update)
+}
+
+// clusterRecords returns the records below the cluster domain without the
+// apex records of a dedicated cluster zone, which cannot be removed.
+func (s *Service) clusterRecords(zoneID string, records []dns.RR) []dns.RR {
+	clusterZone := dns.Fqdn(s.scope.ClusterDomain())
+
+	var result []dns.RR
+	for _, rr := range records {
+		name := strings.ToLower(rr.Header().Name)
+		if name != clusterZone && !strings.HasSuffix(name, "."+clusterZone) {
+			continue
+		}
+		if name == zoneID && (rr.Header().Rrtype == dns.TypeSOA || rr.Header().Rrtype == dns.TypeNS) {
+			continue
+		}
+		result = append(result, rr)
+	}
+	return result
+}
+
+type rrsetKey struct {
+	name   string
+	rrtype uint16
+}
+
+func newRRsetKey(rr dns.RR) rrsetKey {
+	return rrsetKey{name: strings.ToLower(rr.Header().Name), rrtype: rr.Header().Rrtype}
+}
+
+// toRecordSets groups the records into record sets with values in zone file
+// presentation format.
+func toRecordSets(records []dns.RR) []dnsservice.RecordSet {
+	var result []dnsservice.RecordSet
+	index := map[rrsetKey]int{}
+	for _, rr := range records {
+		key := newRRsetKey(rr)
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, dnsservice.RecordSet{
+				Name: strings.TrimSuffix(key.name, "."),
+				Type: dns.TypeToString[key.rrtype],
+				TTL:  int64(rr.Header().Ttl),
+			})
+		}
+		result[i].Values = append(result[i].Values, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+	return result
+}
+
+// PlannedChanges returns the updates which were not sent because DryRun is enabled.
+func (s *Service) PlannedChanges() []dnsservice.PlannedChange {
+	return s.plan.Changes()
+}
+
+// plannedChanges converts the update section of an update message into
+// changes. A removed record set which is inserted again is an upsert.
+func plannedChanges(update *dns.Msg) []dnsservice.PlannedChange {
+	zone := strings.TrimSuffix(update.Question[0].Name, ".")
+
+	var changes []dnsservice.PlannedChange
+	index := map[rrsetKey]int{}
+	for _, rr := range update.Ns {
+		key := newRRsetKey(rr)
+		i, ok := index[key]
+		if !ok {
+			i = len(changes)
+			index[key] = i
+			changes = append(changes, dnsservice.PlannedChange{
+				Action: dnsservice.ActionDelete,
+				Zone:   zone,
+				RecordSet: dnsservice.RecordSet{
+					Name: strings.TrimSuffix(key.name, "."),
+					Type: dns.TypeToString[key.rrtype],
+				},
+			})
+		}
+		if rr.Header().Class == dns.ClassANY {
+			continue
+		}
+
+		changes[i].Action = dnsservice.ActionUpsert
+		changes[i].RecordSet.TTL = int64(rr.Header().Ttl)
+		changes[i].RecordSet.Values = append(changes[i].RecordSet.Values, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+
+	return changes
+}
+
+func (s *Service) exchangeUpdate(ctx context.Context, update *dns.Msg) error {
+	if s.config.DryRun {
+		s.plan.Add(plannedChanges(update)...)
+		return nil
+	}
+
+	// invalidate the cache
+	if err := dnscache.DeleteDNSCacheRecord(dnscache.ZoneRecords, update.Question[0].Name); err != nil && !errors.Is(err, bigcache.ErrEntryNotFound) {
+		return err
+	}
+
+	response, err := s.exchangeSigned(ctx, update)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if response.Rcode == dns.RcodeRefused || response.Rcode == dns.RcodeNotAuth {
+		return microerror.Maskf(permissionDeniedError, "update for zone %s failed with %s", update.Question[0].Name, dns.RcodeToString[response.Rcode])
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		return microerror.Maskf(updateFailedError, "update for zone %s failed with %s", update.Question[0].Name, dns.RcodeToString[response.Rcode])
+	}
+
+	return nil
+}
+
+// rrsetExists sends an update which only consists of the value dependent
+// "RRset exists" prerequisite for the given records, see RFC 2136 section 2.4.2.
+func (s *Service) rrsetExists(ctx context.Context, zoneID string, rrs []dns.RR) (bool, error) {
+	prerequisites := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		prerequisites = append(prerequisites, dns.Copy(rr))
+	}
+
+	check := new(dns.Msg)
+	check.SetUpdate(zoneID)
+	check.Used(prerequisites)
+
+	response, err := s.exchangeSigned(ctx, check)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	switch response.Rcode {
+	case dns.RcodeSuccess:
+		return true, nil
+	case dns.RcodeNXRrset:
+		return false, nil
+	default:
+		return false, microerror.Maskf(updateFailedError, "prerequisite check for zone %s failed with %s", zoneID, dns.RcodeToString[response.Rcode])
+	}
+}
+
+func (s *Service) exchangeSigned(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+	s.sign(msg)
+
+	response, _, err := s.client.ExchangeContext(ctx, msg, s.config.Server)
+	if err != nil {
+		return nil, microerror.Maskf(updateFailedError, "failed to send update for zone %s: %s", msg.Question[0].Name, err)
+	}
+
+	return response, nil
+}
+
+// lookup returns the records of the given name and type served by the DNS server.
+func (s *Service) lookup(ctx context.Context, name string, rrtype uint16) ([]dns.RR, error) {
+	query := new(dns.Msg)
+	query.SetQuestion(name, rrtype)
+	query.RecursionDesired = false
+
+	response, _, err := s.client.ExchangeContext(ctx, query, s.config.Server)
+	if err != nil {
+		return nil, microerror.Maskf(queryFailedError, "failed to query %s %s: %s", name, dns.TypeToString[rrtype], err)
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return nil, microerror.Maskf(queryFailedError, "query %s %s failed with %s", name, dns.TypeToString[rrtype], dns.RcodeToString[response.Rcode])
+	}
+
+	var result []dns.RR
+	for _, rr := range response.Answer {
+		if rr.Header().Rrtype == rrtype && strings.EqualFold(rr.Header().Name, name) {
+			result = append(result, rr)
+		}
+	}
+
+	return result, nil
+}
+
+// cachedTransferZone returns the records of the zone from the cache and
+// transfers the zone if it is not cached. The cache is invalidated with every
+// update of the zone.
+func (s *Service) cachedTransferZone(ctx context.Context, zoneID string) ([]dns.RR, error) {
+	log := log.FromContext(ctx)
+
+	cachedRecords, err := dnscache.GetDNSCacheRecord(dnscache.ZoneRecords, zoneID)
+	if err == nil {
+		records, err := parseRecords(cachedRecords)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		return records, nil
+	} else if !errors.Is(err, bigcache.ErrEntryNotFound) {
+		return nil, err
+	}
+
+	log.Info(fmt.Sprintf("no cached records found for zone %s", zoneID))
+
+	records, err := s.transferZone(ctx, zoneID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if err = dnscache.SetDNSCacheRecord(dnscache.ZoneRecords, zoneID, formatRecords(records)); err != nil {
+		// Large zones exceed the size of a cache shard and are transferred on every reconciliation.
+		log.Info(fmt.Sprintf("failed to cache records for zone %s: %s", zoneID, err))
+	}
+
+	return records, nil
+}
+
+// transferZone returns all records of the zone with an AXFR request. The
+// transfer is aborted when the context is done.
+func (s *Service) transferZone(ctx context.Context, zoneID string) ([]dns.RR, error) {
+	request := new(dns.Msg)
+	request.SetAxfr(zoneID)
+	s.sign(request)
+
+	timeout := defaultTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.config.Server)
+	if err != nil {
+		return nil, transferError(zoneID, err)
+	}
+	// The transfer sets a new read deadline for every message, so the
+	// connection is closed to abort it once the context is done.
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	transfer := &dns.Transfer{
+		Conn:         &dns.Conn{Conn: conn},
+		TsigSecret:   s.client.TsigSecret,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+	}
+
+	envelopes, err := transfer.In(request, s.config.Server)
+	if err != nil {
+		_ = conn.Close()
+		return nil, transferError(zoneID, err)
+	}
+
+	var records []dns.RR
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			if ctx.Err() != nil {
+				return nil, transferError(zoneID, ctx.Err())
+			}
+			return nil, transferError(zoneID, envelope.Error)
+		}
+		records = append(records, envelope.RR...)
+	}
+
+	return records, nil
+}
+
+// formatRecords returns the records in zone file format for the cache.
+func formatRecords(records []dns.RR) []byte {
+	var lines []string
+	for _, rr := range records {
+		lines = append(lines, rr.String())
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// parseRecords parses the cached records of formatRecords.
+func parseRecords(data []byte) ([]dns.RR, error) {
+	var records []dns.RR
+	parser := dns.NewZoneParser(strings.NewReader(string(data)), "", "")
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		records = append(records, rr)
+	}
+	if err := parser.Err(); err != nil {
+		return nil, microerror.Maskf(queryFailedError, "failed to parse cached records: %s", err)
+	}
+
+	return records, nil
+}
+
+// transferError reports rejected TSIG signatures as permission errors.
+func transferError(zoneID string, err error) error {
+	for _, tsigErr := range []error{dns.ErrAuth, dns.ErrNoSig, dns.ErrSig, dns.ErrTime} {
+		if errors.Is(err, tsigErr) {
+			return microerror.Maskf(permissionDeniedError, "transfer of zone %s failed: %s", zoneID, err)
+		}
+	}
+	return microerror.Maskf(queryFailedError, "failed to transfer zone %s: %s", zoneID, err)
+}
+
+func (s *Service) sign(msg *dns.Msg) {
+	if s.config.TSIGKeyName == "" {
+		return
+	}
+
+	algorithm := s.config.TSIGAlgorithm
+	if algorithm == "" {
+		algorithm = dns.HmacSHA256
+	}
+
+	msg.SetTsig(dns.Fqdn(s.config.TSIGKeyName), dns.Fqdn(algorithm), tsigFudge, time.Now().Unix())
+}
+
+func buildRRs(recordSet dnsservice.RecordSet) ([]dns.RR, error) {
+	if len(recordSet.Values) == 0 {
+		return nil, microerror.Maskf(updateFailedError, "record set %s %s has no values", recordSet.Name, recordSet.Type)
+	}
+
+	rrs := make([]dns.RR, 0, len(recordSet.Values))
+	for _, value := range recordSet.Values {
+		switch recordSet.Type {
+		case dnsservice.RecordTypeCNAME, dnsservice.RecordTypeNS:
+			value = dns.Fqdn(value)
+		}
+
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(recordSet.Name), recordSet.TTL, recordSet.Type, value))
+		if err != nil {
+			return nil, microerror.Maskf(updateFailedError, "invalid record set %s %s: %s", recordSet.Name, recordSet.Type, err)
+		}
+		rrs = append(rrs, rr)
+	}
+
+	return rrs, nil
+}
@@ -0,0 +1,600 @@
+package rfc2136
+
+import (
+	"context"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dnscache "github.com/giantswarm/dns-operator-route53/pkg/cloud/cache"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope/scopetest"
+	dnsservice "github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+)
+
+const (
+	testKeyName = "dns-operator."
+	testSecret  = "c2VjcmV0LXNlY3JldC1zZWNyZXQtc2VjcmV0"
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	dnscache.DNSOperatorCache, err = dnscache.NewDNSOperatorCache()
+	if err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// testServer is a minimal authoritative DNS server which supports queries,
+// TSIG signed dynamic updates and zone transfers.
+type testServer struct {
+	mu        sync.Mutex
+	zones     map[string][]dns.RR
+	updates   int
+	transfers int
+
+	addr   string
+	server *dns.Server
+}
+
+func newTestServer(t *testing.T, zones ...string) *testServer {
+	t.Helper()
+
+	ts := &testServer{zones: map[string][]dns.RR{}}
+	for _, zone := range zones {
+		ts.zones[zone] = []dns.RR{
+			mustRR(t, zone+" 3600 IN SOA ns1."+zone+" hostmaster."+zone+" 1 7200 900 1209600 86400"),
+			mustRR(t, zone+" 3600 IN NS ns1."+zone),
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	ts.addr = listener.Addr().String()
+	ts.server = &dns.Server{
+		Listener:          listener,
+		Handler:           ts,
+		TsigSecret:        map[string]string{testKeyName: testSecret},
+		MsgAcceptFunc:     func(dh dns.Header) dns.MsgAcceptAction { return dns.MsgAccept },
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go func() { _ = ts.server.ActivateAndServe() }()
+	<-started
+
+	t.Cleanup(func() { _ = ts.server.Shutdown() })
+
+	return ts
+}
+
+func (ts *testServer) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	response := new(dns.Msg)
+	response.SetReply(req)
+	response.Authoritative = true
+
+	question := req.Question[0]
+	zone := ts.findZone(question.Name)
+
+	switch {
+	case zone == "":
+		response.Rcode = dns.RcodeRefused
+	case req.Opcode == dns.OpcodeUpdate:
+		if req.IsTsig() == nil || w.TsigStatus() != nil {
+			response.Rcode = dns.RcodeNotAuth
+			break
+		}
+		if !ts.prerequisitesMet(zone, req.Answer) {
+			response.Rcode = dns.RcodeNXRrset
+			break
+		}
+		ts.applyUpdate(zone, req.Ns)
+	case question.Qtype == dns.TypeAXFR:
+		if req.IsTsig() == nil || w.TsigStatus() != nil {
+			response.Rcode = dns.RcodeNotAuth
+			break
+		}
+		ts.transfers++
+		records := append([]dns.RR{}, ts.zones[zone]...)
+		envelopes := make(chan *dns.Envelope, 1)
+		envelopes <- &dns.Envelope{RR: append(records, ts.zones[zone][0])}
+		close(envelopes)
+		_ = new(dns.Transfer).Out(w, req, envelopes)
+		return
+	default:
+		nameExists := false
+		for _, rr := range ts.zones[zone] {
+			if !strings.EqualFold(rr.Header().Name, question.Name) {
+				continue
+			}
+			nameExists = true
+			if rr.Header().Rrtype == question.Qtype {
+				response.Answer = append(response.Answer, dns.Copy(rr))
+			}
+		}
+		if !nameExists {
+			response.Rcode = dns.RcodeNameError
+		}
+	}
+
+	if tsig := req.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		response.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+	}
+	_ = w.WriteMsg(response)
+}
+
+// findZone returns the most specific zone hosted by the server for the given name.
+func (ts *testServer) findZone(name string) string {
+	var result string
+	for zone := range ts.zones {
+		if dns.IsSubDomain(zone, name) && len(zone) > len(result) {
+			result = zone
+		}
+	}
+	return result
+}
+
+// prerequisitesMet evaluates value dependent "RRset exists" prerequisites.
+func (ts *testServer) prerequisitesMet(zone string, prerequisites []dns.RR) bool {
+	rdata := func(rr dns.RR) string {
+		rr = dns.Copy(rr)
+		rr.Header().Ttl = 0
+		return strings.ToLower(rr.String())
+	}
+
+	expected := map[string]bool{}
+	for _, rr := range prerequisites {
+		expected[rdata(rr)] = true
+	}
+	for _, rr := range prerequisites {
+		var actual []string
+		for _, current := range ts.zones[zone] {
+			if strings.EqualFold(current.Header().Name, rr.Header().Name) && current.Header().Rrtype == rr.Header().Rrtype {
+				actual = append(actual, rdata(current))
+			}
+		}
+		if len(actual) != len(prerequisites) {
+			return false
+		}
+		for _, a := range actual {
+			if !expected[a] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (ts *testServer) applyUpdate(zone string, changes []dns.RR) {
+	if len(changes) == 0 {
+		return
+	}
+	ts.updates++
+
+	for _, change := range changes {
+		header := change.Header()
+		switch header.Class {
+		case dns.ClassANY:
+			var kept []dns.RR
+			for _, rr := range ts.zones[zone] {
+				if strings.EqualFold(rr.Header().Name, header.Name) && rr.Header().Rrtype == header.Rrtype {
+					continue
+				}
+				kept = append(kept, rr)
+			}
+			ts.zones[zone] = kept
+		case dns.ClassINET:
+			ts.zones[zone] = append(ts.zones[zone], dns.Copy(change))
+		}
+	}
+}
+
+// records returns the records of the given zone except the apex SOA and NS records.
+func (ts *testServer) records(zone string) []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var result []string
+	for _, rr := range ts.zones[zone] {
+		if rr.Header().Name == zone && (rr.Header().Rrtype == dns.TypeSOA || rr.Header().Rrtype == dns.TypeNS) {
+			continue
+		}
+		result = append(result, strings.ReplaceAll(rr.String(), "\t", " "))
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (ts *testServer) transferCount() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.transfers
+}
+
+func (ts *testServer) updateCount() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.updates
+}
+
+func mustRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rr
+}
+
+func newTestScope() *scopetest.ClusterScope {
+	ingress := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ingress-nginx-controller",
+			Namespace: "kube-system",
+			Labels:    map[string]string{"app.kubernetes.io/name": "ingress-nginx"},
+		},
+		Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "10.0.0.20"}},
+			},
+		},
+	}
+
+	return &scopetest.ClusterScope{
+		APIEndpointValue:       "10.0.0.10",
+		BaseDomainValue:        "example.com",
+		BastionIPValue:         "10.0.0.30",
+		ClusterValue:           &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "org-test", UID: "a1b2"}},
+		K8sClient:              fake.NewClientBuilder().WithObjects(ingress).Build(),
+		ManagementClusterValue: "mc",
+		ServiceDiscoveryValue:  dnsservice.DefaultServiceDiscovery(),
+		RecordTTLsValue:        dnsservice.DefaultRecordTTLs(),
+	}
+}
+
+func Test_Service_ReconcileAndDelete(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		zone                 string
+		zones                []string
+		expectedZone         string
+		expectedRecords      []string
+		expectedDelegation   []string
+		unrelatedBaseRecords []string
+	}{
+		{
+			name:         "case 0: records are written into the configured zone",
+			zone:         "example.com",
+			zones:        []string{"example.com."},
+			expectedZone: "example.com.",
+			expectedRecords: []string{
+				"*.test.example.com. 300 IN CNAME ingress.test.example.com.",
+				"a-api.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"a-bastion1.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"a-ingress.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"api.test.example.com. 300 IN A 10.0.0.10",
+				"bastion1.test.example.com. 300 IN A 10.0.0.30",
+				"cname-*.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"ingress.test.example.com. 300 IN A 10.0.0.20",
+				"other.example.com. 300 IN A 10.0.0.99",
+			},
+			unrelatedBaseRecords: []string{"other.example.com. 300 IN A 10.0.0.99"},
+		},
+		{
+			name:         "case 1: records are written into a dedicated cluster zone which gets delegated",
+			zone:         "example.com",
+			zones:        []string{"example.com.", "test.example.com."},
+			expectedZone: "test.example.com.",
+			expectedRecords: []string{
+				"*.test.example.com. 300 IN CNAME ingress.test.example.com.",
+				"a-api.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"a-bastion1.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"a-ingress.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"api.test.example.com. 300 IN A 10.0.0.10",
+				"bastion1.test.example.com. 300 IN A 10.0.0.30",
+				"cname-*.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"ingress.test.example.com. 300 IN A 10.0.0.20",
+			},
+			expectedDelegation: []string{
+				"test.example.com. 300 IN NS ns1.test.example.com.",
+			},
+		},
+		{
+			name:         "case 2: records are written into the zone of the base domain without a configured zone",
+			zones:        []string{"example.com."},
+			expectedZone: "example.com.",
+			expectedRecords: []string{
+				"*.test.example.com. 300 IN CNAME ingress.test.example.com.",
+				"a-api.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"a-bastion1.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"a-ingress.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"api.test.example.com. 300 IN A 10.0.0.10",
+				"bastion1.test.example.com. 300 IN A 10.0.0.30",
+				"cname-*.test.example.com. 300 IN TXT \"heritage=dns-operator-route53,dns-operator-route53/owner=mc/a1b2,dns-operator-route53/resource=cluster/org-test/test\"",
+				"ingress.test.example.com. 300 IN A 10.0.0.20",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			ctx := context.Background()
+			server := newTestServer(t, tc.zones...)
+			for _, record := range tc.unrelatedBaseRecords {
+				server.zones["example.com."] = append(server.zones["example.com."], mustRR(t, record))
+			}
+
+			clusterScope := newTestScope()
+			provider := NewService(clusterScope, Config{
+				Server:      server.addr,
+				Zone:        tc.zone,
+				TSIGKeyName: testKeyName,
+				TSIGSecret:  testSecret,
+			})
+			service := dnsservice.NewService(clusterScope, provider)
+
+			if err := service.Reconcile(ctx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := server.records(tc.expectedZone); !slices.Equal(got, tc.expectedRecords) {
+				t.Fatalf("expected records %v, got %v", tc.expectedRecords, got)
+			}
+			if tc.expectedZone != "example.com." {
+				if got := server.records("example.com."); !slices.Equal(got, tc.expectedDelegation) {
+					t.Fatalf("expected delegation %v, got %v", tc.expectedDelegation, got)
+				}
+			}
+
+			// A second reconciliation must not send any update.
+			updates := server.updateCount()
+			if err := service.Reconcile(ctx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if server.updateCount() != updates {
+				t.Fatalf("expected no update for unchanged records, got %d", server.updateCount()-updates)
+			}
+
+			if err := service.Delete(ctx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := server.records(tc.expectedZone); tc.expectedZone != "example.com." && len(got) != 0 {
+				t.Fatalf("expected cluster zone to be empty, got %v", got)
+			}
+			if got := server.records("example.com."); !slices.Equal(got, tc.unrelatedBaseRecords) {
+				t.Fatalf("expected base zone records %v, got %v", tc.unrelatedBaseRecords, got)
+			}
+		})
+	}
+}
+
+func Test_Service_DeleteKeepsZoneWithForeignRecords(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	ctx := context.Background()
+	server := newTestServer(t, "example.com.", "test.example.com.")
+
+	clusterScope := newTestScope()
+	provider := NewService(clusterScope, Config{
+		Server:      server.addr,
+		Zone:        "example.com",
+		TSIGKeyName: testKeyName,
+		TSIGSecret:  testSecret,
+	})
+	service := dnsservice.NewService(clusterScope, provider)
+
+	if err := service.Reconcile(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	foreign := []string{
+		"a-app.test.example.com. 300 IN TXT \"heritage=external-dns,external-dns/owner=default\"",
+		"app.test.example.com. 300 IN A 10.0.0.50",
+	}
+	server.mu.Lock()
+	for _, record := range foreign {
+		server.zones["test.example.com."] = append(server.zones["test.example.com."], mustRR(t, record))
+	}
+	server.mu.Unlock()
+	delegation := server.records("example.com.")
+
+	err := service.Delete(ctx)
+	if !dnsservice.IsForeignRecords(err) {
+		t.Fatalf("expected foreignRecordsError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "test.example.com") || !strings.Contains(err.Error(), "2 record sets") {
+		t.Fatalf("expected error to name the kept zone and record sets, got %v", err)
+	}
+	if got := server.records("test.example.com."); !slices.Equal(got, foreign) {
+		t.Fatalf("expected only foreign records %v, got %v", foreign, got)
+	}
+	if got := server.records("example.com."); len(got) == 0 || !slices.Equal(got, delegation) {
+		t.Fatalf("expected delegation %v to be kept, got %v", delegation, got)
+	}
+}
+
+func Test_Service_InvalidTSIGSecret(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	ctx := context.Background()
+	server := newTestServer(t, "example.com.")
+
+	clusterScope := newTestScope()
+	provider := NewService(clusterScope, Config{
+		Server:      server.addr,
+		Zone:        "example.com",
+		TSIGKeyName: testKeyName,
+		TSIGSecret:  "d3Jvbmctc2VjcmV0",
+	})
+
+	err := dnsservice.NewService(clusterScope, provider).Reconcile(ctx)
+	if !IsPermissionDenied(err) {
+		t.Fatalf("expected permissionDeniedError, got %v", err)
+	}
+	if got := server.records("example.com."); len(got) != 0 {
+		t.Fatalf("expected no records, got %v", got)
+	}
+}
+
+func Test_Service_DryRun(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	ctx := context.Background()
+	server := newTestServer(t, "example.com.")
+
+	clusterScope := newTestScope()
+	config := Config{
+		Server:      server.addr,
+		Zone:        "example.com",
+		TSIGKeyName: testKeyName,
+		TSIGSecret:  testSecret,
+	}
+	dryRunConfig := config
+	dryRunConfig.DryRun = true
+
+	provider := NewService(clusterScope, dryRunConfig)
+	if err := dnsservice.NewService(clusterScope, provider).Reconcile(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := server.records("example.com."); len(got) != 0 {
+		t.Fatalf("expected no records in dry-run mode, got %v", got)
+	}
+	if got := len(provider.PlannedChanges()); got != 8 {
+		t.Fatalf("expected 8 planned changes, got %v", provider.PlannedChanges())
+	}
+	for _, change := range provider.PlannedChanges() {
+		if change.Action != dnsservice.ActionUpsert || change.Zone != "example.com" {
+			t.Fatalf("expected upsert in zone example.com, got %s", change)
+		}
+	}
+
+	if err := dnsservice.NewService(clusterScope, NewService(clusterScope, config)).Reconcile(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := server.records("example.com.")
+	updates := server.updateCount()
+
+	provider = NewService(clusterScope, dryRunConfig)
+	if err := dnsservice.NewService(clusterScope, provider).Delete(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if server.updateCount() != updates {
+		t.Fatalf("expected no update in dry-run mode, got %d", server.updateCount()-updates)
+	}
+	if got := server.records("example.com."); !slices.Equal(got, records) {
+		t.Fatalf("expected records %v, got %v", records, got)
+	}
+	if got := len(provider.PlannedChanges()); got != len(records) {
+		t.Fatalf("expected %d planned deletions, got %v", len(records), provider.PlannedChanges())
+	}
+	for _, change := range provider.PlannedChanges() {
+		if change.Action != dnsservice.ActionDelete {
+			t.Fatalf("expected deletion, got %s", change)
+		}
+	}
+}
+
+func Test_Service_ZoneTransferCache(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	ctx := context.Background()
+	server := newTestServer(t, "example.com.")
+
+	clusterScope := newTestScope()
+	provider := NewService(clusterScope, Config{
+		Server:      server.addr,
+		Zone:        "example.com",
+		TSIGKeyName: testKeyName,
+		TSIGSecret:  testSecret,
+	})
+
+	if _, err := provider.ListRecordSets(ctx, "example.com."); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recordSets, err := provider.ListRecordSets(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recordSets) != 0 {
+		t.Fatalf("expected no cluster records, got %v", recordSets)
+	}
+	if got := server.transferCount(); got != 1 {
+		t.Fatalf("expected the zone to be transferred once, got %d transfers", got)
+	}
+
+	diff := dnsservice.RecordSetDiff{Create: []dnsservice.RecordSet{{Name: "api.test.example.com", Type: "TXT", TTL: 300, Values: []string{`"a b"`}}}}
+	if err := provider.ApplyRecordSetDiff(ctx, "example.com.", diff); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for range 2 {
+		recordSets, err = provider.ListRecordSets(ctx, "example.com.")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.EqualFunc(recordSets, diff.Create, dnsservice.RecordSet.Equal) {
+			t.Fatalf("expected record sets %v, got %v", diff.Create, recordSets)
+		}
+	}
+	if got := server.transferCount(); got != 2 {
+		t.Fatalf("expected the zone to be transferred again after the update, got %d transfers", got)
+	}
+}
+
+func Test_Service_ZoneTransferContext(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	// The server accepts the connection but never answers the transfer.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	provider := NewService(newTestScope(), Config{
+		Server:      listener.Addr().String(),
+		Zone:        "example.com",
+		TSIGKeyName: testKeyName,
+		TSIGSecret:  testSecret,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = provider.ListRecordSets(ctx, "example.com.")
+	if !IsQueryFailed(err) {
+		t.Fatalf("expected queryFailedError, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > defaultTimeout/2 {
+		t.Fatalf("expected the transfer to be aborted with the context, took %s", elapsed)
+	}
+}
@@ -0,0 +1,68 @@
+package rfc2136
+
+import (
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+	dnsservice "github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+)
+
+const (
+	// ProviderName is the name of the RFC 2136 DNS provider.
+	ProviderName = "rfc2136"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Config holds the settings to send RFC 2136 dynamic updates to an authoritative DNS server.
+type Config struct {
+	// Server is the address of the DNS server, e.g. 10.0.0.53:53.
+	Server string
+	// Zone is the zone which contains the base domains. Cluster records are
+	// written into this zone unless the server hosts a dedicated zone for the
+	// cluster. The base domain of the cluster is used if empty.
+	Zone string
+	// TSIGKeyName is the name of the TSIG key used to sign the updates.
+	TSIGKeyName string
+	// TSIGAlgorithm is the algorithm of the TSIG key, e.g. hmac-sha256.
+	TSIGAlgorithm string
+	// TSIGSecret is the base64 encoded TSIG secret.
+	TSIGSecret string
+	// DryRun only plans the updates instead of sending them, see PlannedChanges.
+	DryRun bool
+}
+
+// Service sends RFC 2136 dynamic updates for the cluster records.
+type Service struct {
+	scope  scope.DNSScope
+	config Config
+	client *dns.Client
+	plan   dnsservice.Plan
+}
+
+// NewService returns a new RFC 2136 DNS provider for the given cluster scope.
+func NewService(clusterScope scope.DNSScope, config Config) *Service {
+	client := &dns.Client{
+		Net:     "tcp",
+		Timeout: defaultTimeout,
+	}
+	if config.TSIGKeyName != "" {
+		client.TsigSecret = map[string]string{dns.Fqdn(config.TSIGKeyName): config.TSIGSecret}
+	}
+
+	return &Service{
+		scope:  clusterScope,
+		config: config,
+		client: client,
+	}
+}
+
+// zone returns the fully qualified zone which contains the base domain of the cluster.
+func (s *Service) zone() string {
+	if s.config.Zone != "" {
+		return dns.Fqdn(s.config.Zone)
+	}
+	return dns.Fqdn(s.scope.BaseDomain())
+}
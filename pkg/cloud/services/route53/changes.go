@@ -0,0 +1,116 @@
+package route53
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/awserrors"
+	awsmetrics "github.com/giantswarm/dns-operator-route53/pkg/cloud/metrics"
+)
+
+// pendingChange is a submitted Route53 change which was not observed as INSYNC yet.
+type pendingChange struct {
+	id          string
+	submittedAt time.Time
+	// baseZone is true for changes of the base hosted zone, which may live in
+	// another AWS account than the cluster hosted zone.
+	baseZone bool
+}
+
+var (
+	pendingChangesMu sync.Mutex
+	// pendingChanges contains the pending changes of all clusters keyed by the
+	// cluster. The changes outlive the Service, which is created per reconciliation.
+	pendingChanges = map[string][]pendingChange{}
+)
+
+// PendingChanges polls the status of the changes submitted for the cluster
+// and returns the IDs of the changes which are not INSYNC yet. Changes are
+// only tracked if WaitForChangePropagation is enabled.
+func (s *Service) PendingChanges(ctx context.Context) ([]string, error) {
+	if !s.config.WaitForChangePropagation {
+		return nil, nil
+	}
+
+	pendingChangesMu.Lock()
+	changes := slices.Clone(pendingChanges[s.changesKey()])
+	pendingChangesMu.Unlock()
+
+	var pending, done []string
+	for _, change := range changes {
+		route53Client := s.Route53Client
+		if change.baseZone {
+			route53Client = s.BaseRoute53Client
+		}
+
+		output, err := route53Client.GetChangeWithContext(ctx, &route53.GetChangeInput{Id: aws.String(change.id)})
+		if code, ok := awserrors.Code(err); ok && code == route53.ErrCodeNoSuchChange {
+			// Route53 only keeps changes for a limited time.
+			done = append(done, change.id)
+			continue
+		} else if err != nil {
+			return nil, wrapRoute53Error(err)
+		}
+
+		if aws.StringValue(output.ChangeInfo.Status) == route53.ChangeStatusInsync {
+			awsmetrics.ObserveChangePropagation(time.Since(change.submittedAt))
+			done = append(done, change.id)
+			continue
+		}
+
+		pending = append(pending, change.id)
+	}
+
+	pendingChangesMu.Lock()
+	defer pendingChangesMu.Unlock()
+
+	key := s.changesKey()
+	pendingChanges[key] = slices.DeleteFunc(pendingChanges[key], func(change pendingChange) bool {
+		return slices.Contains(done, change.id)
+	})
+	if len(pendingChanges[key]) == 0 {
+		delete(pendingChanges, key)
+	}
+
+	return pending, nil
+}
+
+// trackChange remembers the submitted change until it is observed as INSYNC.
+func (s *Service) trackChange(info *route53.ChangeInfo, baseZone bool) {
+	if !s.config.WaitForChangePropagation || info == nil || aws.StringValue(info.Status) == route53.ChangeStatusInsync {
+		return
+	}
+
+	submittedAt := aws.TimeValue(info.SubmittedAt)
+	if submittedAt.IsZero() {
+		submittedAt = time.Now()
+	}
+
+	pendingChangesMu.Lock()
+	defer pendingChangesMu.Unlock()
+
+	key := s.changesKey()
+	pendingChanges[key] = append(pendingChanges[key], pendingChange{
+		id:          aws.StringValue(info.Id),
+		submittedAt: submittedAt,
+		baseZone:    baseZone,
+	})
+}
+
+// forgetChanges drops the pending changes of the cluster, e.g. once its zone is deleted.
+func (s *Service) forgetChanges() {
+	pendingChangesMu.Lock()
+	defer pendingChangesMu.Unlock()
+
+	delete(pendingChanges, s.changesKey())
+}
+
+func (s *Service) changesKey() string {
+	return client.ObjectKeyFromObject(s.scope.Cluster()).String()
+}
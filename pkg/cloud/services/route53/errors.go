@@ -29,36 +29,29 @@ var hostedZoneNotFoundError = &microerror.Error{
 	Kind: "hostedZoneNotFoundError",
 }
 
-func IsThrottlingRateExceededError(err error) bool {
-	return microerror.Cause(err) == rateLimitHitError
-}
-
-var rateLimitHitError = &microerror.Error{
-	Kind: "throttlingRateExceededError",
+// IsAccessDenied asserts accessDeniedError.
+func IsAccessDenied(err error) bool {
+	return microerror.Cause(err) == accessDeniedError
 }
 
-// IsIngressNotRead asserts ingressNotReadyError.
-func IsIngressNotReady(err error) bool {
-	return microerror.Cause(err) == ingressNotReadyError
+var accessDeniedError = &microerror.Error{
+	Kind: "accessDeniedError",
 }
 
-var ingressNotReadyError = &microerror.Error{
-	Kind: "ingressNotReadyError",
-}
-
-// IsTooManyICServices asserts tooManyICServicesError.
-func IsTooManyICServices(err error) bool {
-	return microerror.Cause(err) == tooManyICServicesError
+func IsThrottlingRateExceededError(err error) bool {
+	return microerror.Cause(err) == rateLimitHitError
 }
 
-var tooManyICServicesError = &microerror.Error{
-	Kind: "tooManyICServicesError",
+var rateLimitHitError = &microerror.Error{
+	Kind: "throttlingRateExceededError",
 }
 
 func wrapRoute53Error(err error) error {
 	if code, ok := awserrors.Code(errors.Cause(err)); ok {
 		switch code {
-		case route53.ErrCodeHostedZoneNotFound:
+		case "AccessDenied", "AccessDeniedException":
+			return microerror.Maskf(accessDeniedError, "%s", err)
+		case route53.ErrCodeHostedZoneNotFound, route53.ErrCodeNoSuchHostedZone:
 			return microerror.Mask(hostedZoneNotFoundError)
 		case route53.ErrCodeInvalidChangeBatch:
 			if strings.Contains(err.Error(), "not found") {
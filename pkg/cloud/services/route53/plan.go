@@ -0,0 +1,31 @@
+package route53
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+)
+
+// plannedHostedZoneID identifies the cluster hosted zone in dry-run mode
+// while it does not exist yet. It has no records and no name servers.
+const plannedHostedZoneID = "dry-run-planned-hosted-zone"
+
+// PlannedChanges returns the changes which were not submitted to Route53
+// because DryRun is enabled.
+func (s *Service) PlannedChanges() []dns.PlannedChange {
+	return s.plan.Changes()
+}
+
+// planChanges adds the changes of a change batch for the given zone to the plan.
+func (s *Service) planChanges(zone string, changes []*route53.Change) {
+	for _, change := range changes {
+		for _, recordSet := range toRecordSets([]*route53.ResourceRecordSet{change.ResourceRecordSet}) {
+			s.plan.Add(dns.PlannedChange{
+				Action:    aws.StringValue(change.Action),
+				Zone:      zone,
+				RecordSet: recordSet,
+			})
+		}
+	}
+}
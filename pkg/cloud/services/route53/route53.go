@@ -5,208 +5,307 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/allegro/bigcache/v3"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/route53"
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/giantswarm/microerror"
 
 	dnscache "github.com/giantswarm/dns-operator-route53/pkg/cloud/cache"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
 )
 
 const (
-	ingressServiceSelector = "app.kubernetes.io/name in (ingress-nginx,nginx-ingress-controller)"
-	ingressAppNamespace    = "kube-system"
-
-	gatewayNamespace              = "envoy-gateway-system"
-	externalDNSManagedAnnotation  = "giantswarm.io/external-dns"
-	externalDNSManagedValue       = "managed"
-	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
-
-	ttl = 300
-
+	actionCreate = "CREATE"
 	actionDelete = "DELETE"
 	actionUpsert = "UPSERT"
-)
 
-type gatewayService struct {
-	hostname string
-	ip       string
-}
+	// Route53 limits of a single change batch. The values of an UPSERT count twice.
+	maxChangeBatchRecords    = 1000
+	maxChangeBatchCharacters = 32000
+)
 
-type ingressService struct {
-	hostname string
-	ip       string
+// ReconcileRoute53 reconciles the cluster hosted zone and all its records in Route53.
+func (s *Service) ReconcileRoute53(ctx context.Context) error {
+	return dns.NewService(s.scope, s).Reconcile(ctx)
 }
 
+// DeleteRoute53 deletes the cluster hosted zone including all its records and its delegation.
 func (s *Service) DeleteRoute53(ctx context.Context) error {
+	return dns.NewService(s.scope, s).Delete(ctx)
+}
 
+// EnsureZone returns the ID of the cluster hosted zone and creates the zone if it does not exist yet.
+func (s *Service) EnsureZone(ctx context.Context) (string, error) {
 	log := log.FromContext(ctx)
-	log.Info("Deleting hosted DNS zone")
+
+	cachedHostedZoneID, err := dnscache.GetDNSCacheRecord(dnscache.ZoneID, s.scope.ClusterDomain())
+	if errors.Is(err, bigcache.ErrEntryNotFound) {
+		log.Info(fmt.Sprintf("no hostedZoneID found in local cache for cluster %s", s.scope.Name()))
+		// Describe or create.
+		hostedZoneID, err := s.describeClusterHostedZone(ctx)
+		if IsHostedZoneNotFound(err) && s.config.DryRun {
+			s.plan.Add(dns.PlannedChange{Action: dns.ActionCreateZone, Zone: s.scope.ClusterDomain()})
+			return plannedHostedZoneID, nil
+		} else if IsHostedZoneNotFound(err) {
+			hostedZoneID, err = s.createClusterHostedZone(ctx)
+			if err != nil {
+				return "", microerror.Mask(err)
+			}
+			log.Info(fmt.Sprintf("Created new hosted zone for cluster %s", s.scope.Name()))
+		} else if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		if err := dnscache.SetDNSCacheRecord(dnscache.ZoneID, s.scope.ClusterDomain(), []byte(hostedZoneID)); err != nil {
+			return "", err
+		}
+		cachedHostedZoneID, err = dnscache.GetDNSCacheRecord(dnscache.ZoneID, s.scope.ClusterDomain())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return string(cachedHostedZoneID), nil
+}
+
+// FindZone returns the ID of the cluster hosted zone or an empty ID if the
+// zone does not exist.
+func (s *Service) FindZone(ctx context.Context) (string, error) {
+	cachedHostedZoneID, err := dnscache.GetDNSCacheRecord(dnscache.ZoneID, s.scope.ClusterDomain())
+	if err == nil {
+		return string(cachedHostedZoneID), nil
+	} else if !errors.Is(err, bigcache.ErrEntryNotFound) {
+		return "", err
+	}
 
 	hostedZoneID, err := s.describeClusterHostedZone(ctx)
 	if IsHostedZoneNotFound(err) {
-		return nil
+		return "", nil
 	} else if err != nil {
-		return microerror.Mask(err)
+		return "", microerror.Mask(err)
 	}
 
-	if err := s.deleteClusterRecords(ctx, hostedZoneID); err != nil {
-		return microerror.Mask(err)
+	if err := dnscache.SetDNSCacheRecord(dnscache.ZoneID, s.scope.ClusterDomain(), []byte(hostedZoneID)); err != nil {
+		return "", err
 	}
 
-	// Then delete delegation record in base hosted zone
-	err = s.changeClusterNSDelegation(ctx, hostedZoneID, actionDelete)
-	if IsNotFound(err) {
-		// Entry does not exist, fall through
-	} else if err != nil {
-		return microerror.Mask(err)
-	}
+	return hostedZoneID, nil
+}
 
-	// Finally delete DNS zone for cluster
-	err = s.deleteClusterHostedZone(ctx, hostedZoneID)
+// DelegateZone upserts the NS records of the cluster hosted zone in the base hosted zone.
+func (s *Service) DelegateZone(ctx context.Context, hostedZoneID string) error {
+	return s.changeClusterNSDelegation(ctx, hostedZoneID, actionUpsert)
+}
+
+// ApplyRecordSetDiff applies the given diff to the cluster hosted zone. Large
+// diffs are split into several change requests, see splitChangeBatches.
+// Deletions of record sets which do not exist anymore are skipped.
+func (s *Service) ApplyRecordSetDiff(ctx context.Context, hostedZoneID string, diff dns.RecordSetDiff) error {
+	log := log.FromContext(ctx)
+	var changes []*route53.Change
+
+	recordSets, err := s.cachedResourceRecordSets(ctx, hostedZoneID)
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	log.Info(fmt.Sprintf("Deleting hosted zone completed successfully for cluster %s", s.scope.Name()))
-	return nil
-}
 
-func (s *Service) ReconcileRoute53(ctx context.Context) error {
-	log := log.FromContext(ctx)
-	log.Info("Reconciling hosted DNS zone")
+	// Deletions go first and are directly followed by the record sets which
+	// replace them, so e.g. an A record is replaced with a CNAME in the same
+	// change request.
+	replaced := map[int]bool{}
+	for _, obsolete := range diff.Delete {
+		current := findResourceRecordSet(recordSets, obsolete.Name, obsolete.Type)
+		if current == nil {
+			continue
+		}
+		log.Info("orphaned record found", "name", *current.Name, "type", *current.Type)
+		changes = append(changes, &route53.Change{
+			Action:            aws.String(actionDelete),
+			ResourceRecordSet: current,
+		})
 
-	cachedHostedZoneID, err := dnscache.GetDNSCacheRecord(dnscache.ZoneID, s.scope.Name())
-	if errors.Is(err, bigcache.ErrEntryNotFound) {
-		log.Info(fmt.Sprintf("no hostedZoneID found in local cache for cluster %s", s.scope.Name()))
-		// Describe or create.
-		hostedZoneID, err := s.describeClusterHostedZone(ctx)
-		if IsHostedZoneNotFound(err) {
-			hostedZoneID, err = s.createClusterHostedZone(ctx)
-			if err != nil {
-				return microerror.Mask(err)
+		for i, desired := range diff.Create {
+			if !replaced[i] && desired.Name == obsolete.Name {
+				changes = append(changes, buildChange(desired, actionCreate))
+				replaced[i] = true
 			}
-			log.Info(fmt.Sprintf("Created new hosted zone for cluster %s", s.scope.Name()))
-		} else if err != nil {
-			return microerror.Mask(err)
 		}
+	}
 
-		if err := dnscache.SetDNSCacheRecord(dnscache.ZoneID, s.scope.Name(), []byte(hostedZoneID)); err != nil {
-			return err
+	for i, desired := range diff.Create {
+		if !replaced[i] {
+			changes = append(changes, buildChange(desired, actionCreate))
 		}
-		cachedHostedZoneID, err = dnscache.GetDNSCacheRecord(dnscache.ZoneID, s.scope.Name())
-		if err != nil {
+	}
+	for _, desired := range diff.Upsert {
+		changes = append(changes, buildChange(desired, actionUpsert))
+	}
+
+	if len(changes) > 0 {
+		// invalidate the cache
+		if err = dnscache.DeleteDNSCacheRecord(dnscache.ZoneRecords, hostedZoneID); err != nil && !errors.Is(err, bigcache.ErrEntryNotFound) {
 			return err
 		}
+
+		if err := s.changeResourceRecordSets(ctx, hostedZoneID, changes); err != nil {
+			return microerror.Mask(err)
+		}
 	}
 
-	if err := s.changeClusterNSDelegation(ctx, string(cachedHostedZoneID), actionUpsert); err != nil {
+	return nil
+}
+
+// DeleteZone deletes all records of the cluster hosted zone, its delegation in
+// the base hosted zone and finally the hosted zone itself. The zone and its
+// delegation are kept if the zone contains records owned by someone else,
+// which is reported with an error matched by dns.IsForeignRecords.
+func (s *Service) DeleteZone(ctx context.Context) error {
+	hostedZoneID, err := s.describeClusterHostedZone(ctx)
+	if IsHostedZoneNotFound(err) {
+		return nil
+	} else if err != nil {
 		return microerror.Mask(err)
 	}
 
-	if err := s.changeClusterRecords(ctx, string(cachedHostedZoneID), actionUpsert); err != nil {
+	foreign, err := s.deleteClusterRecords(ctx, hostedZoneID)
+	if err != nil {
 		return microerror.Mask(err)
 	}
+	if foreign > 0 {
+		s.forgetChanges()
+		return dns.NewForeignRecordsError(s.scope.ClusterDomain(), foreign)
+	}
 
-	if err := s.changeClusterIngressRecords(ctx, string(cachedHostedZoneID), actionUpsert); err != nil {
+	// Then delete delegation record in base hosted zone
+	err = s.changeClusterNSDelegation(ctx, hostedZoneID, actionDelete)
+	if IsNotFound(err) {
+		// Entry does not exist, fall through
+	} else if err != nil {
 		return microerror.Mask(err)
 	}
 
-	if err := s.changeClusterGatewayRecords(ctx, string(cachedHostedZoneID), actionUpsert); err != nil {
+	// Finally delete DNS zone for cluster
+	err = s.deleteClusterHostedZone(ctx, hostedZoneID)
+	if err != nil {
 		return microerror.Mask(err)
 	}
+	s.forgetChanges()
 
 	return nil
 }
 
-func (s *Service) buildARecordChange(hostedZoneID, recordName, recordValue, action string) *route53.Change {
-	return &route53.Change{
-		Action: aws.String(action),
-		ResourceRecordSet: &route53.ResourceRecordSet{
-			Name: aws.String(fmt.Sprintf("%s.%s", recordName, s.scope.ClusterDomain())),
-			Type: aws.String("A"),
-			TTL:  aws.Int64(ttl),
-			ResourceRecords: []*route53.ResourceRecord{
-				{
-					Value: aws.String(recordValue),
-				},
-			},
-		},
+// ListRecordSets returns the record sets of the cluster hosted zone.
+func (s *Service) ListRecordSets(ctx context.Context, hostedZoneID string) ([]dns.RecordSet, error) {
+	recordSets, err := s.cachedResourceRecordSets(ctx, hostedZoneID)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
+
+	recordSets = slices.DeleteFunc(slices.Clone(recordSets), func(recordSet *route53.ResourceRecordSet) bool {
+		return isApexRecordSet(recordSet, s.scope.ClusterDomain())
+	})
+
+	return toRecordSets(recordSets), nil
 }
 
-func (s *Service) changeClusterIngressRecords(ctx context.Context, hostedZoneID, action string) error {
-	ingress, err := s.getIngressService(ctx)
-	if err != nil {
-		return microerror.Mask(err)
-	} else if ingress == nil {
-		// Ingress service is not installed in this cluster.
-		return nil
-	}
+func toRecordSets(recordSets []*route53.ResourceRecordSet) []dns.RecordSet {
+	result := make([]dns.RecordSet, 0, len(recordSets))
+	for _, recordSet := range recordSets {
+		if recordSet.Name == nil || recordSet.Type == nil {
+			continue
+		}
 
-	wildcardCNAMETarget := s.scope.WildcardCNAMETarget()
-	if wildcardCNAMETarget == "" {
-		wildcardCNAMETarget = fmt.Sprintf("ingress.%s", s.scope.ClusterDomain())
+		converted := dns.RecordSet{
+			Name: normalizeRecordName(*recordSet.Name),
+			Type: *recordSet.Type,
+			TTL:  aws.Int64Value(recordSet.TTL),
+		}
+		if recordSet.AliasTarget != nil {
+			converted.Values = []string{normalizeRecordName(strings.ToLower(aws.StringValue(recordSet.AliasTarget.DNSName)))}
+			converted.AliasHostedZoneID = aws.StringValue(recordSet.AliasTarget.HostedZoneId)
+		}
+		for _, record := range recordSet.ResourceRecords {
+			converted.Values = append(converted.Values, aws.StringValue(record.Value))
+		}
+		result = append(result, converted)
 	}
+	return result
+}
 
-	log.FromContext(ctx).Info("Reconciling ingress DNS records", "ingressHostname", ingress.hostname, "ingressIP", ingress.ip, "wildcardCNAMETarget", wildcardCNAMETarget)
+func isApexRecordSet(recordSet *route53.ResourceRecordSet, clusterDomain string) bool {
+	return strings.TrimSuffix(*recordSet.Name, ".") == clusterDomain &&
+		(*recordSet.Type == route53.RRTypeNs || *recordSet.Type == route53.RRTypeSoa)
+}
 
-	input := &route53.ChangeResourceRecordSetsInput{
-		HostedZoneId: aws.String(hostedZoneID),
-		ChangeBatch: &route53.ChangeBatch{
-			Changes: []*route53.Change{
-				{
-					Action: aws.String(action),
-					ResourceRecordSet: &route53.ResourceRecordSet{
-						Name: aws.String(ingress.hostname),
-						Type: aws.String("A"),
-						TTL:  aws.Int64(ttl),
-						ResourceRecords: []*route53.ResourceRecord{
-							{Value: aws.String(ingress.ip)},
-						},
-					},
-				},
-				{
-					Action: aws.String(action),
-					ResourceRecordSet: &route53.ResourceRecordSet{
-						Name: aws.String(fmt.Sprintf("*.%s", s.scope.ClusterDomain())),
-						Type: aws.String("CNAME"),
-						TTL:  aws.Int64(300),
-						ResourceRecords: []*route53.ResourceRecord{
-							{
-								Value: aws.String(wildcardCNAMETarget),
-							},
-						},
-					},
+func buildChange(recordSet dns.RecordSet, action string) *route53.Change {
+	if recordSet.AliasHostedZoneID != "" {
+		return &route53.Change{
+			Action: aws.String(action),
+			ResourceRecordSet: &route53.ResourceRecordSet{
+				Name: aws.String(recordSet.Name),
+				Type: aws.String(recordSet.Type),
+				AliasTarget: &route53.AliasTarget{
+					DNSName:              aws.String(recordSet.Values[0]),
+					HostedZoneId:         aws.String(recordSet.AliasHostedZoneID),
+					EvaluateTargetHealth: aws.Bool(false),
 				},
 			},
+		}
+	}
+
+	resourceRecords := make([]*route53.ResourceRecord, 0, len(recordSet.Values))
+	for _, value := range recordSet.Values {
+		resourceRecords = append(resourceRecords, &route53.ResourceRecord{Value: aws.String(value)})
+	}
+
+	return &route53.Change{
+		Action: aws.String(action),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name:            aws.String(recordSet.Name),
+			Type:            aws.String(recordSet.Type),
+			TTL:             aws.Int64(recordSet.TTL),
+			ResourceRecords: resourceRecords,
 		},
 	}
+}
 
-	cachedClusterIngressRecords, _ := dnscache.GetDNSCacheRecord(dnscache.ClusterIngressRecords, hostedZoneID)
-	if input.String() != string(cachedClusterIngressRecords) {
-		if err = dnscache.SetDNSCacheRecord(dnscache.ClusterIngressRecords, hostedZoneID, []byte(input.String())); err != nil {
-			return err
+// findResourceRecordSet returns the record set with the given name and type.
+// Route53 returns names with a trailing dot and escapes the wildcard label as \052.
+func findResourceRecordSet(recordSets []*route53.ResourceRecordSet, name, recordType string) *route53.ResourceRecordSet {
+	for _, recordSet := range recordSets {
+		if recordSet.Name == nil || recordSet.Type == nil {
+			continue
 		}
-
-		if _, err := s.Route53Client.ChangeResourceRecordSetsWithContext(ctx, input); err != nil {
-			return wrapRoute53Error(err)
+		if normalizeRecordName(*recordSet.Name) == name && *recordSet.Type == recordType {
+			return recordSet
 		}
 	}
-
 	return nil
 }
 
+func normalizeRecordName(name string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(name, "."), "\\052", "*")
+}
+
 func (s *Service) changeClusterNSDelegation(ctx context.Context, hostedZoneID, action string) error {
 	log := log.FromContext(ctx)
 
+	if hostedZoneID == plannedHostedZoneID {
+		// The name servers of a hosted zone are only assigned on its creation.
+		s.plan.Add(dns.PlannedChange{
+			Action:    action,
+			Zone:      s.scope.BaseDomain(),
+			RecordSet: dns.RecordSet{Name: s.scope.ClusterDomain(), Type: dns.RecordTypeNS, TTL: s.scope.RecordTTLs().Delegation},
+		})
+		return nil
+	}
+
 	var resourceRecords []*route53.ResourceRecord
 	cachedRecords, err := dnscache.GetDNSCacheRecord(dnscache.NameserverRecords, hostedZoneID)
 	if errors.Is(err, bigcache.ErrEntryNotFound) {
@@ -231,19 +330,21 @@ func (s *Service) changeClusterNSDelegation(ctx context.Context, hostedZoneID, a
 		return err
 	}
 
-	cachedBaseHostedZoneID, err := dnscache.GetDNSCacheRecord(dnscache.ZoneID, s.scope.ClusterDomain())
+	// Zone IDs are cached per domain, the base hosted zone is shared by all
+	// clusters of the base domain.
+	cachedBaseHostedZoneID, err := dnscache.GetDNSCacheRecord(dnscache.ZoneID, s.scope.BaseDomain())
 	if errors.Is(err, bigcache.ErrEntryNotFound) {
-		log.Info(fmt.Sprintf("no cached zone id found for domain %s", s.scope.ClusterDomain()))
+		log.Info(fmt.Sprintf("no cached zone id found for domain %s", s.scope.BaseDomain()))
 
 		baseHostedZoneID, err := s.describeBaseHostedZone(ctx)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 
-		if err = dnscache.SetDNSCacheRecord(dnscache.ZoneID, s.scope.ClusterDomain(), []byte(baseHostedZoneID)); err != nil {
+		if err = dnscache.SetDNSCacheRecord(dnscache.ZoneID, s.scope.BaseDomain(), []byte(baseHostedZoneID)); err != nil {
 			return err
 		}
-		cachedBaseHostedZoneID, err = dnscache.GetDNSCacheRecord(dnscache.ZoneID, s.scope.ClusterDomain())
+		cachedBaseHostedZoneID, err = dnscache.GetDNSCacheRecord(dnscache.ZoneID, s.scope.BaseDomain())
 		if err != nil {
 			return err
 		}
@@ -258,7 +359,7 @@ func (s *Service) changeClusterNSDelegation(ctx context.Context, hostedZoneID, a
 					ResourceRecordSet: &route53.ResourceRecordSet{
 						Name:            aws.String(s.scope.ClusterDomain()),
 						Type:            aws.String("NS"),
-						TTL:             aws.Int64(ttl),
+						TTL:             aws.Int64(s.scope.RecordTTLs().Delegation),
 						ResourceRecords: resourceRecords,
 					},
 				},
@@ -270,131 +371,198 @@ func (s *Service) changeClusterNSDelegation(ctx context.Context, hostedZoneID, a
 
 	// if cached input differ from computed input
 	if input.String() != string(cachedBaseHostedZoneIDRecords) {
+		// The cache is empty after a restart of the operator, so the
+		// delegation in the base hosted zone is compared before it is changed.
+		if action == actionUpsert {
+			current, err := s.describeClusterNSDelegation(ctx, string(cachedBaseHostedZoneID))
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			if current != nil {
+				delegation := toRecordSets([]*route53.ResourceRecordSet{current, input.ChangeBatch.Changes[0].ResourceRecordSet})
+				if delegation[0].Equal(delegation[1]) {
+					return dnscache.SetDNSCacheRecord(dnscache.ZoneRecords, s.scope.ClusterDomain(), []byte(input.String()))
+				}
+			}
+		}
+
+		if s.config.DryRun {
+			s.planChanges(s.scope.BaseDomain(), input.ChangeBatch.Changes)
+			return nil
+		}
+
 		log.Info(fmt.Sprintf("cached records for zone ID %s differs from computed records. Updating ResourceRecordSet", string(cachedBaseHostedZoneID)))
 
 		if err := dnscache.SetDNSCacheRecord(dnscache.ZoneRecords, s.scope.ClusterDomain(), []byte(input.String())); err != nil {
 			return err
 		}
 
-		_, err := s.Route53Client.ChangeResourceRecordSetsWithContext(ctx, input)
+		output, err := s.BaseRoute53Client.ChangeResourceRecordSetsWithContext(ctx, input)
 		if err != nil {
 			return wrapRoute53Error(err)
 		}
+		s.trackChange(output.ChangeInfo, true)
 	}
 
 	return nil
 }
 
-func (s *Service) changeClusterRecords(ctx context.Context, hostedZoneID string, action string) error {
+// cachedResourceRecordSets returns all record sets of the given hosted zone from
+// the cache and lists them if they are not cached yet.
+func (s *Service) cachedResourceRecordSets(ctx context.Context, hostedZoneID string) ([]*route53.ResourceRecordSet, error) {
 	log := log.FromContext(ctx)
-	input := &route53.ChangeResourceRecordSetsInput{
-		HostedZoneId: aws.String(hostedZoneID),
-		ChangeBatch: &route53.ChangeBatch{
-			Changes: []*route53.Change{},
-		},
+
+	if hostedZoneID == plannedHostedZoneID {
+		return nil, nil
 	}
 
-	cachedHostedZoneIDRecordSets, err := dnscache.GetDNSCacheRecord(dnscache.ZoneRecords, hostedZoneID)
+	var recordSets []*route53.ResourceRecordSet
+	cachedRecordSets, err := dnscache.GetDNSCacheRecord(dnscache.ZoneRecords, hostedZoneID)
+	if err == nil {
+		if err := json.Unmarshal(cachedRecordSets, &recordSets); err != nil {
+			return nil, err
+		}
+		return recordSets, nil
+	} else if !errors.Is(err, bigcache.ErrEntryNotFound) {
+		return nil, err
+	}
 
-	if errors.Is(err, bigcache.ErrEntryNotFound) {
+	log.Info(fmt.Sprintf("no cached resource record set found for zone id %s", hostedZoneID))
 
-		log.Info(fmt.Sprintf("no cached resource record set found for zone id %s", hostedZoneID))
+	recordSets, err = s.listResourceRecordSets(ctx, hostedZoneID)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
-		recordSets, err := s.listResourceRecordSets(ctx, hostedZoneID)
-		if err != nil {
-			return wrapRoute53Error(err)
-		}
+	jsonRecords, _ := json.Marshal(recordSets)
+	if err = dnscache.SetDNSCacheRecord(dnscache.ZoneRecords, hostedZoneID, jsonRecords); err != nil {
+		// Large zones exceed the size of a cache shard and are listed on every reconciliation.
+		log.Info(fmt.Sprintf("failed to cache resource record sets for zone id %s: %s", hostedZoneID, err))
+	}
 
-		jsonRecords, _ := json.Marshal(recordSets.ResourceRecordSets)
-		if err = dnscache.SetDNSCacheRecord(dnscache.ZoneRecords, hostedZoneID, jsonRecords); err != nil {
-			return err
-		}
-		cachedHostedZoneIDRecordSets, err = dnscache.GetDNSCacheRecord(dnscache.ZoneRecords, hostedZoneID)
-		if err != nil {
-			return err
-		}
+	return recordSets, nil
+}
+
+// listResourceRecordSets returns all record sets of the given hosted zone. Route53
+// returns at most 300 record sets per call, so all pages are requested.
+func (s *Service) listResourceRecordSets(ctx context.Context, hostedZoneID string) ([]*route53.ResourceRecordSet, error) {
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(hostedZoneID),
 	}
 
 	var recordSets []*route53.ResourceRecordSet
-	if err := json.Unmarshal(cachedHostedZoneIDRecordSets, &recordSets); err != nil {
-		return err
+	err := s.Route53Client.ListResourceRecordSetsPagesWithContext(ctx, input, func(output *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		recordSets = append(recordSets, output.ResourceRecordSets...)
+		return true
+	})
+	if err != nil {
+		return nil, wrapRoute53Error(err)
 	}
 
-	// check if we already have right entries for kubernetes API IP & bastion host IP
-	kubernetesAPIRequiresUpdate := true
-	bastionIPRequiresUpdate := true
+	return recordSets, nil
+}
 
-	for _, recordSet := range recordSets {
-		if *recordSet.Name == "api"+"."+s.scope.Name()+"."+s.scope.BaseDomain()+"." {
-			kubernetesAPIRequiresUpdate = requiresUpdate(recordSet, s.scope.APIEndpoint())
-		}
-		if *recordSet.Name == "bastion1"+"."+s.scope.Name()+"."+s.scope.BaseDomain()+"." {
-			bastionIPRequiresUpdate = requiresUpdate(recordSet, s.scope.BastionIP())
-		}
-	}
-
-	// decide if we need changes
-	if s.scope.APIEndpoint() == "" {
-		log.Info("API endpoint is not ready yet.")
-		return aws.ErrMissingEndpoint
-	} else if s.scope.APIEndpoint() != "" && kubernetesAPIRequiresUpdate {
-		input.ChangeBatch.Changes = append(input.ChangeBatch.Changes,
-			s.buildARecordChange(hostedZoneID, "api", s.scope.APIEndpoint(), actionUpsert),
-		)
-	} else if s.scope.BastionIP() != "" && bastionIPRequiresUpdate {
-		input.ChangeBatch.Changes = append(input.ChangeBatch.Changes,
-			s.buildARecordChange(hostedZoneID, "bastion1", s.scope.BastionIP(), actionUpsert),
-		)
-	} else if s.scope.BastionIP() == "" {
-		for _, recordSet := range recordSets {
-			if *recordSet.Name == "bastion1"+"."+s.scope.Name()+"."+s.scope.BaseDomain()+"." {
-				log.Info("orphaned bastion record found", "name", *recordSet.Name, "record", *recordSet.ResourceRecords[0].Value)
-				input.ChangeBatch.Changes = append(input.ChangeBatch.Changes,
-					s.buildARecordChange(hostedZoneID, "bastion1", *recordSet.ResourceRecords[0].Value, actionDelete),
-				)
-			}
-		}
+// changeResourceRecordSets applies the changes to the given hosted zone. The
+// changes are split into batches which respect the Route53 change batch limits.
+// In dry-run mode the changes are only added to the plan.
+func (s *Service) changeResourceRecordSets(ctx context.Context, hostedZoneID string, changes []*route53.Change) error {
+	if s.config.DryRun {
+		s.planChanges(s.scope.ClusterDomain(), changes)
+		return nil
 	}
 
-	if len(input.ChangeBatch.Changes) > 0 {
-		// invalidate the cache
-		if err = dnscache.DeleteDNSCacheRecord(dnscache.ZoneRecords, hostedZoneID); err != nil {
-			return err
+	for _, batch := range splitChangeBatches(changes) {
+		input := &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(hostedZoneID),
+			ChangeBatch: &route53.ChangeBatch{
+				Changes: batch,
+			},
 		}
 
-		if _, err := s.Route53Client.ChangeResourceRecordSetsWithContext(ctx, input); err != nil {
+		output, err := s.Route53Client.ChangeResourceRecordSetsWithContext(ctx, input)
+		if err != nil {
 			return wrapRoute53Error(err)
 		}
+		s.trackChange(output.ChangeInfo, false)
 	}
 
 	return nil
 }
 
-func requiresUpdate(set *route53.ResourceRecordSet, endpoint string) bool {
-	if set.ResourceRecords == nil {
-		return true
+// splitChangeBatches splits the changes into batches with at most 1000 records
+// and 32000 characters in the record values. Changes of the same record name
+// which follow each other are kept in one batch, so a record set which is
+// replaced with another type never disappears between two batches.
+func splitChangeBatches(changes []*route53.Change) [][]*route53.Change {
+	var batches [][]*route53.Change
+	var batch []*route53.Change
+	records, characters := 0, 0
+
+	for _, group := range groupChangesByName(changes) {
+		groupRecords, groupCharacters := 0, 0
+		for _, change := range group {
+			changeRecords, changeCharacters := changeSize(change)
+			groupRecords += changeRecords
+			groupCharacters += changeCharacters
+		}
+
+		if len(batch) > 0 && (records+groupRecords > maxChangeBatchRecords || characters+groupCharacters > maxChangeBatchCharacters) {
+			batches = append(batches, batch)
+			batch, records, characters = nil, 0, 0
+		}
+
+		batch = append(batch, group...)
+		records += groupRecords
+		characters += groupCharacters
 	}
 
-	if len(set.ResourceRecords) == 0 {
-		return true
+	if len(batch) > 0 {
+		batches = append(batches, batch)
 	}
 
-	if set.ResourceRecords[0].Value == nil {
-		return true
+	return batches
+}
+
+// groupChangesByName groups the changes of the same record name which follow
+// each other.
+func groupChangesByName(changes []*route53.Change) [][]*route53.Change {
+	var groups [][]*route53.Change
+	previous := ""
+
+	for _, change := range changes {
+		name := ""
+		if change.ResourceRecordSet != nil {
+			name = strings.ToLower(normalizeRecordName(aws.StringValue(change.ResourceRecordSet.Name)))
+		}
+
+		if len(groups) > 0 && name != "" && name == previous {
+			groups[len(groups)-1] = append(groups[len(groups)-1], change)
+			continue
+		}
+		groups = append(groups, []*route53.Change{change})
+		previous = name
 	}
 
-	return *set.ResourceRecords[0].Value != endpoint
+	return groups
 }
 
-func (s *Service) listResourceRecordSets(ctx context.Context, hostedZoneID string) (*route53.ListResourceRecordSetsOutput, error) {
-	listParams := &route53.ListResourceRecordSetsInput{
-		HostedZoneId: aws.String(hostedZoneID),
+// changeSize returns the number of records and characters a change counts
+// against the change batch limits.
+func changeSize(change *route53.Change) (int, int) {
+	weight := 1
+	if aws.StringValue(change.Action) == actionUpsert {
+		weight = 2
 	}
-	respList, err := s.Route53Client.ListResourceRecordSets(listParams)
-	if err != nil {
-		return &route53.ListResourceRecordSetsOutput{}, wrapRoute53Error(err)
+
+	records, characters := 0, 0
+	if change.ResourceRecordSet != nil {
+		for _, record := range change.ResourceRecordSet.ResourceRecords {
+			records += weight
+			characters += weight * len(aws.StringValue(record.Value))
+		}
 	}
-	return respList, nil
+
+	return records, characters
 }
 
 func (s *Service) createClusterHostedZone(ctx context.Context) (string, error) {
@@ -413,67 +581,67 @@ func (s *Service) createClusterHostedZone(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", wrapRoute53Error(err)
 	}
+	s.trackChange(output.ChangeInfo, false)
 
 	return *output.HostedZone.Id, nil
 }
 
-func (s *Service) deleteClusterRecords(ctx context.Context, hostedZoneID string) error {
-	input := &route53.ListResourceRecordSetsInput{
-		HostedZoneId: aws.String(hostedZoneID),
-	}
-
-	output, err := s.Route53Client.ListResourceRecordSetsWithContext(ctx, input)
+// deleteClusterRecords deletes all records of the hosted zone which are not
+// owned by someone else and returns the number of kept record sets.
+func (s *Service) deleteClusterRecords(ctx context.Context, hostedZoneID string) (int, error) {
+	recordSets, err := s.listResourceRecordSets(ctx, hostedZoneID)
 	if err != nil {
-		return wrapRoute53Error(err)
+		return 0, microerror.Mask(err)
 	}
 
-	recordsToDelete := &route53.ChangeResourceRecordSetsInput{
-		HostedZoneId: aws.String(hostedZoneID),
-		ChangeBatch: &route53.ChangeBatch{
-			Changes: []*route53.Change{},
-		},
-	}
+	registry := dns.NewRegistry(s.scope, toRecordSets(recordSets))
 
-	for _, recordSet := range output.ResourceRecordSets {
-		if strings.TrimSuffix(*recordSet.Name, ".") == s.scope.ClusterDomain() {
+	var changes []*route53.Change
+	var foreign int
+	for _, recordSet := range recordSets {
+		if isApexRecordSet(recordSet, s.scope.ClusterDomain()) {
 			// We cannot delete those entries, they get automatically cleaned up when deleting the hosted zone
 			continue
 		}
+		if registry.IsForeign(normalizeRecordName(*recordSet.Name), *recordSet.Type) {
+			foreign++
+			continue
+		}
 
-		recordsToDelete.ChangeBatch.Changes = append(recordsToDelete.ChangeBatch.Changes, &route53.Change{
+		changes = append(changes, &route53.Change{
 			Action:            aws.String(actionDelete),
 			ResourceRecordSet: recordSet,
 		})
 	}
 
-	if len(recordsToDelete.ChangeBatch.Changes) == 0 {
+	if len(changes) == 0 {
 		// Nothing to delete
-		return nil
+		return foreign, nil
 	}
 
-	_, err = s.Route53Client.ChangeResourceRecordSetsWithContext(ctx, recordsToDelete)
+	err = s.changeResourceRecordSets(ctx, hostedZoneID, changes)
 	if err != nil {
-		return wrapRoute53Error(err)
+		return 0, microerror.Mask(err)
 	}
 
 	// delete cached records for given Zone
-	if err = dnscache.DeleteDNSCacheRecord(dnscache.ZoneRecords, hostedZoneID); err != nil {
-		return err
+	if err = dnscache.DeleteDNSCacheRecord(dnscache.ZoneRecords, hostedZoneID); err != nil && !errors.Is(err, bigcache.ErrEntryNotFound) {
+		return 0, err
 	}
 
 	// delete cached zoneID for cluster
-	if err = dnscache.DeleteDNSCacheRecord(dnscache.ZoneID, s.scope.Name()); err != nil {
-		return err
+	if err = dnscache.DeleteDNSCacheRecord(dnscache.ZoneID, s.scope.ClusterDomain()); err != nil && !errors.Is(err, bigcache.ErrEntryNotFound) {
+		return 0, err
 	}
 
-	return nil
+	return foreign, nil
 }
 
 func (s *Service) describeBaseHostedZone(ctx context.Context) (string, error) {
 	input := &route53.ListHostedZonesByNameInput{
 		DNSName: aws.String(s.scope.BaseDomain()),
 	}
-	out, err := s.Route53Client.ListHostedZonesByNameWithContext(ctx, input)
+	out, err := s.BaseRoute53Client.ListHostedZonesByNameWithContext(ctx, input)
 	if err != nil {
 		return "", wrapRoute53Error(err)
 	}
@@ -509,145 +677,51 @@ func (s *Service) describeClusterHostedZone(ctx context.Context) (string, error)
 	return *out.HostedZones[0].Id, nil
 }
 
-func (s *Service) getIngressService(ctx context.Context) (*ingressService, error) {
-	k8sClient, err := s.scope.ClusterK8sClient(ctx)
-	if err != nil {
-		return nil, microerror.Mask(err)
+func (s *Service) listClusterNSRecords(ctx context.Context, hostedZoneID string) ([]*route53.ResourceRecord, error) {
+	// First entry is always NS record
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(hostedZoneID),
+		MaxItems:     aws.String("1"),
 	}
 
-	var icServices corev1.ServiceList
-
-	err = k8sClient.List(ctx, &icServices,
-		client.InNamespace(ingressAppNamespace),
-		&client.ListOptions{Raw: &metav1.ListOptions{LabelSelector: ingressServiceSelector}},
-	)
-
+	output, err := s.Route53Client.ListResourceRecordSetsWithContext(ctx, input)
 	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	var result *ingressService
-
-	for _, icService := range icServices.Items {
-		if icService.Spec.Type == corev1.ServiceTypeLoadBalancer {
-			if result != nil {
-				return nil, microerror.Mask(tooManyICServicesError)
-			}
-
-			if len(icService.Status.LoadBalancer.Ingress) < 1 || icService.Status.LoadBalancer.Ingress[0].IP == "" {
-				return nil, microerror.Mask(ingressNotReadyError)
-			}
-
-			hostname := fmt.Sprintf("ingress.%s", s.scope.ClusterDomain())
-			if annotated, ok := icService.Annotations[externalDNSHostnameAnnotation]; ok && annotated != "" {
-				log.FromContext(ctx).Info("Using custom ingress hostname from annotation", "annotation", externalDNSHostnameAnnotation, "hostname", annotated)
-				hostname = annotated
-			}
-
-			result = &ingressService{
-				hostname: hostname,
-				ip:       icService.Status.LoadBalancer.Ingress[0].IP,
-			}
-		}
+		return nil, wrapRoute53Error(err)
 	}
-
-	return result, nil
+	return output.ResourceRecordSets[0].ResourceRecords, nil
 }
 
-func (s *Service) getGatewayServices(ctx context.Context) ([]gatewayService, error) {
-	k8sClient, err := s.scope.ClusterK8sClient(ctx)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	var services corev1.ServiceList
-	if err = k8sClient.List(ctx, &services, client.InNamespace(gatewayNamespace)); err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	var result []gatewayService
-	for _, svc := range services.Items {
-		if svc.Annotations[externalDNSManagedAnnotation] != externalDNSManagedValue {
-			continue
-		}
-		hostname, ok := svc.Annotations[externalDNSHostnameAnnotation]
-		if !ok || hostname == "" {
-			continue
-		}
-		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
-			continue
-		}
-		if len(svc.Status.LoadBalancer.Ingress) < 1 || svc.Status.LoadBalancer.Ingress[0].IP == "" {
-			continue
-		}
-		result = append(result, gatewayService{
-			hostname: hostname,
-			ip:       svc.Status.LoadBalancer.Ingress[0].IP,
-		})
+// describeClusterNSDelegation returns the NS record set of the cluster domain
+// in the base hosted zone or nil if the cluster hosted zone is not delegated.
+func (s *Service) describeClusterNSDelegation(ctx context.Context, baseHostedZoneID string) (*route53.ResourceRecordSet, error) {
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(baseHostedZoneID),
+		StartRecordName: aws.String(s.scope.ClusterDomain()),
+		StartRecordType: aws.String(route53.RRTypeNs),
+		MaxItems:        aws.String("1"),
 	}
 
-	return result, nil
-}
-
-func (s *Service) changeClusterGatewayRecords(ctx context.Context, hostedZoneID, action string) error {
-	gateways, err := s.getGatewayServices(ctx)
+	output, err := s.BaseRoute53Client.ListResourceRecordSetsWithContext(ctx, input)
 	if err != nil {
-		return microerror.Mask(err)
-	}
-	if len(gateways) == 0 {
-		return nil
-	}
-
-	var changes []*route53.Change
-	for _, gw := range gateways {
-		changes = append(changes, &route53.Change{
-			Action: aws.String(action),
-			ResourceRecordSet: &route53.ResourceRecordSet{
-				Name: aws.String(gw.hostname),
-				Type: aws.String("A"),
-				TTL:  aws.Int64(ttl),
-				ResourceRecords: []*route53.ResourceRecord{
-					{Value: aws.String(gw.ip)},
-				},
-			},
-		})
+		return nil, wrapRoute53Error(err)
 	}
-
-	input := &route53.ChangeResourceRecordSetsInput{
-		HostedZoneId: aws.String(hostedZoneID),
-		ChangeBatch: &route53.ChangeBatch{
-			Changes: changes,
-		},
+	if len(output.ResourceRecordSets) == 0 {
+		return nil, nil
 	}
 
-	cachedGatewayRecords, _ := dnscache.GetDNSCacheRecord(dnscache.ClusterGatewayRecords, hostedZoneID)
-	if input.String() != string(cachedGatewayRecords) {
-		if err = dnscache.SetDNSCacheRecord(dnscache.ClusterGatewayRecords, hostedZoneID, []byte(input.String())); err != nil {
-			return err
-		}
-		if _, err := s.Route53Client.ChangeResourceRecordSetsWithContext(ctx, input); err != nil {
-			return wrapRoute53Error(err)
-		}
+	recordSet := output.ResourceRecordSets[0]
+	if normalizeRecordName(aws.StringValue(recordSet.Name)) != s.scope.ClusterDomain() || aws.StringValue(recordSet.Type) != route53.RRTypeNs {
+		return nil, nil
 	}
-
-	return nil
+	return recordSet, nil
 }
 
-func (s *Service) listClusterNSRecords(ctx context.Context, hostedZoneID string) ([]*route53.ResourceRecord, error) {
-	// First entry is always NS record
-	input := &route53.ListResourceRecordSetsInput{
-		HostedZoneId: aws.String(hostedZoneID),
-		MaxItems:     aws.String("1"),
-	}
-
-	output, err := s.Route53Client.ListResourceRecordSetsWithContext(ctx, input)
-	if err != nil {
-		return nil, wrapRoute53Error(err)
+func (s *Service) deleteClusterHostedZone(ctx context.Context, hostedZoneID string) error {
+	if s.config.DryRun {
+		s.plan.Add(dns.PlannedChange{Action: dns.ActionDeleteZone, Zone: s.scope.ClusterDomain()})
+		return nil
 	}
-	return output.ResourceRecordSets[0].ResourceRecords, nil
-}
 
-func (s *Service) deleteClusterHostedZone(ctx context.Context, hostedZoneID string) error {
 	input := &route53.DeleteHostedZoneInput{
 		Id: aws.String(hostedZoneID),
 	}
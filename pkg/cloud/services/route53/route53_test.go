@@ -0,0 +1,1612 @@
+package route53
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud"
+	dnscache "github.com/giantswarm/dns-operator-route53/pkg/cloud/cache"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope/scopetest"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/route53/route53test"
+	"github.com/giantswarm/dns-operator-route53/pkg/remote"
+)
+
+const (
+	testBaseDomain    = "example.com"
+	testClusterDomain = "test.example.com"
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	dnscache.DNSOperatorCache, err = dnscache.NewDNSOperatorCache()
+	if err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+func newIngressService(endpoints ...string) *corev1.Service {
+	return newNamedIngressService("ingress-nginx-controller", endpoints...)
+}
+
+// newNamedIngressService returns the LoadBalancer Service of an ingress controller with the given name.
+func newNamedIngressService(name string, endpoints ...string) *corev1.Service {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "kube-system",
+			Labels:    map[string]string{"app.kubernetes.io/name": "ingress-nginx"},
+		},
+		Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	}
+	service.Status.LoadBalancer.Ingress = loadBalancerIngress(endpoints...)
+	return service
+}
+
+func newGatewayService(name, hostname string, endpoints ...string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "envoy-gateway-system",
+			Annotations: map[string]string{
+				"giantswarm.io/external-dns":                "managed",
+				"external-dns.alpha.kubernetes.io/hostname": hostname,
+			},
+		},
+		Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: loadBalancerIngress(endpoints...),
+			},
+		},
+	}
+}
+
+// newGateway returns a Gateway API Gateway with a listener for every hostname.
+func newGateway(name string, addresses []string, hostnames ...string) *gatewayv1.Gateway {
+	gw := &gatewayv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+	}
+	for _, hostname := range hostnames {
+		gw.Spec.Listeners = append(gw.Spec.Listeners, gatewayv1.Listener{
+			Name:     gatewayv1.SectionName(hostname),
+			Hostname: ptr.To(gatewayv1.Hostname(hostname)),
+			Port:     443,
+			Protocol: gatewayv1.HTTPSProtocolType,
+		})
+	}
+	for _, address := range addresses {
+		addressType := gatewayv1.IPAddressType
+		if net.ParseIP(address) == nil {
+			addressType = gatewayv1.HostnameAddressType
+		}
+		gw.Status.Addresses = append(gw.Status.Addresses, gatewayv1.GatewayStatusAddress{Type: &addressType, Value: address})
+	}
+	return gw
+}
+
+// newHTTPRoute returns an HTTPRoute of the given Gateway, which is accepted
+// by the Gateway if accepted is true.
+func newHTTPRoute(name, gateway string, accepted bool, hostnames ...string) *gatewayv1.HTTPRoute {
+	parentRef := gatewayv1.ParentReference{Name: gatewayv1.ObjectName(gateway)}
+	status := metav1.ConditionFalse
+	if accepted {
+		status = metav1.ConditionTrue
+	}
+
+	route := &gatewayv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+	}
+	route.Spec.ParentRefs = []gatewayv1.ParentReference{parentRef}
+	for _, hostname := range hostnames {
+		route.Spec.Hostnames = append(route.Spec.Hostnames, gatewayv1.Hostname(hostname))
+	}
+	route.Status.Parents = []gatewayv1.RouteParentStatus{{
+		ParentRef:      parentRef,
+		ControllerName: "example.com/gateway-controller",
+		Conditions: []metav1.Condition{{
+			Type:   string(gatewayv1.RouteConditionAccepted),
+			Status: status,
+			Reason: string(gatewayv1.RouteReasonAccepted),
+		}},
+	}}
+	return route
+}
+
+// loadBalancerIngress returns the load balancer status for the given IP
+// addresses and hostnames.
+func loadBalancerIngress(endpoints ...string) []corev1.LoadBalancerIngress {
+	var ingress []corev1.LoadBalancerIngress
+	for _, endpoint := range endpoints {
+		switch {
+		case endpoint == "":
+		case net.ParseIP(endpoint) != nil:
+			ingress = append(ingress, corev1.LoadBalancerIngress{IP: endpoint})
+		default:
+			ingress = append(ingress, corev1.LoadBalancerIngress{Hostname: endpoint})
+		}
+	}
+	return ingress
+}
+
+func newTestService(fakeRoute53 *route53test.FakeRoute53, apiEndpoint, bastionIP string, objects ...client.Object) *Service {
+	return &Service{
+		scope: &scopetest.ClusterScope{
+			APIEndpointValue:       apiEndpoint,
+			BaseDomainValue:        testBaseDomain,
+			BastionIPValue:         bastionIP,
+			ClusterValue:           &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "org-test"}},
+			K8sClient:              fake.NewClientBuilder().WithScheme(remote.Scheme).WithObjects(objects...).Build(),
+			ManagementClusterValue: "mc",
+			ServiceDiscoveryValue:  dns.DefaultServiceDiscovery(),
+			RecordTTLsValue:        dns.DefaultRecordTTLs(),
+		},
+		Route53Client:     fakeRoute53,
+		BaseRoute53Client: fakeRoute53,
+	}
+}
+
+func aRecord(name, value string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name:            aws.String(name),
+		Type:            aws.String("A"),
+		TTL:             aws.Int64(300),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(value)}},
+	}
+}
+
+func txtRecord(name, value string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name:            aws.String(name),
+		Type:            aws.String("TXT"),
+		TTL:             aws.Int64(300),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(value)}},
+	}
+}
+
+// recordValue returns the first value of the given record set or an empty
+// string if the record set does not exist.
+func recordValue(fakeRoute53 *route53test.FakeRoute53, zoneName, name, recordType string) string {
+	recordSet := fakeRoute53.RecordSet(fakeRoute53.HostedZoneID(zoneName), name, recordType)
+	if recordSet == nil || len(recordSet.ResourceRecords) == 0 {
+		return ""
+	}
+	return *recordSet.ResourceRecords[0].Value
+}
+
+func Test_Service_ReconcileRoute53(t *testing.T) {
+	const owned = `"heritage=dns-operator-route53,dns-operator-route53/owner=mc/,dns-operator-route53/resource=cluster/org-test/test"`
+
+	testCases := []struct {
+		name                   string
+		apiEndpoint            string
+		bastionIP              string
+		objects                []client.Object
+		wildcardIngressService string
+		serviceDiscovery       *cloud.ServiceDiscovery
+		noBaseZone             bool
+		setup                  func(fakeRoute53 *route53test.FakeRoute53)
+
+		expectedError   func(error) bool
+		expectedRecords map[string]string
+		absentRecords   []string
+	}{
+		{
+			name:        "case 0: create cluster zone, delegation and records",
+			apiEndpoint: "10.0.0.10",
+			bastionIP:   "10.0.0.30",
+			objects:     []client.Object{newIngressService("10.0.0.20")},
+			expectedRecords: map[string]string{
+				"api.test.example.com A":      "10.0.0.10",
+				"bastion1.test.example.com A": "10.0.0.30",
+				"ingress.test.example.com A":  "10.0.0.20",
+				"*.test.example.com CNAME":    "ingress.test.example.com",
+			},
+		},
+		{
+			name:        "case 1: update outdated API record in existing zone",
+			apiEndpoint: "10.0.0.11",
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+				fakeRoute53.AddRecordSet(zoneID, aRecord("api.test.example.com", "10.0.0.10"))
+			},
+			expectedRecords: map[string]string{
+				"api.test.example.com A": "10.0.0.11",
+			},
+			absentRecords: []string{"bastion1.test.example.com A", "ingress.test.example.com A"},
+		},
+		{
+			name:        "case 2: delete orphaned bastion record",
+			apiEndpoint: "10.0.0.10",
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+				fakeRoute53.AddRecordSet(zoneID, aRecord("bastion1.test.example.com", "10.0.0.30"))
+			},
+			expectedRecords: map[string]string{
+				"api.test.example.com A": "10.0.0.10",
+			},
+			absentRecords: []string{"bastion1.test.example.com A"},
+		},
+		{
+			name:        "case 3: create gateway records",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newGatewayService("gateway-a", "a.test.example.com", "10.0.0.40"),
+				newGatewayService("gateway-b", "b.test.example.com", "10.0.0.41"),
+			},
+			expectedRecords: map[string]string{
+				"a.test.example.com A": "10.0.0.40",
+				"b.test.example.com A": "10.0.0.41",
+			},
+		},
+		{
+			name:          "case 4: API endpoint is missing",
+			expectedError: dns.IsAPIEndpointMissing,
+		},
+		{
+			name:          "case 5: ingress is not ready",
+			apiEndpoint:   "10.0.0.10",
+			objects:       []client.Object{newIngressService("")},
+			expectedError: dns.IsIngressNotReady,
+		},
+		{
+			name:          "case 6: base zone does not exist",
+			apiEndpoint:   "10.0.0.10",
+			noBaseZone:    true,
+			expectedError: IsHostedZoneNotFound,
+		},
+		{
+			name:        "case 7: throttled record changes",
+			apiEndpoint: "10.0.0.10",
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				fakeRoute53.Throttle("ChangeResourceRecordSets", 1)
+			},
+			expectedError: IsThrottlingRateExceededError,
+		},
+		{
+			name:        "case 8: access denied on zone creation",
+			apiEndpoint: "10.0.0.10",
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				fakeRoute53.InjectError("CreateHostedZone", awserr.New("AccessDenied", "not authorized", nil), 1)
+			},
+			expectedError: func(err error) bool {
+				return IsAccessDenied(err) && dns.IsZoneNotReady(err)
+			},
+		},
+		{
+			name:        "case 9: delegation fails",
+			apiEndpoint: "10.0.0.10",
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				fakeRoute53.Throttle("ChangeResourceRecordSets", 1)
+			},
+			expectedError: func(err error) bool {
+				return IsThrottlingRateExceededError(err) && dns.IsDelegationFailed(err)
+			},
+		},
+		{
+			name:        "case 10: create A and AAAA records for dual-stack load balancers",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newIngressService("10.0.0.20", "fd00::20"),
+				newGatewayService("gateway-a", "a.test.example.com", "10.0.0.40", "fd00::40"),
+			},
+			expectedRecords: map[string]string{
+				"ingress.test.example.com A":    "10.0.0.20",
+				"ingress.test.example.com AAAA": "fd00::20",
+				"a.test.example.com A":          "10.0.0.40",
+				"a.test.example.com AAAA":       "fd00::40",
+			},
+		},
+		{
+			name:        "case 11: create AAAA records for IPv6 only clusters",
+			apiEndpoint: "fd00::10",
+			bastionIP:   "fd00::30",
+			objects:     []client.Object{newIngressService("fd00::20")},
+			expectedRecords: map[string]string{
+				"api.test.example.com AAAA":      "fd00::10",
+				"bastion1.test.example.com AAAA": "fd00::30",
+				"ingress.test.example.com AAAA":  "fd00::20",
+			},
+			absentRecords: []string{"api.test.example.com A", "bastion1.test.example.com A", "ingress.test.example.com A"},
+		},
+		{
+			name:        "case 12: delete AAAA record when the load balancer lost its IPv6 address",
+			apiEndpoint: "10.0.0.10",
+			objects:     []client.Object{newIngressService("10.0.0.20")},
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+				fakeRoute53.AddRecordSet(zoneID, &route53.ResourceRecordSet{
+					Name:            aws.String("ingress.test.example.com"),
+					Type:            aws.String("AAAA"),
+					TTL:             aws.Int64(300),
+					ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("fd00::20")}},
+				})
+			},
+			expectedRecords: map[string]string{
+				"ingress.test.example.com A": "10.0.0.20",
+			},
+			absentRecords: []string{"ingress.test.example.com AAAA"},
+		},
+		{
+			name:        "case 13: create CNAME records for hostname endpoints",
+			apiEndpoint: "lb.example.org",
+			objects: []client.Object{
+				newIngressService("ingress.example.org"),
+				newGatewayService("gateway-a", "a.test.example.com", "gateway.example.org"),
+			},
+			expectedRecords: map[string]string{
+				"api.test.example.com CNAME":     "lb.example.org",
+				"ingress.test.example.com CNAME": "ingress.example.org",
+				"a.test.example.com CNAME":       "gateway.example.org",
+			},
+			absentRecords: []string{"api.test.example.com A", "ingress.test.example.com A"},
+		},
+		{
+			name:        "case 14: replace A record when the endpoint changed to a hostname",
+			apiEndpoint: "lb.example.org",
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+				fakeRoute53.AddRecordSet(zoneID, aRecord("api.test.example.com", "10.0.0.10"))
+			},
+			expectedRecords: map[string]string{
+				"api.test.example.com CNAME": "lb.example.org",
+			},
+			absentRecords: []string{"api.test.example.com A"},
+		},
+		{
+			name:          "case 15: skip endpoints which refer to the record itself",
+			apiEndpoint:   "api.test.example.com",
+			absentRecords: []string{"api.test.example.com A", "api.test.example.com CNAME"},
+		},
+		{
+			name:        "case 16: delete records of removed gateway Services",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newGatewayService("gateway-a", "a.test.example.com", "10.0.0.40"),
+			},
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+				fakeRoute53.AddRecordSet(zoneID, aRecord("b.test.example.com", "10.0.0.41"))
+				fakeRoute53.AddRecordSet(zoneID, txtRecord("a-b.test.example.com", owned))
+				fakeRoute53.AddRecordSet(zoneID, aRecord("vault.test.example.com", "10.0.0.50"))
+				fakeRoute53.AddRecordSet(zoneID, txtRecord("a-vault.test.example.com",
+					`"heritage=dns-operator-route53,dns-operator-route53/owner=mc/,dns-operator-route53/resource=dnsrecord/org-test/vault"`))
+			},
+			expectedRecords: map[string]string{
+				"a.test.example.com A":     "10.0.0.40",
+				"vault.test.example.com A": "10.0.0.50",
+			},
+			absentRecords: []string{"b.test.example.com A", "a-b.test.example.com TXT"},
+		},
+		{
+			name:        "case 17: keep records of gateway Services without address",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newGatewayService("gateway-a", "a.test.example.com"),
+			},
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+				fakeRoute53.AddRecordSet(zoneID, aRecord("a.test.example.com", "10.0.0.40"))
+				fakeRoute53.AddRecordSet(zoneID, txtRecord("a-a.test.example.com", owned))
+			},
+			expectedRecords: map[string]string{
+				"a.test.example.com A":     "10.0.0.40",
+				"a-a.test.example.com TXT": owned,
+			},
+		},
+		{
+			name:        "case 18: create records for the listeners and accepted HTTPRoutes of Gateways",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newGateway("gateway", []string{"10.0.0.60", "fd00::60"}, "gateway.test.example.com", "*.apps.test.example.com", "gateway.example.org"),
+				newHTTPRoute("app", "gateway", true, "app.apps.test.example.com", "app.example.org", "*.test.example.com", "unserved.test.example.com"),
+				newHTTPRoute("rejected", "gateway", false, "rejected.apps.test.example.com"),
+				newHTTPRoute("other", "other-gateway", true, "other.apps.test.example.com"),
+			},
+			expectedRecords: map[string]string{
+				"gateway.test.example.com A":         "10.0.0.60",
+				"gateway.test.example.com AAAA":      "fd00::60",
+				"*.apps.test.example.com A":          "10.0.0.60",
+				"app.apps.test.example.com A":        "10.0.0.60",
+				"api.test.example.com A":             "10.0.0.10",
+				"a-app.apps.test.example.com TXT":    owned,
+				"aaaa-app.apps.test.example.com TXT": owned,
+			},
+			absentRecords: []string{
+				"*.test.example.com A",
+				"unserved.test.example.com A",
+				"rejected.apps.test.example.com A",
+				"other.apps.test.example.com A",
+			},
+		},
+		{
+			name:        "case 19: create CNAME records for Gateways with a hostname address",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newGateway("gateway", []string{"gateway.example.org"}, "gateway.test.example.com"),
+				newGateway("second", []string{"10.0.0.61"}, "second.test.example.com"),
+			},
+			expectedRecords: map[string]string{
+				"gateway.test.example.com CNAME": "gateway.example.org",
+				"second.test.example.com A":      "10.0.0.61",
+			},
+		},
+		{
+			name:        "case 20: create records for every ingress controller with the first one as wildcard target",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newNamedIngressService("ingress-nginx-internal", "10.0.0.21"),
+				newNamedIngressService("ingress-nginx-controller", "10.0.0.20"),
+			},
+			expectedRecords: map[string]string{
+				"ingress.test.example.com A":                "10.0.0.20",
+				"ingress-nginx-internal.test.example.com A": "10.0.0.21",
+				"*.test.example.com CNAME":                  "ingress.test.example.com",
+			},
+			absentRecords: []string{"ingress-nginx-controller.test.example.com A"},
+		},
+		{
+			name:        "case 21: use the selected wildcard ingress service and annotated hostnames",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newIngressService("10.0.0.20"),
+				func() client.Object {
+					service := newNamedIngressService("ingress-nginx-internal", "10.0.0.21")
+					service.Annotations = map[string]string{"external-dns.alpha.kubernetes.io/hostname": "internal.test.example.com"}
+					return service
+				}(),
+			},
+			wildcardIngressService: "ingress-nginx-internal",
+			expectedRecords: map[string]string{
+				"internal.test.example.com A":                 "10.0.0.21",
+				"ingress-nginx-controller.test.example.com A": "10.0.0.20",
+				"*.test.example.com CNAME":                    "internal.test.example.com",
+			},
+			absentRecords: []string{"ingress.test.example.com A"},
+		},
+		{
+			name:        "case 22: fall back to the first ingress service if the wildcard ingress service does not exist",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newIngressService("10.0.0.20"),
+			},
+			wildcardIngressService: "missing",
+			expectedRecords: map[string]string{
+				"ingress.test.example.com A": "10.0.0.20",
+				"*.test.example.com CNAME":   "ingress.test.example.com",
+			},
+		},
+		{
+			name:        "case 23: keep the wildcard record while its ingress service has no address",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newIngressService(),
+				newNamedIngressService("ingress-nginx-internal", "10.0.0.21"),
+			},
+			setup: func(fakeRoute53 *route53test.FakeRoute53) {
+				zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+				fakeRoute53.AddRecordSet(zoneID, aRecord("ingress.test.example.com", "10.0.0.20"))
+				fakeRoute53.AddRecordSet(zoneID, &route53.ResourceRecordSet{
+					Name:            aws.String("*.test.example.com"),
+					Type:            aws.String("CNAME"),
+					TTL:             aws.Int64(300),
+					ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("ingress.test.example.com")}},
+				})
+			},
+			expectedError: dns.IsIngressNotReady,
+			expectedRecords: map[string]string{
+				"ingress.test.example.com A":                "10.0.0.20",
+				"*.test.example.com CNAME":                  "ingress.test.example.com",
+				"ingress-nginx-internal.test.example.com A": "10.0.0.21",
+			},
+		},
+		{
+			name:        "case 24: discover ingress and gateway Services with custom namespaces and selectors",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				func() client.Object {
+					service := newNamedIngressService("traefik", "10.0.0.22")
+					service.Namespace = "traefik"
+					service.Labels = map[string]string{"app.kubernetes.io/name": "traefik"}
+					return service
+				}(),
+				newIngressService("10.0.0.20"),
+				func() client.Object {
+					service := newGatewayService("istio-gateway", "istio.test.example.com", "10.0.0.42")
+					service.Namespace = "istio-ingress"
+					service.Labels = map[string]string{"istio": "ingressgateway"}
+					return service
+				}(),
+				func() client.Object {
+					service := newGatewayService("istio-internal", "internal.test.example.com", "10.0.0.43")
+					service.Namespace = "istio-ingress"
+					return service
+				}(),
+				newGatewayService("gateway-a", "a.test.example.com", "10.0.0.40"),
+			},
+			serviceDiscovery: &cloud.ServiceDiscovery{
+				IngressNamespaces: []string{"traefik"},
+				IngressSelector:   "app.kubernetes.io/name=traefik",
+				GatewayNamespaces: []string{"istio-ingress"},
+				GatewaySelector:   "istio=ingressgateway",
+			},
+			expectedRecords: map[string]string{
+				"ingress.test.example.com A": "10.0.0.22",
+				"istio.test.example.com A":   "10.0.0.42",
+				"*.test.example.com CNAME":   "ingress.test.example.com",
+			},
+			absentRecords: []string{"internal.test.example.com A", "a.test.example.com A"},
+		},
+		{
+			name:        "case 25: intersect HTTPRoute hostnames with the hostnames of the listeners they are attached to",
+			apiEndpoint: "10.0.0.10",
+			objects: []client.Object{
+				newGateway("gateway", []string{"10.0.0.60"}, "gateway.test.example.com", "*.apps.test.example.com"),
+				func() client.Object {
+					gw := newGateway("catch-all", []string{"10.0.0.61"})
+					gw.Spec.Listeners = []gatewayv1.Listener{{Name: "http", Port: 80, Protocol: gatewayv1.HTTPProtocolType}}
+					return gw
+				}(),
+				func() client.Object {
+					route := newHTTPRoute("section", "gateway", true, "*.test.example.com", "section.apps.test.example.com")
+					route.Spec.ParentRefs[0].SectionName = ptr.To(gatewayv1.SectionName("gateway.test.example.com"))
+					route.Status.Parents[0].ParentRef = route.Spec.ParentRefs[0]
+					return route
+				}(),
+				func() client.Object {
+					route := newHTTPRoute("port", "gateway", true, "port.apps.test.example.com")
+					route.Spec.ParentRefs[0].Port = ptr.To(gatewayv1.PortNumber(80))
+					route.Status.Parents[0].ParentRef = route.Spec.ParentRefs[0]
+					return route
+				}(),
+				newHTTPRoute("any", "catch-all", true, "any.test.example.com", "api.test.example.com"),
+			},
+			expectedRecords: map[string]string{
+				"gateway.test.example.com A": "10.0.0.60",
+				"*.apps.test.example.com A":  "10.0.0.60",
+				"any.test.example.com A":     "10.0.0.61",
+				"api.test.example.com A":     "10.0.0.10",
+			},
+			absentRecords: []string{
+				"*.test.example.com A",
+				"section.apps.test.example.com A",
+				"port.apps.test.example.com A",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			if !tc.noBaseZone {
+				fakeRoute53.AddHostedZone(testBaseDomain)
+			}
+			if tc.setup != nil {
+				tc.setup(fakeRoute53)
+			}
+
+			service := newTestService(fakeRoute53, tc.apiEndpoint, tc.bastionIP, tc.objects...)
+			service.scope.(*scopetest.ClusterScope).WildcardIngressServiceValue = tc.wildcardIngressService
+			if tc.serviceDiscovery != nil {
+				service.scope.(*scopetest.ClusterScope).ServiceDiscoveryValue = *tc.serviceDiscovery
+			}
+			err := service.ReconcileRoute53(context.Background())
+
+			switch {
+			case tc.expectedError == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expectedError != nil && !tc.expectedError(err):
+				t.Fatalf("expected matching error, got %v", err)
+			case tc.expectedError != nil && tc.expectedRecords == nil:
+				return
+			}
+
+			clusterZoneID := fakeRoute53.HostedZoneID(testClusterDomain)
+			if clusterZoneID == "" {
+				t.Fatalf("expected hosted zone %s to exist", testClusterDomain)
+			}
+
+			delegation := fakeRoute53.RecordSet(fakeRoute53.HostedZoneID(testBaseDomain), testClusterDomain, "NS")
+			nameservers := fakeRoute53.RecordSet(clusterZoneID, testClusterDomain, "NS")
+			if delegation == nil || len(delegation.ResourceRecords) != len(nameservers.ResourceRecords) {
+				t.Fatalf("expected delegation %v, got %v", nameservers, delegation)
+			}
+
+			for key, expected := range tc.expectedRecords {
+				name, recordType, _ := strings.Cut(key, " ")
+				if got := recordValue(fakeRoute53, testClusterDomain, name, recordType); got != expected {
+					t.Errorf("expected record %s to be %q, got %q", key, expected, got)
+				}
+			}
+			for _, key := range tc.absentRecords {
+				name, recordType, _ := strings.Cut(key, " ")
+				if got := recordValue(fakeRoute53, testClusterDomain, name, recordType); got != "" {
+					t.Errorf("expected record %s to be absent, got %q", key, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_Service_ReconcileRoute53_DeletedRecordSets(t *testing.T) {
+	const owned = `"heritage=dns-operator-route53,dns-operator-route53/owner=mc/,dns-operator-route53/resource=cluster/org-test/test"`
+
+	testCases := []struct {
+		name    string
+		objects []client.Object
+		records []*route53.ResourceRecordSet
+
+		expectedDeleted []string
+	}{
+		{
+			name: "case 0: nothing to delete",
+			objects: []client.Object{
+				newGatewayService("gateway-a", "a.test.example.com", "10.0.0.40"),
+			},
+			records: []*route53.ResourceRecordSet{
+				aRecord("a.test.example.com", "10.0.0.40"),
+				txtRecord("a-a.test.example.com", owned),
+			},
+		},
+		{
+			name: "case 1: delete the record of a removed gateway Service",
+			records: []*route53.ResourceRecordSet{
+				aRecord("a.test.example.com", "10.0.0.40"),
+				txtRecord("a-a.test.example.com", owned),
+			},
+			expectedDeleted: []string{"A a.test.example.com"},
+		},
+		{
+			name: "case 2: delete the record of the previous hostname of a gateway Service",
+			objects: []client.Object{
+				newGatewayService("gateway-a", "c.test.example.com", "10.0.0.40", "fd00::40"),
+			},
+			records: []*route53.ResourceRecordSet{
+				aRecord("a.test.example.com", "10.0.0.40"),
+				txtRecord("a-a.test.example.com", owned),
+			},
+			expectedDeleted: []string{"A a.test.example.com"},
+		},
+		{
+			name: "case 3: keep the record of another cluster",
+			records: []*route53.ResourceRecordSet{
+				aRecord("a.test.example.com", "10.0.0.40"),
+				txtRecord("a-a.test.example.com", `"heritage=dns-operator-route53,dns-operator-route53/owner=mc/other,dns-operator-route53/resource=cluster/org-test/other"`),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			fakeRoute53.AddHostedZone(testBaseDomain)
+			zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+			for _, record := range tc.records {
+				fakeRoute53.AddRecordSet(zoneID, record)
+			}
+
+			service := newTestService(fakeRoute53, "10.0.0.10", "", tc.objects...)
+			dnsService := dns.NewService(service.scope, service)
+			if err := dnsService.Reconcile(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var deleted []string
+			for _, recordSet := range dnsService.DeletedRecordSets() {
+				deleted = append(deleted, fmt.Sprintf("%s %s", recordSet.Type, recordSet.Name))
+			}
+			if !slices.Equal(deleted, tc.expectedDeleted) {
+				t.Errorf("expected deleted record sets %v, got %v", tc.expectedDeleted, deleted)
+			}
+			for _, name := range deleted {
+				recordType, recordName, _ := strings.Cut(name, " ")
+				if got := recordValue(fakeRoute53, testClusterDomain, recordName, recordType); got != "" {
+					t.Errorf("expected record %s to be absent, got %q", name, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_Service_ReconcileRoute53_Alias(t *testing.T) {
+	testCases := []struct {
+		name           string
+		hostname       string
+		expectedZoneID string
+	}{
+		{
+			name:           "case 0: classic or application load balancer",
+			hostname:       "internal-a1b2c3-123456789.eu-west-1.elb.amazonaws.com",
+			expectedZoneID: "Z32O12XQLNTSW2",
+		},
+		{
+			name:           "case 1: network load balancer",
+			hostname:       "a1b2c3-123456789.elb.eu-central-1.amazonaws.com",
+			expectedZoneID: "Z3F0SRJ5LGBH90",
+		},
+		{
+			name:           "case 2: network load balancer in China",
+			hostname:       "a1b2c3-123456789.elb.cn-north-1.amazonaws.com.cn",
+			expectedZoneID: "Z3QFB96KMJ7ED6",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			fakeRoute53.AddHostedZone(testBaseDomain)
+			service := newTestService(fakeRoute53, "10.0.0.10", "", newIngressService(tc.hostname))
+
+			if err := service.ReconcileRoute53(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// The alias records must be stable.
+			changes := fakeRoute53.Calls("ChangeResourceRecordSets")
+			if err := service.ReconcileRoute53(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fakeRoute53.Calls("ChangeResourceRecordSets"); got != changes {
+				t.Fatalf("expected no further changes, got %d", got-changes)
+			}
+
+			zoneID := fakeRoute53.HostedZoneID(testClusterDomain)
+			for _, recordType := range []string{"A", "AAAA"} {
+				recordSet := fakeRoute53.RecordSet(zoneID, "ingress.test.example.com", recordType)
+				if recordSet == nil || recordSet.AliasTarget == nil {
+					t.Fatalf("expected %s alias record, got %v", recordType, recordSet)
+				}
+				if got := aws.StringValue(recordSet.AliasTarget.HostedZoneId); got != tc.expectedZoneID {
+					t.Errorf("expected %s alias in hosted zone %s, got %s", recordType, tc.expectedZoneID, got)
+				}
+				if got := strings.TrimSuffix(aws.StringValue(recordSet.AliasTarget.DNSName), "."); got != tc.hostname {
+					t.Errorf("expected %s alias for %s, got %s", recordType, tc.hostname, got)
+				}
+			}
+			if recordSet := fakeRoute53.RecordSet(zoneID, "ingress.test.example.com", "CNAME"); recordSet != nil {
+				t.Errorf("expected no CNAME record, got %v", recordSet)
+			}
+		})
+	}
+}
+
+func Test_Service_ReconcileRoute53_Ownership(t *testing.T) {
+	const owned = `"heritage=dns-operator-route53,dns-operator-route53/owner=mc/,dns-operator-route53/resource=cluster/org-test/test"`
+
+	testCases := []struct {
+		name    string
+		records []*route53.ResourceRecordSet
+
+		expectNotOwned  bool
+		expectedRecords map[string]string
+	}{
+		{
+			name: "case 0: adopt records without owner",
+			records: []*route53.ResourceRecordSet{
+				aRecord("api.test.example.com", "10.0.0.99"),
+			},
+			expectedRecords: map[string]string{
+				"api.test.example.com A":     "10.0.0.10",
+				"a-api.test.example.com TXT": owned,
+			},
+		},
+		{
+			name: "case 1: update owned records",
+			records: []*route53.ResourceRecordSet{
+				aRecord("api.test.example.com", "10.0.0.99"),
+				txtRecord("a-api.test.example.com", owned),
+			},
+			expectedRecords: map[string]string{
+				"api.test.example.com A": "10.0.0.10",
+			},
+		},
+		{
+			name: "case 2: refuse records of another cluster",
+			records: []*route53.ResourceRecordSet{
+				aRecord("api.test.example.com", "10.0.0.99"),
+				txtRecord("a-api.test.example.com", `"heritage=dns-operator-route53,dns-operator-route53/owner=mc/other"`),
+			},
+			expectNotOwned: true,
+			expectedRecords: map[string]string{
+				"api.test.example.com A":      "10.0.0.99",
+				"bastion1.test.example.com A": "10.0.0.30",
+				"ingress.test.example.com A":  "10.0.0.20",
+			},
+		},
+		{
+			name: "case 3: refuse records of external-dns",
+			records: []*route53.ResourceRecordSet{
+				aRecord("ingress.test.example.com", "10.0.0.99"),
+				txtRecord("ingress.test.example.com", `"heritage=external-dns,external-dns/owner=default"`),
+			},
+			expectNotOwned: true,
+			expectedRecords: map[string]string{
+				"api.test.example.com A":     "10.0.0.10",
+				"ingress.test.example.com A": "10.0.0.99",
+			},
+		},
+		{
+			name: "case 4: keep foreign records of other types",
+			records: []*route53.ResourceRecordSet{
+				txtRecord("aaaa-api.test.example.com", `"heritage=external-dns,external-dns/owner=default"`),
+				{
+					Name:            aws.String("api.test.example.com"),
+					Type:            aws.String("AAAA"),
+					TTL:             aws.Int64(300),
+					ResourceRecords: []*route53.ResourceRecord{{Value: aws.String("fd00::99")}},
+				},
+			},
+			expectNotOwned: true,
+			expectedRecords: map[string]string{
+				"api.test.example.com A":    "10.0.0.10",
+				"api.test.example.com AAAA": "fd00::99",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			fakeRoute53.AddHostedZone(testBaseDomain)
+			zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+			for _, record := range tc.records {
+				fakeRoute53.AddRecordSet(zoneID, record)
+			}
+
+			service := newTestService(fakeRoute53, "10.0.0.10", "10.0.0.30", newIngressService("10.0.0.20"))
+			err := service.ReconcileRoute53(context.Background())
+			if tc.expectNotOwned && !dns.IsRecordNotOwned(err) {
+				t.Fatalf("expected recordNotOwnedError, got %v", err)
+			} else if !tc.expectNotOwned && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for key, expected := range tc.expectedRecords {
+				name, recordType, _ := strings.Cut(key, " ")
+				if got := recordValue(fakeRoute53, testClusterDomain, name, recordType); got != expected {
+					t.Errorf("expected record %s to be %q, got %q", key, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_Service_ReconcileRoute53_Idempotent(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	fakeRoute53 := route53test.NewFakeRoute53()
+	fakeRoute53.AddHostedZone(testBaseDomain)
+	service := newTestService(fakeRoute53, "10.0.0.10", "10.0.0.30", newIngressService("10.0.0.20"))
+
+	if err := service.ReconcileRoute53(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	changes := fakeRoute53.Calls("ChangeResourceRecordSets")
+
+	if err := service.ReconcileRoute53(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fakeRoute53.Calls("ChangeResourceRecordSets"); got != changes {
+		t.Fatalf("expected no further changes, got %d", got-changes)
+	}
+	if got := fakeRoute53.Calls("CreateHostedZone"); got != 1 {
+		t.Fatalf("expected hosted zone to be created once, got %d", got)
+	}
+}
+
+func Test_Service_ReconcileRoute53_TTLs(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	fakeRoute53 := route53test.NewFakeRoute53()
+	fakeRoute53.AddHostedZone(testBaseDomain)
+	service := newTestService(fakeRoute53, "10.0.0.10", "10.0.0.30",
+		newIngressService("10.0.0.20"),
+		newGatewayService("gateway-a", "a.test.example.com", "10.0.0.40"),
+	)
+	ttls := cloud.RecordTTLs{Delegation: 86400, API: 60, Bastion: 600, Ingress: 120, Wildcard: 180, Gateway: 30}
+	service.scope.(*scopetest.ClusterScope).RecordTTLsValue = ttls
+
+	if err := service.ReconcileRoute53(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]int64{
+		"api.test.example.com A":      ttls.API,
+		"bastion1.test.example.com A": ttls.Bastion,
+		"ingress.test.example.com A":  ttls.Ingress,
+		"*.test.example.com CNAME":    ttls.Wildcard,
+		"a.test.example.com A":        ttls.Gateway,
+		"a-api.test.example.com TXT":  300,
+	}
+	for key, expectedTTL := range expected {
+		name, recordType, _ := strings.Cut(key, " ")
+		recordSet := fakeRoute53.RecordSet(fakeRoute53.HostedZoneID(testClusterDomain), name, recordType)
+		if recordSet == nil || *recordSet.TTL != expectedTTL {
+			t.Errorf("expected record %s with TTL %d, got %v", key, expectedTTL, recordSet)
+		}
+	}
+
+	delegation := fakeRoute53.RecordSet(fakeRoute53.HostedZoneID(testBaseDomain), testClusterDomain, "NS")
+	if delegation == nil || *delegation.TTL != ttls.Delegation {
+		t.Errorf("expected delegation with TTL %d, got %v", ttls.Delegation, delegation)
+	}
+
+	// Changed TTLs are applied to the existing records.
+	ttls.API = 30
+	service.scope.(*scopetest.ClusterScope).RecordTTLsValue = ttls
+	if err := service.ReconcileRoute53(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recordSet := fakeRoute53.RecordSet(fakeRoute53.HostedZoneID(testClusterDomain), "api.test.example.com", "A"); *recordSet.TTL != 30 {
+		t.Errorf("expected api record with TTL 30, got %d", *recordSet.TTL)
+	}
+}
+
+func Test_Service_ReconcileRoute53_BaseDomains(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	fakeRoute53 := route53test.NewFakeRoute53()
+	fakeRoute53.AddHostedZone(testBaseDomain)
+	fakeRoute53.AddHostedZone("customer.example.org")
+
+	// Clusters of the same name on different base domains get their own zones
+	// which are delegated from the hosted zone of their base domain.
+	first := newTestService(fakeRoute53, "10.0.0.10", "", newIngressService("10.0.0.20"))
+	second := newTestService(fakeRoute53, "10.0.1.10", "", newIngressService("10.0.1.20"))
+	second.scope.(*scopetest.ClusterScope).BaseDomainValue = "customer.example.org"
+
+	for _, service := range []*Service{first, second} {
+		if err := service.ReconcileRoute53(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for baseDomain, apiEndpoint := range map[string]string{testBaseDomain: "10.0.0.10", "customer.example.org": "10.0.1.10"} {
+		clusterDomain := "test." + baseDomain
+		if delegation := fakeRoute53.RecordSet(fakeRoute53.HostedZoneID(baseDomain), clusterDomain, "NS"); delegation == nil {
+			t.Errorf("expected delegation of %s in %s", clusterDomain, baseDomain)
+		}
+		api := fakeRoute53.RecordSet(fakeRoute53.HostedZoneID(clusterDomain), "api."+clusterDomain, "A")
+		if api == nil || *api.ResourceRecords[0].Value != apiEndpoint {
+			t.Errorf("expected api record of %s pointing to %s, got %v", clusterDomain, apiEndpoint, api)
+		}
+	}
+}
+
+func Test_Service_ReconcileRoute53_RecordNames(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	fakeRoute53 := route53test.NewFakeRoute53()
+	fakeRoute53.AddHostedZone(testBaseDomain)
+	service := newTestService(fakeRoute53, "10.0.0.10", "", newIngressService("10.0.0.20"))
+	clusterScope := service.scope.(*scopetest.ClusterScope)
+	clusterScope.ClusterDomainValue = "test.org-test.example.com"
+	clusterScope.RecordNamesValue = cloud.RecordNames{
+		API:      "k8s-api.test.org-test.example.com",
+		Bastion:  "bastion1.test.org-test.example.com",
+		Ingress:  "ingress.test.org-test.example.com",
+		Wildcard: "*.apps.test.org-test.example.com",
+	}
+
+	if err := service.ReconcileRoute53(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zoneID := fakeRoute53.HostedZoneID("test.org-test.example.com")
+	if delegation := fakeRoute53.RecordSet(fakeRoute53.HostedZoneID(testBaseDomain), "test.org-test.example.com", "NS"); delegation == nil {
+		t.Errorf("expected delegation of the templated cluster domain")
+	}
+	for _, expected := range []struct{ name, recordType string }{
+		{name: "k8s-api.test.org-test.example.com", recordType: "A"},
+		{name: "ingress.test.org-test.example.com", recordType: "A"},
+		{name: "*.apps.test.org-test.example.com", recordType: "CNAME"},
+	} {
+		if fakeRoute53.RecordSet(zoneID, expected.name, expected.recordType) == nil {
+			t.Errorf("expected %s record %s", expected.recordType, expected.name)
+		}
+	}
+	if fakeRoute53.RecordSet(zoneID, "api.test.org-test.example.com", "A") != nil {
+		t.Errorf("expected no record with the default api name")
+	}
+}
+
+func Test_Service_ReconcileRoute53_SeparateAccounts(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	baseRoute53 := route53test.NewFakeRoute53()
+	baseZoneID := baseRoute53.AddHostedZone(testBaseDomain)
+	clusterRoute53 := route53test.NewFakeRoute53()
+
+	service := newTestService(clusterRoute53, "10.0.0.10", "")
+	service.BaseRoute53Client = baseRoute53
+
+	if err := service.ReconcileRoute53(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if baseRoute53.HostedZoneID(testClusterDomain) != "" {
+		t.Fatalf("expected cluster zone not to be created in the base zone account")
+	}
+	clusterZoneID := clusterRoute53.HostedZoneID(testClusterDomain)
+	if clusterZoneID == "" {
+		t.Fatalf("expected cluster zone to be created in the cluster zone account")
+	}
+	if got := recordValue(clusterRoute53, testClusterDomain, "api.test.example.com", "A"); got != "10.0.0.10" {
+		t.Fatalf("expected API record in the cluster zone account, got %q", got)
+	}
+
+	delegation := baseRoute53.RecordSet(baseZoneID, testClusterDomain, "NS")
+	nameservers := clusterRoute53.RecordSet(clusterZoneID, testClusterDomain, "NS")
+	if delegation == nil || len(delegation.ResourceRecords) != len(nameservers.ResourceRecords) {
+		t.Fatalf("expected delegation %v in the base zone account, got %v", nameservers, delegation)
+	}
+}
+
+func Test_Service_ReconcileRoute53_Paginated(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	fakeRoute53 := route53test.NewFakeRoute53()
+	fakeRoute53.AddHostedZone(testBaseDomain)
+	zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+	// The API record is listed on the second page.
+	for i := range 400 {
+		fakeRoute53.AddRecordSet(zoneID, aRecord(fmt.Sprintf("a%03d.test.example.com", i), "10.0.0.1"))
+	}
+	fakeRoute53.AddRecordSet(zoneID, aRecord("api.test.example.com", "10.0.0.10"))
+	fakeRoute53.AddRecordSet(zoneID, aRecord("bastion1.test.example.com", "10.0.0.30"))
+
+	service := newTestService(fakeRoute53, "10.0.0.10", "")
+	if err := service.ReconcileRoute53(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the delegation and the removal of the orphaned bastion record are expected.
+	if got := fakeRoute53.Calls("ChangeResourceRecordSets"); got != 2 {
+		t.Fatalf("expected 2 changes, got %d", got)
+	}
+	if got := recordValue(fakeRoute53, testClusterDomain, "bastion1.test.example.com", "A"); got != "" {
+		t.Fatalf("expected bastion record to be deleted, got %q", got)
+	}
+}
+
+func Test_Service_PendingChanges(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		waitForChangePropagation bool
+		propagationPolls         int
+		expectedPending          []bool
+	}{
+		{
+			name:            "case 0: changes are not tracked by default",
+			expectedPending: []bool{false},
+		},
+		{
+			name:                     "case 1: changes which are INSYNC on the first poll",
+			waitForChangePropagation: true,
+			expectedPending:          []bool{false, false},
+		},
+		{
+			name:                     "case 2: changes are pending until they are INSYNC",
+			waitForChangePropagation: true,
+			propagationPolls:         2,
+			expectedPending:          []bool{true, true, false, false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			fakeRoute53.AddHostedZone(testBaseDomain)
+			fakeRoute53.DelayPropagation(tc.propagationPolls)
+			service := newTestService(fakeRoute53, "10.0.0.10", "")
+			service.config.WaitForChangePropagation = tc.waitForChangePropagation
+			t.Cleanup(service.forgetChanges)
+
+			if err := service.ReconcileRoute53(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for i, expected := range tc.expectedPending {
+				pending, err := service.PendingChanges(context.Background())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := len(pending) > 0; got != expected {
+					t.Fatalf("poll %d: expected pending changes to be %t, got %v", i, expected, pending)
+				}
+			}
+
+			if !tc.waitForChangePropagation && fakeRoute53.Calls("GetChange") != 0 {
+				t.Fatalf("expected no GetChange calls, got %d", fakeRoute53.Calls("GetChange"))
+			}
+		})
+	}
+}
+
+func Test_Service_DryRun(t *testing.T) {
+	const owned = `"heritage=dns-operator-route53,dns-operator-route53/owner=mc/,dns-operator-route53/resource=cluster/org-test/test"`
+
+	testCases := []struct {
+		name string
+		// reconciledIngress is the ingress address of a previous reconciliation
+		// without dry-run. The cluster is not reconciled before if it is empty.
+		reconciledIngress string
+		// restart clears the cache after the previous reconciliation, as a
+		// restart of the operator does.
+		restart bool
+		ingress string
+		delete  bool
+
+		expectedPlan []string
+	}{
+		{
+			name:    "case 0: plan the hosted zone, the delegation and all records of a new cluster",
+			ingress: "10.0.0.20",
+			expectedPlan: []string{
+				"CREATE_ZONE test.example.com",
+				"UPSERT NS test.example.com",
+				"CREATE A api.test.example.com 10.0.0.10",
+				"CREATE TXT a-api.test.example.com " + owned,
+				"CREATE A ingress.test.example.com 10.0.0.20",
+				"CREATE TXT a-ingress.test.example.com " + owned,
+				"CREATE CNAME *.test.example.com ingress.test.example.com",
+				"CREATE TXT cname-*.test.example.com " + owned,
+			},
+		},
+		{
+			name:              "case 1: plan only the changed records of an existing cluster",
+			reconciledIngress: "10.0.0.20",
+			ingress:           "10.0.0.21",
+			expectedPlan: []string{
+				"UPSERT A ingress.test.example.com 10.0.0.21",
+			},
+		},
+		{
+			name:              "case 2: plan the deletion of an existing cluster",
+			reconciledIngress: "10.0.0.20",
+			ingress:           "10.0.0.20",
+			delete:            true,
+			expectedPlan: []string{
+				"DELETE A api.test.example.com 10.0.0.10",
+				"DELETE A ingress.test.example.com 10.0.0.20",
+				"DELETE CNAME *.test.example.com ingress.test.example.com",
+				"DELETE TXT a-api.test.example.com " + owned,
+				"DELETE TXT a-ingress.test.example.com " + owned,
+				"DELETE TXT cname-*.test.example.com " + owned,
+				"DELETE NS test.example.com <name servers>",
+				"DELETE_ZONE test.example.com",
+			},
+		},
+		{
+			name:              "case 3: plan nothing for an unchanged cluster after a restart of the operator",
+			reconciledIngress: "10.0.0.20",
+			restart:           true,
+			ingress:           "10.0.0.20",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			baseZoneID := fakeRoute53.AddHostedZone(testBaseDomain)
+			if tc.reconciledIngress != "" {
+				service := newTestService(fakeRoute53, "10.0.0.10", "", newIngressService(tc.reconciledIngress))
+				if err := service.ReconcileRoute53(context.Background()); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if tc.restart {
+				dnscache.DNSOperatorCache.Reset()
+			}
+			changes := fakeRoute53.Calls("ChangeResourceRecordSets")
+			zoneChanges := fakeRoute53.Calls("CreateHostedZone") + fakeRoute53.Calls("DeleteHostedZone")
+			recordSets := fakeRoute53.RecordSets(fakeRoute53.HostedZoneID(testClusterDomain))
+			var nameservers []string
+			if delegation := fakeRoute53.RecordSet(baseZoneID, testClusterDomain, "NS"); delegation != nil {
+				for _, record := range delegation.ResourceRecords {
+					nameservers = append(nameservers, aws.StringValue(record.Value))
+				}
+			}
+
+			service := newTestService(fakeRoute53, "10.0.0.10", "", newIngressService(tc.ingress))
+			service.config.DryRun = true
+			var err error
+			if tc.delete {
+				err = service.DeleteRoute53(context.Background())
+			} else {
+				err = service.ReconcileRoute53(context.Background())
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var plan []string
+			for _, change := range service.PlannedChanges() {
+				plan = append(plan, change.String())
+			}
+			// Changes which Route53 applies in one batch have no fixed order.
+			if tc.delete {
+				slices.Sort(plan[:len(plan)-2])
+			}
+			expectedPlan := make([]string, 0, len(tc.expectedPlan))
+			for _, change := range tc.expectedPlan {
+				expectedPlan = append(expectedPlan, strings.ReplaceAll(change, "<name servers>", strings.Join(nameservers, ",")))
+			}
+			if !slices.Equal(plan, expectedPlan) {
+				t.Fatalf("expected plan\n%s\ngot\n%s", strings.Join(expectedPlan, "\n"), strings.Join(plan, "\n"))
+			}
+
+			if got := fakeRoute53.Calls("ChangeResourceRecordSets"); got != changes {
+				t.Fatalf("expected no changes, got %d", got-changes)
+			}
+			if got := fakeRoute53.Calls("CreateHostedZone") + fakeRoute53.Calls("DeleteHostedZone"); got != zoneChanges {
+				t.Fatalf("expected hosted zone not to be created or deleted in dry-run mode")
+			}
+			if got := fakeRoute53.RecordSets(fakeRoute53.HostedZoneID(testClusterDomain)); len(got) != len(recordSets) {
+				t.Fatalf("expected records to be unchanged, got %v", got)
+			}
+			if tc.reconciledIngress != "" && fakeRoute53.RecordSet(baseZoneID, testClusterDomain, "NS") == nil {
+				t.Fatalf("expected delegation to be kept")
+			}
+		})
+	}
+}
+
+func Test_Service_ReconcileRecordSet(t *testing.T) {
+	testCases := []struct {
+		name     string
+		desired  dns.RecordSet
+		previous *dns.RecordSet
+		setup    func(fakeRoute53 *route53test.FakeRoute53, zoneID string)
+
+		expectedError  func(error) bool
+		expectedValues []string
+		absentRecords  []string
+		presentRecords []string
+	}{
+		{
+			name:           "case 0: create multi value TXT record",
+			desired:        dns.RecordSet{Name: "test.example.com", Type: "TXT", TTL: 300, Values: []string{`"a"`, `"b"`}},
+			expectedValues: []string{`"a"`, `"b"`},
+		},
+		{
+			name:    "case 1: update record with changed second value",
+			desired: dns.RecordSet{Name: "vault.test.example.com", Type: "A", TTL: 300, Values: []string{"10.0.0.1", "10.0.0.3"}},
+			setup: func(fakeRoute53 *route53test.FakeRoute53, zoneID string) {
+				recordSet := aRecord("vault.test.example.com", "10.0.0.1")
+				recordSet.ResourceRecords = append(recordSet.ResourceRecords, &route53.ResourceRecord{Value: aws.String("10.0.0.2")})
+				fakeRoute53.AddRecordSet(zoneID, recordSet)
+			},
+			expectedValues: []string{"10.0.0.1", "10.0.0.3"},
+		},
+		{
+			name:     "case 2: rename record",
+			desired:  dns.RecordSet{Name: "vault2.test.example.com", Type: "A", TTL: 300, Values: []string{"10.0.0.1"}},
+			previous: &dns.RecordSet{Name: "vault.test.example.com", Type: "A"},
+			setup: func(fakeRoute53 *route53test.FakeRoute53, zoneID string) {
+				fakeRoute53.AddRecordSet(zoneID, aRecord("vault.test.example.com", "10.0.0.1"))
+			},
+			expectedValues: []string{"10.0.0.1"},
+			absentRecords:  []string{"vault.test.example.com A"},
+		},
+		{
+			name:          "case 3: record conflicts with API record",
+			desired:       dns.RecordSet{Name: "api.test.example.com", Type: "A", TTL: 300, Values: []string{"10.0.0.1"}},
+			expectedError: dns.IsRecordConflict,
+		},
+		{
+			name:    "case 4: keep record of another DNSRecord",
+			desired: dns.RecordSet{Name: "vault2.test.example.com", Type: "A", TTL: 300, Values: []string{"10.0.0.1"}},
+			setup: func(fakeRoute53 *route53test.FakeRoute53, zoneID string) {
+				fakeRoute53.AddRecordSet(zoneID, aRecord("consul.test.example.com", "10.0.0.2"))
+				fakeRoute53.AddRecordSet(zoneID, txtRecord("a-consul.test.example.com",
+					`"heritage=dns-operator-route53,dns-operator-route53/owner=mc/,dns-operator-route53/resource=dnsrecord/org-test/consul"`))
+			},
+			expectedValues: []string{"10.0.0.1"},
+			presentRecords: []string{"consul.test.example.com A"},
+		},
+		{
+			name:          "case 5: NS record at the cluster domain",
+			desired:       dns.RecordSet{Name: "test.example.com", Type: "NS", TTL: 300, Values: []string{"ns1.example.org"}},
+			expectedError: dns.IsRecordConflict,
+		},
+		{
+			name:          "case 6: CNAME record at the cluster domain",
+			desired:       dns.RecordSet{Name: "test.example.com", Type: "CNAME", TTL: 300, Values: []string{"lb.example.org"}},
+			expectedError: dns.IsRecordConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			fakeRoute53.AddHostedZone(testBaseDomain)
+			zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+			if tc.setup != nil {
+				tc.setup(fakeRoute53, zoneID)
+			}
+
+			service := newTestService(fakeRoute53, "10.0.0.10", "")
+			err := dns.NewService(service.scope, service).ReconcileRecordSet(context.Background(), dns.DNSRecordResource("org-test", "vault"), tc.desired, tc.previous)
+
+			switch {
+			case tc.expectedError == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expectedError != nil && !tc.expectedError(err):
+				t.Fatalf("expected matching error, got %v", err)
+			case tc.expectedError != nil:
+				return
+			}
+
+			recordSet := fakeRoute53.RecordSet(zoneID, tc.desired.Name, tc.desired.Type)
+			if recordSet == nil {
+				t.Fatalf("expected record %s %s to exist", tc.desired.Name, tc.desired.Type)
+			}
+			var values []string
+			for _, record := range recordSet.ResourceRecords {
+				values = append(values, *record.Value)
+			}
+			if !slices.Equal(values, tc.expectedValues) {
+				t.Errorf("expected values %v, got %v", tc.expectedValues, values)
+			}
+			for _, key := range tc.absentRecords {
+				name, recordType, _ := strings.Cut(key, " ")
+				if got := recordValue(fakeRoute53, testClusterDomain, name, recordType); got != "" {
+					t.Errorf("expected record %s to be absent, got %q", key, got)
+				}
+			}
+			for _, key := range tc.presentRecords {
+				name, recordType, _ := strings.Cut(key, " ")
+				if got := recordValue(fakeRoute53, testClusterDomain, name, recordType); got == "" {
+					t.Errorf("expected record %s to be present", key)
+				}
+			}
+		})
+	}
+}
+
+func Test_Service_RecordSetWithoutZone(t *testing.T) {
+	dnscache.DNSOperatorCache.Reset()
+
+	fakeRoute53 := route53test.NewFakeRoute53()
+	fakeRoute53.AddHostedZone(testBaseDomain)
+	service := newTestService(fakeRoute53, "10.0.0.10", "")
+	dnsService := dns.NewService(service.scope, service)
+	recordSet := dns.RecordSet{Name: "vault.test.example.com", Type: "A", TTL: 300, Values: []string{"10.0.0.1"}}
+
+	err := dnsService.ReconcileRecordSet(context.Background(), dns.DNSRecordResource("org-test", "vault"), recordSet, nil)
+	if !dns.IsZoneNotReady(err) {
+		t.Fatalf("expected zoneNotReadyError, got %v", err)
+	}
+	if err := dnsService.DeleteRecordSet(context.Background(), dns.DNSRecordResource("org-test", "vault"), recordSet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := fakeRoute53.Calls("CreateHostedZone"); got != 0 {
+		t.Fatalf("expected hosted zone not to be created, got %d calls", got)
+	}
+	if got := fakeRoute53.Calls("ChangeResourceRecordSets"); got != 0 {
+		t.Fatalf("expected no changes, got %d", got)
+	}
+}
+
+// batchRecorder records the change batches sent to the fake.
+type batchRecorder struct {
+	*route53test.FakeRoute53
+	batches [][]*route53.Change
+}
+
+func (r *batchRecorder) ChangeResourceRecordSetsWithContext(ctx aws.Context, input *route53.ChangeResourceRecordSetsInput, opts ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
+	r.batches = append(r.batches, input.ChangeBatch.Changes)
+	return r.FakeRoute53.ChangeResourceRecordSetsWithContext(ctx, input, opts...)
+}
+
+func Test_Service_ApplyRecordSetDiff_Batches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		obsolete int
+
+		expectedBatches int
+	}{
+		{
+			name:            "case 0: replace A record with CNAME",
+			expectedBatches: 1,
+		},
+		{
+			name:            "case 1: replace A record with CNAME across the batch boundary",
+			obsolete:        route53test.MaxChangeBatchRecords - 1,
+			expectedBatches: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+			var diff dns.RecordSetDiff
+			for i := range tc.obsolete {
+				name := fmt.Sprintf("gw%04d.test.example.com", i)
+				fakeRoute53.AddRecordSet(zoneID, aRecord(name, "10.0.0.10"))
+				diff.Delete = append(diff.Delete, dns.RecordSet{Name: name, Type: "A"})
+			}
+			fakeRoute53.AddRecordSet(zoneID, aRecord("app.test.example.com", "10.0.0.20"))
+			diff.Delete = append(diff.Delete, dns.RecordSet{Name: "app.test.example.com", Type: "A"})
+			diff.Create = append(diff.Create, dns.RecordSet{Name: "app.test.example.com", Type: "CNAME", TTL: 300, Values: []string{"lb.example.org"}})
+
+			recorder := &batchRecorder{FakeRoute53: fakeRoute53}
+			service := newTestService(fakeRoute53, "10.0.0.10", "")
+			service.Route53Client = recorder
+
+			if err := service.ApplyRecordSetDiff(context.Background(), zoneID, diff); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(recorder.batches) != tc.expectedBatches {
+				t.Fatalf("expected %d batches, got %d", tc.expectedBatches, len(recorder.batches))
+			}
+			for _, batch := range recorder.batches {
+				var actions []string
+				for _, change := range batch {
+					if normalizeRecordName(aws.StringValue(change.ResourceRecordSet.Name)) == "app.test.example.com" {
+						actions = append(actions, aws.StringValue(change.Action)+" "+aws.StringValue(change.ResourceRecordSet.Type))
+					}
+				}
+				if len(actions) > 0 && !slices.Equal(actions, []string{"DELETE A", "CREATE CNAME"}) {
+					t.Fatalf("expected the A record to be replaced in one batch, got %v", actions)
+				}
+			}
+			if got := recordValue(fakeRoute53, testClusterDomain, "app.test.example.com", "CNAME"); got != "lb.example.org" {
+				t.Fatalf("expected CNAME record, got %q", got)
+			}
+			if got := recordValue(fakeRoute53, testClusterDomain, "app.test.example.com", "A"); got != "" {
+				t.Fatalf("expected A record to be deleted, got %q", got)
+			}
+		})
+	}
+}
+
+func Test_Service_DeleteRoute53(t *testing.T) {
+	testCases := []struct {
+		name  string
+		setup func(t *testing.T, fakeRoute53 *route53test.FakeRoute53, service *Service)
+
+		expectedError    func(error) bool
+		expectedZoneKept bool
+	}{
+		{
+			name: "case 0: delete records, delegation and zone",
+			setup: func(t *testing.T, fakeRoute53 *route53test.FakeRoute53, service *Service) {
+				if err := service.ReconcileRoute53(context.Background()); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "case 1: zone does not exist",
+		},
+		{
+			name: "case 2: delegation does not exist",
+			setup: func(t *testing.T, fakeRoute53 *route53test.FakeRoute53, service *Service) {
+				zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+				fakeRoute53.AddRecordSet(zoneID, aRecord("api.test.example.com", "10.0.0.10"))
+			},
+		},
+		{
+			name: "case 3: delete large zone in multiple batches",
+			setup: func(t *testing.T, fakeRoute53 *route53test.FakeRoute53, service *Service) {
+				zoneID := fakeRoute53.AddHostedZone(testClusterDomain)
+				for i := range 1200 {
+					fakeRoute53.AddRecordSet(zoneID, aRecord(fmt.Sprintf("gw%04d.test.example.com", i), "10.0.0.10"))
+				}
+				txt := &route53.ResourceRecordSet{Name: aws.String(testClusterDomain), Type: aws.String("TXT"), TTL: aws.Int64(300)}
+				for i := range 10 {
+					txt.ResourceRecords = append(txt.ResourceRecords, &route53.ResourceRecord{Value: aws.String(fmt.Sprintf("%q", strings.Repeat(strconv.Itoa(i), 250)))})
+				}
+				fakeRoute53.AddRecordSet(zoneID, txt)
+			},
+		},
+		{
+			name: "case 4: throttled zone deletion",
+			setup: func(t *testing.T, fakeRoute53 *route53test.FakeRoute53, service *Service) {
+				fakeRoute53.AddHostedZone(testClusterDomain)
+				fakeRoute53.Throttle("DeleteHostedZone", 1)
+			},
+			expectedError: IsThrottlingRateExceededError,
+		},
+		{
+			name: "case 5: keep zone with records owned by someone else",
+			setup: func(t *testing.T, fakeRoute53 *route53test.FakeRoute53, service *Service) {
+				if err := service.ReconcileRoute53(context.Background()); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				zoneID := fakeRoute53.HostedZoneID(testClusterDomain)
+				fakeRoute53.AddRecordSet(zoneID, aRecord("app.test.example.com", "10.0.0.50"))
+				fakeRoute53.AddRecordSet(zoneID, txtRecord("a-app.test.example.com", `"heritage=external-dns,external-dns/owner=default"`))
+			},
+			expectedError:    dns.IsForeignRecords,
+			expectedZoneKept: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dnscache.DNSOperatorCache.Reset()
+
+			fakeRoute53 := route53test.NewFakeRoute53()
+			baseZoneID := fakeRoute53.AddHostedZone(testBaseDomain)
+			service := newTestService(fakeRoute53, "10.0.0.10", "10.0.0.30", newIngressService("10.0.0.20"))
+			if tc.setup != nil {
+				tc.setup(t, fakeRoute53, service)
+			}
+
+			err := service.DeleteRoute53(context.Background())
+
+			switch {
+			case tc.expectedError == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expectedError != nil && !tc.expectedError(err):
+				t.Fatalf("expected matching error, got %v", err)
+			case tc.expectedError != nil && !tc.expectedZoneKept:
+				return
+			}
+
+			if tc.expectedZoneKept {
+				if !strings.Contains(err.Error(), "test.example.com") || !strings.Contains(err.Error(), "2 record sets") {
+					t.Fatalf("expected error to name the kept zone and record sets, got %v", err)
+				}
+				if got := recordValue(fakeRoute53, testClusterDomain, "app.test.example.com", "A"); got != "10.0.0.50" {
+					t.Fatalf("expected foreign record to be kept, got %q", got)
+				}
+				if got := recordValue(fakeRoute53, testClusterDomain, "api.test.example.com", "A"); got != "" {
+					t.Fatalf("expected owned record to be deleted, got %q", got)
+				}
+				if delegation := fakeRoute53.RecordSet(baseZoneID, testClusterDomain, "NS"); delegation == nil {
+					t.Fatalf("expected delegation to be kept")
+				}
+				return
+			}
+
+			if zoneID := fakeRoute53.HostedZoneID(testClusterDomain); zoneID != "" {
+				t.Fatalf("expected hosted zone to be deleted, got %v", fakeRoute53.RecordSets(zoneID))
+			}
+			if delegation := fakeRoute53.RecordSet(baseZoneID, testClusterDomain, "NS"); delegation != nil {
+				t.Fatalf("expected delegation to be deleted, got %v", delegation)
+			}
+		})
+	}
+}
@@ -0,0 +1,772 @@
+// Package route53test provides a stateful in-memory implementation of the
+// Route53 API to test the Route53 service without AWS credentials.
+package route53test
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+const (
+	hostedZoneIDPrefix = "/hostedzone/"
+	changeIDPrefix     = "/change/"
+
+	defaultMaxHostedZones = 100
+	defaultMaxRecordSets  = 300
+
+	// Limits of a single ChangeResourceRecordSets request. UPSERT changes count twice.
+	MaxChangeBatchRecords    = 1000
+	MaxChangeBatchCharacters = 32000
+
+	nsTTL  = 172800
+	soaTTL = 900
+
+	actionCreate = "CREATE"
+	actionDelete = "DELETE"
+	actionUpsert = "UPSERT"
+)
+
+type hostedZone struct {
+	id              string
+	name            string
+	callerReference string
+	comment         string
+	recordSets      []*route53.ResourceRecordSet
+}
+
+type injectedError struct {
+	err   error
+	times int
+}
+
+// FakeRoute53 models hosted zones, their records and the validation rules of
+// ChangeResourceRecordSets. Methods which are not implemented panic through
+// the embedded nil interface.
+type FakeRoute53 struct {
+	route53iface.Route53API
+
+	mu       sync.Mutex
+	zones    map[string]*hostedZone
+	changes  map[string]time.Time
+	errors   map[string]*injectedError
+	calls    map[string]int
+	sequence int
+
+	// propagationPolls is the number of GetChange calls which report a change as PENDING.
+	propagationPolls int
+	changePolls      map[string]int
+}
+
+// NewFakeRoute53 returns an empty fake without any hosted zone.
+func NewFakeRoute53() *FakeRoute53 {
+	return &FakeRoute53{
+		zones:       map[string]*hostedZone{},
+		changes:     map[string]time.Time{},
+		errors:      map[string]*injectedError{},
+		calls:       map[string]int{},
+		changePolls: map[string]int{},
+	}
+}
+
+// AddHostedZone creates a hosted zone including its NS and SOA records and returns its ID.
+func (f *FakeRoute53) AddHostedZone(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.addHostedZone(name, "", "")
+}
+
+// AddRecordSet adds a record set to the given hosted zone bypassing any validation.
+func (f *FakeRoute53) AddRecordSet(hostedZoneID string, recordSet *route53.ResourceRecordSet) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	zone := f.zones[normalizeZoneID(hostedZoneID)]
+	recordSet = copyRecordSet(recordSet)
+	recordSet.Name = aws.String(canonicalName(*recordSet.Name))
+	zone.recordSets = append(zone.recordSets, recordSet)
+}
+
+// RecordSets returns a copy of all record sets of the given hosted zone.
+func (f *FakeRoute53) RecordSets(hostedZoneID string) []*route53.ResourceRecordSet {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	zone, ok := f.zones[normalizeZoneID(hostedZoneID)]
+	if !ok {
+		return nil
+	}
+
+	return copyRecordSets(sortedRecordSets(zone.recordSets))
+}
+
+// RecordSet returns a copy of the record set with the given name and type or nil.
+func (f *FakeRoute53) RecordSet(hostedZoneID, name, recordType string) *route53.ResourceRecordSet {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	zone, ok := f.zones[normalizeZoneID(hostedZoneID)]
+	if !ok {
+		return nil
+	}
+
+	if recordSet := findRecordSet(zone.recordSets, name, recordType); recordSet != nil {
+		return copyRecordSet(recordSet)
+	}
+	return nil
+}
+
+// HostedZoneID returns the ID of the hosted zone with the given name or an empty string.
+func (f *FakeRoute53) HostedZoneID(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, zone := range f.zones {
+		if zone.name == canonicalName(name) {
+			return zone.id
+		}
+	}
+	return ""
+}
+
+// Calls returns how often the given operation, e.g. ChangeResourceRecordSets, was called.
+func (f *FakeRoute53) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls[operation]
+}
+
+// InjectError makes the next calls of the given operation fail with err.
+func (f *FakeRoute53) InjectError(operation string, err error, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errors[operation] = &injectedError{err: err, times: times}
+}
+
+// Throttle makes the next calls of the given operation fail with a ThrottlingException.
+func (f *FakeRoute53) Throttle(operation string, times int) {
+	f.InjectError(operation, newError(route53.ErrCodeThrottlingException, 400, "Rate exceeded"), times)
+}
+
+// call records the call of an operation and returns an injected error if any.
+func (f *FakeRoute53) call(operation string) error {
+	f.calls[operation]++
+
+	injected, ok := f.errors[operation]
+	if !ok {
+		return nil
+	}
+	injected.times--
+	if injected.times <= 0 {
+		delete(f.errors, operation)
+	}
+	return injected.err
+}
+
+func (f *FakeRoute53) CreateHostedZone(input *route53.CreateHostedZoneInput) (*route53.CreateHostedZoneOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("CreateHostedZone"); err != nil {
+		return nil, err
+	}
+
+	for _, zone := range f.zones {
+		if zone.callerReference == aws.StringValue(input.CallerReference) {
+			return nil, newError(route53.ErrCodeHostedZoneAlreadyExists, 409, fmt.Sprintf("A hosted zone has already been created with the specified caller reference %q.", zone.callerReference))
+		}
+	}
+
+	var comment string
+	if input.HostedZoneConfig != nil {
+		comment = aws.StringValue(input.HostedZoneConfig.Comment)
+	}
+	id := f.addHostedZone(aws.StringValue(input.Name), aws.StringValue(input.CallerReference), comment)
+	zone := f.zones[normalizeZoneID(id)]
+
+	nameservers := findRecordSet(zone.recordSets, zone.name, route53.RRTypeNs)
+	delegationSet := &route53.DelegationSet{}
+	for _, rr := range nameservers.ResourceRecords {
+		delegationSet.NameServers = append(delegationSet.NameServers, aws.String(*rr.Value))
+	}
+
+	return &route53.CreateHostedZoneOutput{
+		HostedZone:    zone.toAPI(),
+		DelegationSet: delegationSet,
+		ChangeInfo:    f.newChangeInfo(),
+		Location:      aws.String("https://route53.amazonaws.com/2013-04-01" + id),
+	}, nil
+}
+
+func (f *FakeRoute53) CreateHostedZoneWithContext(_ aws.Context, input *route53.CreateHostedZoneInput, _ ...request.Option) (*route53.CreateHostedZoneOutput, error) {
+	return f.CreateHostedZone(input)
+}
+
+func (f *FakeRoute53) GetHostedZone(input *route53.GetHostedZoneInput) (*route53.GetHostedZoneOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("GetHostedZone"); err != nil {
+		return nil, err
+	}
+
+	zone, err := f.getZone(aws.StringValue(input.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &route53.GetHostedZoneOutput{HostedZone: zone.toAPI()}, nil
+}
+
+func (f *FakeRoute53) GetHostedZoneWithContext(_ aws.Context, input *route53.GetHostedZoneInput, _ ...request.Option) (*route53.GetHostedZoneOutput, error) {
+	return f.GetHostedZone(input)
+}
+
+func (f *FakeRoute53) DeleteHostedZone(input *route53.DeleteHostedZoneInput) (*route53.DeleteHostedZoneOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("DeleteHostedZone"); err != nil {
+		return nil, err
+	}
+
+	zone, err := f.getZone(aws.StringValue(input.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, recordSet := range zone.recordSets {
+		if !isApexRecord(zone, recordSet) {
+			return nil, newError(route53.ErrCodeHostedZoneNotEmpty, 400, "The hosted zone contains resource record sets in addition to the default NS and SOA resource record sets. Before you can delete the hosted zone, you must delete the additional resource record sets.")
+		}
+	}
+
+	delete(f.zones, normalizeZoneID(zone.id))
+
+	return &route53.DeleteHostedZoneOutput{ChangeInfo: f.newChangeInfo()}, nil
+}
+
+func (f *FakeRoute53) DeleteHostedZoneWithContext(_ aws.Context, input *route53.DeleteHostedZoneInput, _ ...request.Option) (*route53.DeleteHostedZoneOutput, error) {
+	return f.DeleteHostedZone(input)
+}
+
+// ListHostedZonesByName returns the hosted zones ordered by their reversed
+// labels, starting with the zone given by DNSName.
+func (f *FakeRoute53) ListHostedZonesByName(input *route53.ListHostedZonesByNameInput) (*route53.ListHostedZonesByNameOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("ListHostedZonesByName"); err != nil {
+		return nil, err
+	}
+
+	maxItems, err := parseMaxItems(input.MaxItems, defaultMaxHostedZones)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]*hostedZone, 0, len(f.zones))
+	for _, zone := range f.zones {
+		zones = append(zones, zone)
+	}
+	slices.SortFunc(zones, func(a, b *hostedZone) int {
+		if c := strings.Compare(reverseLabels(a.name), reverseLabels(b.name)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.id, b.id)
+	})
+
+	start := 0
+	if input.DNSName != nil {
+		dnsName := reverseLabels(canonicalName(*input.DNSName))
+		start = len(zones)
+		for i, zone := range zones {
+			if reverseLabels(zone.name) >= dnsName {
+				start = i
+				break
+			}
+		}
+	}
+
+	output := &route53.ListHostedZonesByNameOutput{
+		DNSName:  input.DNSName,
+		MaxItems: aws.String(strconv.Itoa(maxItems)),
+	}
+	end := min(start+maxItems, len(zones))
+	for _, zone := range zones[start:end] {
+		output.HostedZones = append(output.HostedZones, zone.toAPI())
+	}
+	output.IsTruncated = aws.Bool(end < len(zones))
+	if end < len(zones) {
+		output.NextDNSName = aws.String(zones[end].name)
+		output.NextHostedZoneId = aws.String(zones[end].id)
+	}
+
+	return output, nil
+}
+
+func (f *FakeRoute53) ListHostedZonesByNameWithContext(_ aws.Context, input *route53.ListHostedZonesByNameInput, _ ...request.Option) (*route53.ListHostedZonesByNameOutput, error) {
+	return f.ListHostedZonesByName(input)
+}
+
+// ListResourceRecordSets returns the record sets ordered by name with reversed
+// labels and type, starting at StartRecordName and StartRecordType.
+func (f *FakeRoute53) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("ListResourceRecordSets"); err != nil {
+		return nil, err
+	}
+
+	zone, err := f.getZone(aws.StringValue(input.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	maxItems, err := parseMaxItems(input.MaxItems, defaultMaxRecordSets)
+	if err != nil {
+		return nil, err
+	}
+
+	recordSets := sortedRecordSets(zone.recordSets)
+
+	start := 0
+	if input.StartRecordName != nil {
+		startKey := sortKey(canonicalName(*input.StartRecordName), aws.StringValue(input.StartRecordType))
+		start = len(recordSets)
+		for i, recordSet := range recordSets {
+			if sortKey(*recordSet.Name, *recordSet.Type) >= startKey {
+				start = i
+				break
+			}
+		}
+	}
+
+	end := min(start+maxItems, len(recordSets))
+	output := &route53.ListResourceRecordSetsOutput{
+		ResourceRecordSets: copyRecordSets(recordSets[start:end]),
+		MaxItems:           aws.String(strconv.Itoa(maxItems)),
+		IsTruncated:        aws.Bool(end < len(recordSets)),
+	}
+	if end < len(recordSets) {
+		output.NextRecordName = aws.String(*recordSets[end].Name)
+		output.NextRecordType = aws.String(*recordSets[end].Type)
+	}
+
+	return output, nil
+}
+
+func (f *FakeRoute53) ListResourceRecordSetsWithContext(_ aws.Context, input *route53.ListResourceRecordSetsInput, _ ...request.Option) (*route53.ListResourceRecordSetsOutput, error) {
+	return f.ListResourceRecordSets(input)
+}
+
+func (f *FakeRoute53) ListResourceRecordSetsPages(input *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool) error {
+	return f.ListResourceRecordSetsPagesWithContext(aws.BackgroundContext(), input, fn)
+}
+
+func (f *FakeRoute53) ListResourceRecordSetsPagesWithContext(ctx aws.Context, input *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool, _ ...request.Option) error {
+	pageInput := *input
+	for {
+		output, err := f.ListResourceRecordSetsWithContext(ctx, &pageInput)
+		if err != nil {
+			return err
+		}
+
+		lastPage := !aws.BoolValue(output.IsTruncated)
+		if !fn(output, lastPage) || lastPage {
+			return nil
+		}
+
+		pageInput.StartRecordName = output.NextRecordName
+		pageInput.StartRecordType = output.NextRecordType
+	}
+}
+
+// ChangeResourceRecordSets validates the whole change batch before applying
+// it, so a failing change leaves the hosted zone untouched.
+func (f *FakeRoute53) ChangeResourceRecordSets(input *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("ChangeResourceRecordSets"); err != nil {
+		return nil, err
+	}
+
+	zone, err := f.getZone(aws.StringValue(input.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+
+	if input.ChangeBatch == nil || len(input.ChangeBatch.Changes) == 0 {
+		return nil, newError(route53.ErrCodeInvalidInput, 400, "1 validation error detected: Value null at 'changeBatch.changes' failed to satisfy constraint: Member must not be null")
+	}
+
+	if err := validateBatchLimits(input.ChangeBatch.Changes); err != nil {
+		return nil, err
+	}
+
+	recordSets := copyRecordSets(zone.recordSets)
+	var messages []string
+	seen := map[string]bool{}
+
+	for _, change := range input.ChangeBatch.Changes {
+		recordSet := copyRecordSet(change.ResourceRecordSet)
+		recordSet.Name = aws.String(canonicalName(aws.StringValue(recordSet.Name)))
+		name, recordType := *recordSet.Name, aws.StringValue(recordSet.Type)
+		description := fmt.Sprintf("[name='%s', type='%s']", name, recordType)
+
+		if seen[sortKey(name, recordType)] {
+			messages = append(messages, fmt.Sprintf("The request contains an invalid set of changes for a resource record set '%s %s'", recordType, name))
+			continue
+		}
+		seen[sortKey(name, recordType)] = true
+
+		if name != zone.name && !strings.HasSuffix(name, "."+zone.name) {
+			messages = append(messages, fmt.Sprintf("RRSet with DNS name %s is not permitted in zone %s", name, zone.name))
+			continue
+		}
+
+		current := findRecordSet(recordSets, name, recordType)
+
+		switch aws.StringValue(change.Action) {
+		case actionCreate:
+			if current != nil {
+				messages = append(messages, fmt.Sprintf("Tried to create resource record set %s but it already exists", description))
+				continue
+			}
+			if message := conflictingCNAME(recordSets, recordSet); message != "" {
+				messages = append(messages, message)
+				continue
+			}
+			recordSets = append(recordSets, recordSet)
+		case actionUpsert:
+			if message := conflictingCNAME(recordSets, recordSet); message != "" {
+				messages = append(messages, message)
+				continue
+			}
+			if current != nil {
+				recordSets = removeRecordSet(recordSets, current)
+			}
+			recordSets = append(recordSets, recordSet)
+		case actionDelete:
+			if current == nil {
+				messages = append(messages, fmt.Sprintf("Tried to delete resource record set %s but it was not found", description))
+				continue
+			}
+			if !equalRecordSets(current, recordSet) {
+				messages = append(messages, fmt.Sprintf("Tried to delete resource record set %s but the values provided do not match the current values", description))
+				continue
+			}
+			if isApexRecord(zone, current) {
+				messages = append(messages, "A HostedZone must contain at least one NS record for the zone itself.")
+				continue
+			}
+			recordSets = removeRecordSet(recordSets, current)
+		default:
+			messages = append(messages, fmt.Sprintf("Invalid action %q", aws.StringValue(change.Action)))
+		}
+	}
+
+	if len(messages) > 0 {
+		return nil, newError(route53.ErrCodeInvalidChangeBatch, 400, "["+strings.Join(messages, ", ")+"]")
+	}
+
+	zone.recordSets = recordSets
+
+	return &route53.ChangeResourceRecordSetsOutput{ChangeInfo: f.newChangeInfo()}, nil
+}
+
+func (f *FakeRoute53) ChangeResourceRecordSetsWithContext(_ aws.Context, input *route53.ChangeResourceRecordSetsInput, _ ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
+	return f.ChangeResourceRecordSets(input)
+}
+
+// DelayPropagation makes GetChange report every change as PENDING for the given number of calls.
+func (f *FakeRoute53) DelayPropagation(polls int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.propagationPolls = polls
+}
+
+// GetChange reports a change as INSYNC once it was polled more often than
+// configured with DelayPropagation.
+func (f *FakeRoute53) GetChange(input *route53.GetChangeInput) (*route53.GetChangeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("GetChange"); err != nil {
+		return nil, err
+	}
+
+	id := strings.TrimPrefix(aws.StringValue(input.Id), changeIDPrefix)
+	submittedAt, ok := f.changes[id]
+	if !ok {
+		return nil, newError(route53.ErrCodeNoSuchChange, 404, fmt.Sprintf("A change with the specified change ID does not exist: %s", id))
+	}
+
+	status := route53.ChangeStatusInsync
+	if f.changePolls[id] < f.propagationPolls {
+		status = route53.ChangeStatusPending
+	}
+	f.changePolls[id]++
+
+	return &route53.GetChangeOutput{
+		ChangeInfo: &route53.ChangeInfo{
+			Id:          aws.String(changeIDPrefix + id),
+			Status:      aws.String(status),
+			SubmittedAt: aws.Time(submittedAt),
+		},
+	}, nil
+}
+
+func (f *FakeRoute53) GetChangeWithContext(_ aws.Context, input *route53.GetChangeInput, _ ...request.Option) (*route53.GetChangeOutput, error) {
+	return f.GetChange(input)
+}
+
+func (f *FakeRoute53) addHostedZone(name, callerReference, comment string) string {
+	f.sequence++
+	id := fmt.Sprintf("Z%020d", f.sequence)
+	name = canonicalName(name)
+
+	f.zones[id] = &hostedZone{
+		id:              hostedZoneIDPrefix + id,
+		name:            name,
+		callerReference: callerReference,
+		comment:         comment,
+		recordSets: []*route53.ResourceRecordSet{
+			{
+				Name: aws.String(name),
+				Type: aws.String(route53.RRTypeNs),
+				TTL:  aws.Int64(nsTTL),
+				ResourceRecords: []*route53.ResourceRecord{
+					{Value: aws.String(fmt.Sprintf("ns-%d.awsdns-%02d.com.", f.sequence, f.sequence%64))},
+					{Value: aws.String(fmt.Sprintf("ns-%d.awsdns-%02d.net.", f.sequence+512, f.sequence%64))},
+					{Value: aws.String(fmt.Sprintf("ns-%d.awsdns-%02d.org.", f.sequence+1024, f.sequence%64))},
+					{Value: aws.String(fmt.Sprintf("ns-%d.awsdns-%02d.co.uk.", f.sequence+1536, f.sequence%64))},
+				},
+			},
+			{
+				Name: aws.String(name),
+				Type: aws.String(route53.RRTypeSoa),
+				TTL:  aws.Int64(soaTTL),
+				ResourceRecords: []*route53.ResourceRecord{
+					{Value: aws.String(fmt.Sprintf("ns-%d.awsdns-%02d.com. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400", f.sequence, f.sequence%64))},
+				},
+			},
+		},
+	}
+
+	return hostedZoneIDPrefix + id
+}
+
+func (f *FakeRoute53) getZone(id string) (*hostedZone, error) {
+	zone, ok := f.zones[normalizeZoneID(id)]
+	if !ok {
+		return nil, newError(route53.ErrCodeNoSuchHostedZone, 404, fmt.Sprintf("No hosted zone found with ID: %s", normalizeZoneID(id)))
+	}
+	return zone, nil
+}
+
+func (f *FakeRoute53) newChangeInfo() *route53.ChangeInfo {
+	f.sequence++
+	id := fmt.Sprintf("C%020d", f.sequence)
+	f.changes[id] = time.Now()
+
+	return &route53.ChangeInfo{
+		Id:          aws.String(changeIDPrefix + id),
+		Status:      aws.String(route53.ChangeStatusPending),
+		SubmittedAt: aws.Time(f.changes[id]),
+	}
+}
+
+func (z *hostedZone) toAPI() *route53.HostedZone {
+	zone := &route53.HostedZone{
+		Id:                     aws.String(z.id),
+		Name:                   aws.String(z.name),
+		CallerReference:        aws.String(z.callerReference),
+		ResourceRecordSetCount: aws.Int64(int64(len(z.recordSets))),
+		Config:                 &route53.HostedZoneConfig{PrivateZone: aws.Bool(false)},
+	}
+	if z.comment != "" {
+		zone.Config.Comment = aws.String(z.comment)
+	}
+	return zone
+}
+
+func validateBatchLimits(changes []*route53.Change) error {
+	records, characters := 0, 0
+	for _, change := range changes {
+		weight := 1
+		if aws.StringValue(change.Action) == actionUpsert {
+			weight = 2
+		}
+		if change.ResourceRecordSet == nil {
+			continue
+		}
+		for _, rr := range change.ResourceRecordSet.ResourceRecords {
+			records += weight
+			characters += weight * len(aws.StringValue(rr.Value))
+		}
+	}
+
+	if records > MaxChangeBatchRecords {
+		return newError(route53.ErrCodeInvalidChangeBatch, 400, fmt.Sprintf("[Number of records limit of %d exceeded.]", MaxChangeBatchRecords))
+	}
+	if characters > MaxChangeBatchCharacters {
+		return newError(route53.ErrCodeInvalidChangeBatch, 400, fmt.Sprintf("[Number of characters limit of %d exceeded.]", MaxChangeBatchCharacters))
+	}
+	return nil
+}
+
+func conflictingCNAME(recordSets []*route53.ResourceRecordSet, recordSet *route53.ResourceRecordSet) string {
+	for _, current := range recordSets {
+		if *current.Name != *recordSet.Name || *current.Type == *recordSet.Type {
+			continue
+		}
+		if *current.Type == route53.RRTypeCname || *recordSet.Type == route53.RRTypeCname {
+			return fmt.Sprintf("RRSet of type %s with DNS name %s is not permitted because a conflicting RRSet of type %s with the same DNS name already exists in zone", *recordSet.Type, *recordSet.Name, *current.Type)
+		}
+	}
+	return ""
+}
+
+func isApexRecord(zone *hostedZone, recordSet *route53.ResourceRecordSet) bool {
+	return *recordSet.Name == zone.name && (*recordSet.Type == route53.RRTypeNs || *recordSet.Type == route53.RRTypeSoa)
+}
+
+func findRecordSet(recordSets []*route53.ResourceRecordSet, name, recordType string) *route53.ResourceRecordSet {
+	name = canonicalName(name)
+	for _, recordSet := range recordSets {
+		if *recordSet.Name == name && *recordSet.Type == recordType {
+			return recordSet
+		}
+	}
+	return nil
+}
+
+func removeRecordSet(recordSets []*route53.ResourceRecordSet, recordSet *route53.ResourceRecordSet) []*route53.ResourceRecordSet {
+	return slices.DeleteFunc(recordSets, func(current *route53.ResourceRecordSet) bool {
+		return current == recordSet
+	})
+}
+
+func equalRecordSets(a, b *route53.ResourceRecordSet) bool {
+	if aws.Int64Value(a.TTL) != aws.Int64Value(b.TTL) {
+		return false
+	}
+	if (a.AliasTarget == nil) != (b.AliasTarget == nil) {
+		return false
+	}
+	if a.AliasTarget != nil && (canonicalName(aws.StringValue(a.AliasTarget.DNSName)) != canonicalName(aws.StringValue(b.AliasTarget.DNSName)) ||
+		aws.StringValue(a.AliasTarget.HostedZoneId) != aws.StringValue(b.AliasTarget.HostedZoneId)) {
+		return false
+	}
+
+	return slices.Equal(recordValues(a), recordValues(b))
+}
+
+func recordValues(recordSet *route53.ResourceRecordSet) []string {
+	values := make([]string, 0, len(recordSet.ResourceRecords))
+	for _, rr := range recordSet.ResourceRecords {
+		values = append(values, aws.StringValue(rr.Value))
+	}
+	slices.Sort(values)
+	return values
+}
+
+func sortedRecordSets(recordSets []*route53.ResourceRecordSet) []*route53.ResourceRecordSet {
+	sorted := slices.Clone(recordSets)
+	slices.SortFunc(sorted, func(a, b *route53.ResourceRecordSet) int {
+		return strings.Compare(sortKey(*a.Name, *a.Type), sortKey(*b.Name, *b.Type))
+	})
+	return sorted
+}
+
+func sortKey(name, recordType string) string {
+	return reverseLabels(name) + " " + recordType
+}
+
+// reverseLabels turns api.test.example.com. into com.example.test.api which
+// is the order in which Route53 lists hosted zones and record sets.
+func reverseLabels(name string) string {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	slices.Reverse(labels)
+	return strings.Join(labels, ".")
+}
+
+// canonicalName returns the lower case FQDN with the wildcard label escaped
+// as \052, which is how Route53 returns record names.
+func canonicalName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	if strings.HasPrefix(name, "*.") {
+		name = "\\052" + strings.TrimPrefix(name, "*")
+	}
+	return name
+}
+
+func normalizeZoneID(id string) string {
+	return strings.TrimPrefix(id, hostedZoneIDPrefix)
+}
+
+func parseMaxItems(maxItems *string, defaultValue int) (int, error) {
+	if maxItems == nil {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(*maxItems)
+	if err != nil || value < 1 {
+		return 0, newError(route53.ErrCodeInvalidInput, 400, fmt.Sprintf("Invalid value for MaxItems: %s", *maxItems))
+	}
+	return min(value, defaultValue), nil
+}
+
+func copyRecordSet(recordSet *route53.ResourceRecordSet) *route53.ResourceRecordSet {
+	result := &route53.ResourceRecordSet{
+		Name: aws.String(aws.StringValue(recordSet.Name)),
+		Type: aws.String(aws.StringValue(recordSet.Type)),
+	}
+	if recordSet.TTL != nil {
+		result.TTL = aws.Int64(*recordSet.TTL)
+	}
+	if recordSet.SetIdentifier != nil {
+		result.SetIdentifier = aws.String(*recordSet.SetIdentifier)
+	}
+	if recordSet.AliasTarget != nil {
+		result.AliasTarget = &route53.AliasTarget{
+			DNSName:              aws.String(aws.StringValue(recordSet.AliasTarget.DNSName)),
+			HostedZoneId:         aws.String(aws.StringValue(recordSet.AliasTarget.HostedZoneId)),
+			EvaluateTargetHealth: aws.Bool(aws.BoolValue(recordSet.AliasTarget.EvaluateTargetHealth)),
+		}
+	}
+	for _, rr := range recordSet.ResourceRecords {
+		result.ResourceRecords = append(result.ResourceRecords, &route53.ResourceRecord{Value: aws.String(aws.StringValue(rr.Value))})
+	}
+	return result
+}
+
+func copyRecordSets(recordSets []*route53.ResourceRecordSet) []*route53.ResourceRecordSet {
+	result := make([]*route53.ResourceRecordSet, 0, len(recordSets))
+	for _, recordSet := range recordSets {
+		result = append(result, copyRecordSet(recordSet))
+	}
+	return result
+}
+
+func newError(code string, statusCode int, message string) error {
+	return awserr.NewRequestFailure(awserr.New(code, message, nil), statusCode, "fake-request-id")
+}
@@ -0,0 +1,137 @@
+package route53test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+func change(action, name, value string) *route53.Change {
+	return &route53.Change{
+		Action: aws.String(action),
+		ResourceRecordSet: &route53.ResourceRecordSet{
+			Name:            aws.String(name),
+			Type:            aws.String(route53.RRTypeA),
+			TTL:             aws.Int64(300),
+			ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(value)}},
+		},
+	}
+}
+
+func errorCode(err error) string {
+	if awsErr, ok := err.(awserr.Error); ok {
+		return awsErr.Code()
+	}
+	return ""
+}
+
+func Test_FakeRoute53_ChangeResourceRecordSets(t *testing.T) {
+	testCases := []struct {
+		name         string
+		changes      []*route53.Change
+		expectedCode string
+	}{
+		{
+			name:    "case 0: create new record",
+			changes: []*route53.Change{change(actionCreate, "new.example.com", "10.0.0.2")},
+		},
+		{
+			name:         "case 1: create existing record",
+			changes:      []*route53.Change{change(actionCreate, "api.example.com", "10.0.0.2")},
+			expectedCode: route53.ErrCodeInvalidChangeBatch,
+		},
+		{
+			name:    "case 2: upsert existing record",
+			changes: []*route53.Change{change(actionUpsert, "api.example.com", "10.0.0.2")},
+		},
+		{
+			name:         "case 3: delete record with different value",
+			changes:      []*route53.Change{change(actionDelete, "api.example.com", "10.0.0.2")},
+			expectedCode: route53.ErrCodeInvalidChangeBatch,
+		},
+		{
+			name:         "case 4: delete missing record",
+			changes:      []*route53.Change{change(actionDelete, "missing.example.com", "10.0.0.2")},
+			expectedCode: route53.ErrCodeInvalidChangeBatch,
+		},
+		{
+			name: "case 5: failing change rolls back the whole batch",
+			changes: []*route53.Change{
+				change(actionDelete, "api.example.com", "10.0.0.1"),
+				change(actionCreate, "api.other.com", "10.0.0.2"),
+			},
+			expectedCode: route53.ErrCodeInvalidChangeBatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := NewFakeRoute53()
+			zoneID := fake.AddHostedZone("example.com")
+			fake.AddRecordSet(zoneID, change(actionCreate, "api.example.com", "10.0.0.1").ResourceRecordSet)
+
+			_, err := fake.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+				HostedZoneId: aws.String(zoneID),
+				ChangeBatch:  &route53.ChangeBatch{Changes: tc.changes},
+			})
+			if code := errorCode(err); code != tc.expectedCode {
+				t.Fatalf("expected error code %q, got %v", tc.expectedCode, err)
+			}
+			if tc.expectedCode != "" && fake.RecordSet(zoneID, "api.example.com", route53.RRTypeA) == nil {
+				t.Fatalf("expected failed batch to leave the zone untouched")
+			}
+		})
+	}
+}
+
+func Test_FakeRoute53_Pagination(t *testing.T) {
+	fake := NewFakeRoute53()
+	zoneID := fake.AddHostedZone("example.com")
+	for i := range 650 {
+		fake.AddRecordSet(zoneID, change(actionCreate, fmt.Sprintf("r%03d.example.com", i), "10.0.0.1").ResourceRecordSet)
+	}
+
+	var pages, records int
+	err := fake.ListResourceRecordSetsPages(&route53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)},
+		func(output *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+			pages++
+			records += len(output.ResourceRecordSets)
+			return true
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 650 records plus the NS and SOA records of the apex.
+	if pages != 3 || records != 652 {
+		t.Fatalf("expected 652 records in 3 pages, got %d records in %d pages", records, pages)
+	}
+}
+
+func Test_FakeRoute53_DeleteHostedZone(t *testing.T) {
+	fake := NewFakeRoute53()
+	zoneID := fake.AddHostedZone("example.com")
+	fake.AddRecordSet(zoneID, change(actionCreate, "api.example.com", "10.0.0.1").ResourceRecordSet)
+
+	_, err := fake.DeleteHostedZone(&route53.DeleteHostedZoneInput{Id: aws.String(zoneID)})
+	if code := errorCode(err); code != route53.ErrCodeHostedZoneNotEmpty {
+		t.Fatalf("expected %s, got %v", route53.ErrCodeHostedZoneNotEmpty, err)
+	}
+
+	_, err = fake.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch:  &route53.ChangeBatch{Changes: []*route53.Change{change(actionDelete, "api.example.com", "10.0.0.1")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err = fake.DeleteHostedZone(&route53.DeleteHostedZoneInput{Id: aws.String(zoneID)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = fake.DeleteHostedZone(&route53.DeleteHostedZoneInput{Id: aws.String(zoneID)}); errorCode(err) != route53.ErrCodeNoSuchHostedZone {
+		t.Fatalf("expected %s, got %v", route53.ErrCodeNoSuchHostedZone, err)
+	}
+}
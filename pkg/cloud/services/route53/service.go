@@ -4,18 +4,39 @@ import (
 	"github.com/aws/aws-sdk-go/service/route53/route53iface"
 
 	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/services/dns"
 )
 
-// Service holds a collection of interfaces.
+// ProviderName is the name of the Route53 DNS provider.
+const ProviderName = "route53"
+
+// Config holds the settings of the Route53 DNS provider.
+type Config struct {
+	// WaitForChangePropagation tracks the submitted changes until Route53
+	// reports them as INSYNC, see PendingChanges.
+	WaitForChangePropagation bool
+	// DryRun only plans the changes of the hosted zones and records instead
+	// of applying them, see PlannedChanges.
+	DryRun bool
+}
+
+// Service holds a collection of interfaces. Route53Client manages the cluster
+// hosted zone and BaseRoute53Client the delegation in the base hosted zone,
+// which may live in another AWS account.
 type Service struct {
-	scope         scope.Route53Scope
-	Route53Client route53iface.Route53API
+	scope             scope.Route53Scope
+	config            Config
+	plan              dns.Plan
+	Route53Client     route53iface.Route53API
+	BaseRoute53Client route53iface.Route53API
 }
 
-// NewService returns a new service given the route53 api client.
-func NewService(clusterScope scope.Route53Scope) *Service {
+// NewService returns a new Route53 DNS provider for the given cluster scope.
+func NewService(clusterScope scope.Route53Scope, config Config) *Service {
 	return &Service{
-		scope:         clusterScope,
-		Route53Client: scope.NewRoute53Client(clusterScope, clusterScope.Cluster()),
+		scope:             clusterScope,
+		config:            config,
+		Route53Client:     scope.NewRoute53Client(clusterScope.Session(), clusterScope.Cluster()),
+		BaseRoute53Client: scope.NewRoute53Client(clusterScope.BaseZoneSession(), clusterScope.Cluster()),
 	}
 }
@@ -0,0 +1,19 @@
+package cloud
+
+// RecordTTLs are the TTLs in seconds of the classes of records published for
+// a cluster.
+type RecordTTLs struct {
+	// Delegation is the TTL of the NS records which delegate the cluster zone.
+	Delegation int64
+	// API is the TTL of the api record.
+	API int64
+	// Bastion is the TTL of the bastion1 record.
+	Bastion int64
+	// Ingress is the TTL of the records of the ingress controller Services.
+	Ingress int64
+	// Wildcard is the TTL of the wildcard record of the cluster domain.
+	Wildcard int64
+	// Gateway is the TTL of the records of the gateway Services and the
+	// Gateway API Gateways.
+	Gateway int64
+}
@@ -5,4 +5,107 @@ const (
 	DNSFinalizerNameNew = "dns-operator-route53.finalizers.giantswarm.io"
 
 	AnnotationWildcardCNAMETarget = "network.giantswarm.io/wildcard-cname-target"
+	// AnnotationWildcardIngressService selects the ingress Service of the
+	// workload cluster which backs the wildcard record of a Cluster.
+	AnnotationWildcardIngressService = "dns.giantswarm.io/wildcard-ingress-service"
+
+	// AnnotationIngressNamespaces overrides the comma separated namespaces of
+	// the ingress controller Services of a Cluster.
+	AnnotationIngressNamespaces = "dns.giantswarm.io/ingress-namespaces"
+	// AnnotationIngressSelector overrides the label selector of the ingress controller Services of a Cluster.
+	AnnotationIngressSelector = "dns.giantswarm.io/ingress-selector"
+	// AnnotationGatewayNamespaces overrides the comma separated namespaces of
+	// the gateway Services of a Cluster.
+	AnnotationGatewayNamespaces = "dns.giantswarm.io/gateway-namespaces"
+	// AnnotationGatewaySelector overrides the label selector of the gateway Services of a Cluster.
+	AnnotationGatewaySelector = "dns.giantswarm.io/gateway-selector"
+
+	// AnnotationTTLDelegation overrides the TTL in seconds of the NS records
+	// which delegate the zone of a Cluster.
+	AnnotationTTLDelegation = "dns.giantswarm.io/ttl-delegation"
+	// AnnotationTTLAPI overrides the TTL in seconds of the api record of a Cluster.
+	AnnotationTTLAPI = "dns.giantswarm.io/ttl-api"
+	// AnnotationTTLBastion overrides the TTL in seconds of the bastion record of a Cluster.
+	AnnotationTTLBastion = "dns.giantswarm.io/ttl-bastion"
+	// AnnotationTTLIngress overrides the TTL in seconds of the ingress records of a Cluster.
+	AnnotationTTLIngress = "dns.giantswarm.io/ttl-ingress"
+	// AnnotationTTLWildcard overrides the TTL in seconds of the wildcard record of a Cluster.
+	AnnotationTTLWildcard = "dns.giantswarm.io/ttl-wildcard"
+	// AnnotationTTLGateway overrides the TTL in seconds of the gateway records of a Cluster.
+	AnnotationTTLGateway = "dns.giantswarm.io/ttl-gateway"
+
+	// AnnotationDNSDisabled set to "true" on a Cluster stops the operator from
+	// managing its DNS zone and records.
+	AnnotationDNSDisabled = "dns.giantswarm.io/disabled"
+	// AnnotationDNSDryRun set to "true" on a Cluster makes the operator only plan
+	// the changes of its DNS zone and records instead of applying them.
+	AnnotationDNSDryRun = "dns.giantswarm.io/dry-run"
+
+	// AnnotationBaseDomain selects the base domain of a Cluster from the base
+	// domains allowed by the operator. It can be set as annotation on the
+	// Cluster or as label on its namespace.
+	AnnotationBaseDomain = "dns.giantswarm.io/base-domain"
+	// AnnotationProvisionedBaseDomain records the base domain in which the zone
+	// of a Cluster was created. It is set by the operator and the base domain
+	// of a Cluster can't be changed afterwards.
+	AnnotationProvisionedBaseDomain = "dns.giantswarm.io/provisioned-base-domain"
+	// AnnotationProvisionedClusterDomain records the domain of the zone of a
+	// Cluster. It is set by the operator and takes precedence over the cluster
+	// domain template.
+	AnnotationProvisionedClusterDomain = "dns.giantswarm.io/provisioned-cluster-domain"
+
+	// AnnotationAWSRoleARN selects the AWS role which is assumed for the cluster
+	// hosted zone. It can be set on the Cluster or on its namespace.
+	AnnotationAWSRoleARN = "dns.giantswarm.io/aws-role-arn"
+	// AnnotationAWSExternalID sets the external ID used to assume the role of AnnotationAWSRoleARN.
+	AnnotationAWSExternalID = "dns.giantswarm.io/aws-external-id"
+	// AnnotationAWSClusterRoleIdentity references an AWSClusterRoleIdentity whose
+	// role is assumed for the cluster hosted zone.
+	AnnotationAWSClusterRoleIdentity = "dns.giantswarm.io/aws-cluster-role-identity"
+)
+
+const (
+	// DNSReadyCondition documents whether the DNS zone and the records of a cluster are reconciled.
+	DNSReadyCondition = "DNSReady"
+
+	// DNSReadyReason is used when the DNS zone and all records are reconciled.
+	DNSReadyReason = "DNSReady"
+	// HostedZoneCreatingReason is used when the cluster zone does not exist yet or could not be created.
+	HostedZoneCreatingReason = "HostedZoneCreating"
+	// DelegationFailedReason is used when the cluster zone could not be delegated from the base zone.
+	DelegationFailedReason = "DelegationFailed"
+	// APIEndpointMissingReason is used when the cluster does not have a control plane endpoint yet.
+	APIEndpointMissingReason = "APIEndpointMissing"
+	// IngressNotReadyReason is used when the ingress service does not have a load balancer address yet.
+	IngressNotReadyReason = "IngressNotReady"
+	// RecordNotOwnedReason is used when records of the cluster are owned by someone else.
+	RecordNotOwnedReason = "RecordNotOwned"
+	// DryRunReason is used when changes were planned but not applied in dry-run mode.
+	DryRunReason = "DryRun"
+	// PermissionDeniedReason is used when the DNS provider rejected the operator credentials.
+	PermissionDeniedReason = "PermissionDenied"
+	// InvalidConfigReason is used when the annotations of the cluster are invalid.
+	InvalidConfigReason = "InvalidConfig"
+	// ReconciliationFailedReason is used for all other errors.
+	ReconciliationFailedReason = "ReconciliationFailed"
+)
+
+const (
+	// DNSPropagatedCondition documents whether the submitted DNS changes of a
+	// cluster are served by all authoritative name servers.
+	DNSPropagatedCondition = "DNSPropagated"
+
+	// ChangesInSyncReason is used when all submitted DNS changes are propagated.
+	ChangesInSyncReason = "ChangesInSync"
+	// ChangesPendingReason is used while submitted DNS changes are propagating.
+	ChangesPendingReason = "ChangesPending"
+)
+
+const (
+	// RecordDeletedReason is the reason of the event emitted for every stale
+	// record of a cluster which is deleted, e.g. of a removed gateway Service.
+	RecordDeletedReason = "RecordDeleted"
+	// ZoneKeptReason is the reason of the event emitted when the zone of a
+	// deleted cluster is kept because it contains records owned by someone else.
+	ZoneKeptReason = "ZoneKept"
 )
@@ -0,0 +1,23 @@
+package remote
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var cacheNotSyncedError = &microerror.Error{
+	Kind: "cacheNotSyncedError",
+}
+
+// IsCacheNotSynced asserts cacheNotSyncedError.
+func IsCacheNotSynced(err error) bool {
+	return microerror.Cause(err) == cacheNotSyncedError
+}
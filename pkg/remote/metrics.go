@@ -0,0 +1,52 @@
+package remote
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricWorkloadClusterSubsystem = "workload_cluster"
+	metricClusterNamespaceLabel    = "cluster_namespace"
+	metricClusterNameLabel         = "cluster_name"
+	metricReasonLabel              = "reason"
+)
+
+var (
+	trackedClusters = prometheus.NewGauge(prometheus.GaugeOpts{
+		Subsystem: metricWorkloadClusterSubsystem,
+		Name:      "tracked_clusters",
+		Help:      "Number of workload clusters with a cached client",
+	})
+	clusterHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: metricWorkloadClusterSubsystem,
+		Name:      "healthy",
+		Help:      "Whether the API of the workload cluster answered the last health check",
+	}, []string{metricClusterNamespaceLabel, metricClusterNameLabel})
+	healthCheckFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: metricWorkloadClusterSubsystem,
+		Name:      "health_check_failures_total",
+		Help:      "Total number of failed health checks of the workload cluster API",
+	}, []string{metricClusterNamespaceLabel, metricClusterNameLabel})
+	clientInvalidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: metricWorkloadClusterSubsystem,
+		Name:      "client_invalidations_total",
+		Help:      "Total number of invalidated workload cluster clients",
+	}, []string{metricClusterNamespaceLabel, metricClusterNameLabel, metricReasonLabel})
+)
+
+func init() {
+	metrics.Registry.MustRegister(trackedClusters)
+	metrics.Registry.MustRegister(clusterHealthy)
+	metrics.Registry.MustRegister(healthCheckFailures)
+	metrics.Registry.MustRegister(clientInvalidations)
+}
+
+// deleteClusterMetrics removes the series of a workload cluster which is no longer tracked.
+func deleteClusterMetrics(cluster client.ObjectKey) {
+	labels := prometheus.Labels{metricClusterNamespaceLabel: cluster.Namespace, metricClusterNameLabel: cluster.Name}
+	clusterHealthy.DeletePartialMatch(labels)
+	healthCheckFailures.DeletePartialMatch(labels)
+	clientInvalidations.DeletePartialMatch(labels)
+}
@@ -0,0 +1,16 @@
+package remote
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Scheme contains the types which are read from the workload clusters: the
+// Kubernetes types and the Gateway API types.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(Scheme)
+	_ = gatewayv1.Install(Scheme)
+}
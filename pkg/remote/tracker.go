@@ -0,0 +1,543 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dns-operator-route53/pkg/cloud/scope"
+)
+
+const (
+	// cacheSyncTimeout limits how long the initial list of the Services of a
+	// workload cluster may take.
+	cacheSyncTimeout = 30 * time.Second
+
+	// healthCheckInterval is the interval in which the API of every tracked
+	// workload cluster is probed.
+	healthCheckInterval = 30 * time.Second
+	// healthCheckTimeout limits a single probe.
+	healthCheckTimeout = 10 * time.Second
+	// healthCheckFailureThreshold is the number of consecutive failed probes
+	// after which the client of a workload cluster is invalidated.
+	healthCheckFailureThreshold = 3
+
+	invalidationReasonKubeconfigChanged = "kubeconfig_changed"
+	invalidationReasonNamespacesChanged = "namespaces_changed"
+	invalidationReasonUnhealthy         = "unhealthy"
+)
+
+// ClusterTrackerConfig configures the ClusterTracker.
+type ClusterTrackerConfig struct {
+	// SecretReader reads the kubeconfig secrets of the workload clusters from
+	// the management cluster. It should be backed by a cache, because the
+	// secret is read on every GetClient call to detect kubeconfig changes.
+	SecretReader client.Reader
+	// Namespaces of the workload clusters in which the Services are watched.
+	Namespaces []string
+	// ClusterNamespaces optionally returns the namespaces of a single workload
+	// cluster, e.g. from its annotations. Namespaces are used if it returns none.
+	ClusterNamespaces func(cluster *capi.Cluster) []string
+}
+
+// ClusterTracker keeps one client and one cache of the Services and Gateway
+// API resources of every workload cluster. It emits an event for the owning
+// Cluster whenever a LoadBalancer Service, a Gateway or an HTTPRoute changes,
+// so DNS records are updated without polling the workload clusters. Clients
+// are rebuilt when the kubeconfig secret of the cluster changes or its API
+// stops answering.
+type ClusterTracker struct {
+	namespaces        []string
+	clusterNamespaces func(cluster *capi.Cluster) []string
+	secretReader      client.Reader
+	// queue is the work queue of the controller the Cluster events are added
+	// to. It is set when the controller starts the Source.
+	queue atomic.Pointer[workqueue.TypedRateLimitingInterface[reconcile.Request]]
+
+	// newCache, newHealthCheck and healthCheckInterval are replaced in tests.
+	newCache            func(config *rest.Config, opts cache.Options) (cache.Cache, error)
+	newHealthCheck      func(config *rest.Config) (func(ctx context.Context) error, error)
+	healthCheckInterval time.Duration
+
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	clusters map[client.ObjectKey]*trackedCluster
+	// locks serialize building the client of a workload cluster.
+	locks map[client.ObjectKey]*clusterLock
+}
+
+// clusterLock serializes building the client of a workload cluster. It is
+// marked as stopped when the cluster is stopped, so a client which is built
+// meanwhile is not tracked.
+type clusterLock struct {
+	sync.Mutex
+	// stopped is guarded by ClusterTracker.mu.
+	stopped bool
+}
+
+type trackedCluster struct {
+	client client.Client
+	// ctx is done when the cache is stopped.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// secretResourceVersion is the resource version of the kubeconfig secret
+	// the client was built from.
+	secretResourceVersion string
+	// namespaces are the namespaces whose Services are cached.
+	namespaces []string
+}
+
+// NewClusterTracker returns a ClusterTracker. It has to be added to the
+// manager, which stops all caches on shutdown.
+func NewClusterTracker(config ClusterTrackerConfig) (*ClusterTracker, error) {
+	if config.SecretReader == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SecretReader must not be empty", config)
+	}
+	if len(config.Namespaces) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Namespaces must not be empty", config)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &ClusterTracker{
+		namespaces:        config.Namespaces,
+		clusterNamespaces: config.ClusterNamespaces,
+		secretReader:      config.SecretReader,
+
+		newCache:            cache.New,
+		newHealthCheck:      newHealthCheck,
+		healthCheckInterval: healthCheckInterval,
+
+		ctx:      ctx,
+		cancel:   cancel,
+		clusters: map[client.ObjectKey]*trackedCluster{},
+		locks:    map[client.ObjectKey]*clusterLock{},
+	}
+
+	return t, nil
+}
+
+// GetClient returns a client for the workload cluster whose reads are served
+// from the cache. The client is built on the first call for a cluster and
+// rebuilt if the kubeconfig secret or the watched namespaces changed since.
+// Clients are built outside of t.mu, so a workload cluster whose cache does
+// not sync only blocks the callers for the same cluster.
+func (t *ClusterTracker) GetClient(ctx context.Context, cluster *capi.Cluster) (client.Client, error) {
+	key := client.ObjectKeyFromObject(cluster)
+
+	lock := t.clusterLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	secret, err := t.kubeconfigSecret(ctx, key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	watchedNamespaces := t.namespacesOf(cluster)
+
+	t.mu.Lock()
+	if tc, ok := t.clusters[key]; ok {
+		switch {
+		case tc.secretResourceVersion != secret.ResourceVersion:
+			log.FromContext(ctx).Info("Kubeconfig of the workload cluster changed, rebuilding client", "cluster", key)
+			t.invalidate(key, tc, invalidationReasonKubeconfigChanged)
+		case !slices.Equal(tc.namespaces, watchedNamespaces):
+			log.FromContext(ctx).Info("Watched namespaces of the workload cluster changed, rebuilding client", "cluster", key, "namespaces", watchedNamespaces)
+			t.invalidate(key, tc, invalidationReasonNamespacesChanged)
+		default:
+			t.mu.Unlock()
+			return tc.client, nil
+		}
+	}
+	t.mu.Unlock()
+
+	tc, healthCheck, err := t.newTrackedCluster(ctx, key, secret, watchedNamespaces)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ctx.Err() != nil || lock.stopped {
+		tc.cancel()
+		return nil, microerror.Maskf(cacheNotSyncedError, "tracking of workload cluster %s was stopped", key)
+	}
+
+	t.clusters[key] = tc
+	trackedClusters.Set(float64(len(t.clusters)))
+	clusterHealthy.WithLabelValues(key.Namespace, key.Name).Set(1)
+
+	go t.runHealthChecks(tc.ctx, key, tc, healthCheck)
+
+	log.FromContext(ctx).Info("Started tracking workload cluster", "cluster", key)
+
+	return tc.client, nil
+}
+
+// clusterLock returns the lock which serializes building the client of the
+// workload cluster.
+func (t *ClusterTracker) clusterLock(key client.ObjectKey) *clusterLock {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lock, ok := t.locks[key]
+	if !ok {
+		lock = &clusterLock{}
+		t.locks[key] = lock
+	}
+	return lock
+}
+
+// newTrackedCluster builds and syncs the cache and the client of the workload
+// cluster. It must not be called with t.mu held, waiting for the cache to sync
+// can take up to cacheSyncTimeout.
+func (t *ClusterTracker) newTrackedCluster(ctx context.Context, key client.ObjectKey, secret *corev1.Secret, watchedNamespaces []string) (*trackedCluster, func(ctx context.Context) error, error) {
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(secret.Data[scope.KubeConfigSecretKey])
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	restConfig.UserAgent = "dns-operator-route53"
+
+	healthCheck, err := t.newHealthCheck(restConfig)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	namespaces := map[string]cache.Config{}
+	for _, namespace := range watchedNamespaces {
+		namespaces[namespace] = cache.Config{}
+	}
+
+	// Gateways and their routes can live in any namespace.
+	allNamespaces := map[string]cache.Config{cache.AllNamespaces: {}}
+	clusterCache, err := t.newCache(restConfig, cache.Options{
+		Scheme:            Scheme,
+		DefaultNamespaces: namespaces,
+		ByObject: map[client.Object]cache.ByObject{
+			&gatewayv1.Gateway{}:   {Namespaces: allNamespaces},
+			&gatewayv1.HTTPRoute{}: {Namespaces: allNamespaces},
+		},
+	})
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	informer, err := clusterCache.GetInformer(ctx, &corev1.Service{})
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	if _, err := informer.AddEventHandler(t.serviceEventHandler(key)); err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	for _, obj := range []client.Object{&gatewayv1.Gateway{}, &gatewayv1.HTTPRoute{}} {
+		informer, err := clusterCache.GetInformer(ctx, obj)
+		if meta.IsNoMatchError(err) {
+			// The Gateway API CRDs are not installed in the workload cluster.
+			log.FromContext(ctx).Info("Not watching Gateway API resources, the CRDs are not installed", "cluster", key, "kind", fmt.Sprintf("%T", obj))
+			continue
+		} else if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+		if _, err := informer.AddEventHandler(t.gatewayAPIEventHandler(key)); err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+	}
+
+	cacheCtx, cancel := context.WithCancel(t.ctx)
+	go func() {
+		if err := clusterCache.Start(cacheCtx); err != nil {
+			log.FromContext(ctx).Error(err, "workload cluster cache stopped", "cluster", key)
+		}
+	}()
+
+	syncCtx, syncCancel := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer syncCancel()
+	if !clusterCache.WaitForCacheSync(syncCtx) {
+		cancel()
+		clusterHealthy.WithLabelValues(key.Namespace, key.Name).Set(0)
+		return nil, nil, microerror.Maskf(cacheNotSyncedError, "failed to sync the Services of workload cluster %s", key)
+	}
+
+	k8sClient, err := client.New(restConfig, client.Options{
+		Scheme: Scheme,
+		Cache:  &client.CacheOptions{Reader: clusterCache},
+	})
+	if err != nil {
+		cancel()
+		return nil, nil, microerror.Mask(err)
+	}
+
+	tc := &trackedCluster{
+		client:                k8sClient,
+		ctx:                   cacheCtx,
+		cancel:                cancel,
+		secretResourceVersion: secret.ResourceVersion,
+		namespaces:            watchedNamespaces,
+	}
+
+	return tc, healthCheck, nil
+}
+
+// namespacesOf returns the sorted namespaces in which the Services of the
+// workload cluster are watched.
+func (t *ClusterTracker) namespacesOf(cluster *capi.Cluster) []string {
+	var namespaces []string
+	if t.clusterNamespaces != nil {
+		namespaces = t.clusterNamespaces(cluster)
+	}
+	if len(namespaces) == 0 {
+		namespaces = t.namespaces
+	}
+	namespaces = slices.Clone(namespaces)
+	slices.Sort(namespaces)
+	return slices.Compact(namespaces)
+}
+
+// Stop stops the cache and the health checks of the given workload cluster.
+// It is a no-op if the cluster is not tracked.
+func (t *ClusterTracker) Stop(cluster client.ObjectKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tc, ok := t.clusters[cluster]; ok {
+		tc.cancel()
+		delete(t.clusters, cluster)
+		trackedClusters.Set(float64(len(t.clusters)))
+	}
+	if lock, ok := t.locks[cluster]; ok {
+		lock.stopped = true
+		delete(t.locks, cluster)
+	}
+	deleteClusterMetrics(cluster)
+}
+
+// Tracking returns true if the given workload cluster is tracked.
+func (t *ClusterTracker) Tracking(cluster client.ObjectKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.clusters[cluster]
+	return ok
+}
+
+// Source returns the source of the Cluster events. It must only be used by a single controller.
+func (t *ClusterTracker) Source() source.Source {
+	return source.Func(func(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+		t.queue.Store(&queue)
+		return nil
+	})
+}
+
+// Start implements manager.Runnable. It blocks until the manager stops and
+// then stops all caches.
+func (t *ClusterTracker) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cancel()
+	t.clusters = map[client.ObjectKey]*trackedCluster{}
+	trackedClusters.Set(0)
+
+	return nil
+}
+
+// invalidate stops the cache of the workload cluster if tc is still its
+// tracked client. t.mu must be held by the caller.
+func (t *ClusterTracker) invalidate(key client.ObjectKey, tc *trackedCluster, reason string) {
+	if t.clusters[key] != tc {
+		return
+	}
+
+	tc.cancel()
+	delete(t.clusters, key)
+	trackedClusters.Set(float64(len(t.clusters)))
+	clientInvalidations.WithLabelValues(key.Namespace, key.Name, reason).Inc()
+}
+
+// runHealthChecks probes the API of the workload cluster until ctx is done.
+// The client is invalidated and the Cluster is enqueued after
+// healthCheckFailureThreshold consecutive failures, so the next reconciliation
+// builds a new client.
+func (t *ClusterTracker) runHealthChecks(ctx context.Context, key client.ObjectKey, tc *trackedCluster, healthCheck func(ctx context.Context) error) {
+	ticker := time.NewTicker(t.healthCheckInterval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		err := healthCheck(checkCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			failures = 0
+			clusterHealthy.WithLabelValues(key.Namespace, key.Name).Set(1)
+			continue
+		}
+
+		failures++
+		healthCheckFailures.WithLabelValues(key.Namespace, key.Name).Inc()
+		log.FromContext(ctx).Error(err, "workload cluster health check failed", "cluster", key, "failures", failures)
+
+		if failures >= healthCheckFailureThreshold {
+			clusterHealthy.WithLabelValues(key.Namespace, key.Name).Set(0)
+
+			t.mu.Lock()
+			t.invalidate(key, tc, invalidationReasonUnhealthy)
+			t.mu.Unlock()
+
+			t.enqueue(key)
+			return
+		}
+	}
+}
+
+func (t *ClusterTracker) kubeconfigSecret(ctx context.Context, cluster client.ObjectKey) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{
+		Namespace: cluster.Namespace,
+		Name:      cluster.Name + scope.KubeConfigSecretSuffix,
+	}
+	if err := t.secretReader.Get(ctx, key, secret); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return secret, nil
+}
+
+// newHealthCheck returns a probe of the version endpoint of the workload cluster API.
+func newHealthCheck(config *rest.Config) (func(ctx context.Context) error, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return func(ctx context.Context) error {
+		return discoveryClient.RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+	}, nil
+}
+
+func (t *ClusterTracker) serviceEventHandler(cluster client.ObjectKey) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if isLoadBalancer(obj) {
+				t.enqueue(cluster)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			if (isLoadBalancer(oldObj) || isLoadBalancer(newObj)) && serviceChanged(oldObj, newObj) {
+				t.enqueue(cluster)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if isLoadBalancer(obj) {
+				t.enqueue(cluster)
+			}
+		},
+	}
+}
+
+func (t *ClusterTracker) gatewayAPIEventHandler(cluster client.ObjectKey) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			t.enqueue(cluster)
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			if gatewayAPIObjectChanged(oldObj, newObj) {
+				t.enqueue(cluster)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			t.enqueue(cluster)
+		},
+	}
+}
+
+// enqueue adds the Cluster to the work queue of the controller. Adding never
+// blocks the informers and events of the same Cluster are coalesced until it
+// is reconciled. Events before the controller started are dropped, because
+// the controller reconciles all Clusters on start.
+func (t *ClusterTracker) enqueue(cluster client.ObjectKey) {
+	queue := t.queue.Load()
+	if queue == nil {
+		return
+	}
+	(*queue).Add(reconcile.Request{NamespacedName: cluster})
+}
+
+func isLoadBalancer(obj any) bool {
+	svc, ok := obj.(*corev1.Service)
+	return ok && svc.Spec.Type == corev1.ServiceTypeLoadBalancer
+}
+
+// serviceChanged returns true if a field which is used for the DNS records changed.
+func serviceChanged(oldObj, newObj any) bool {
+	oldSvc, ok := oldObj.(*corev1.Service)
+	if !ok {
+		return true
+	}
+	newSvc, ok := newObj.(*corev1.Service)
+	if !ok {
+		return true
+	}
+
+	return oldSvc.Spec.Type != newSvc.Spec.Type ||
+		!reflect.DeepEqual(oldSvc.Labels, newSvc.Labels) ||
+		!reflect.DeepEqual(oldSvc.Annotations, newSvc.Annotations) ||
+		!reflect.DeepEqual(oldSvc.Status.LoadBalancer, newSvc.Status.LoadBalancer)
+}
+
+// gatewayAPIObjectChanged returns true if the spec of a Gateway or HTTPRoute,
+// the addresses of a Gateway or the parents which accepted an HTTPRoute changed.
+func gatewayAPIObjectChanged(oldObj, newObj any) bool {
+	switch oldObj := oldObj.(type) {
+	case *gatewayv1.Gateway:
+		newObj, ok := newObj.(*gatewayv1.Gateway)
+		return !ok || oldObj.Generation != newObj.Generation ||
+			!reflect.DeepEqual(oldObj.Status.Addresses, newObj.Status.Addresses)
+	case *gatewayv1.HTTPRoute:
+		newObj, ok := newObj.(*gatewayv1.HTTPRoute)
+		return !ok || oldObj.Generation != newObj.Generation ||
+			!reflect.DeepEqual(oldObj.Status.Parents, newObj.Status.Parents)
+	default:
+		return true
+	}
+}
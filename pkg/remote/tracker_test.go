@@ -0,0 +1,504 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: %s
+`
+
+func kubeconfigSecret(token string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "org-giantswarm", Name: "test-kubeconfig"},
+		Data:       map[string][]byte{"value": fmt.Appendf(nil, testKubeconfig, token)},
+	}
+}
+
+type testTracker struct {
+	*ClusterTracker
+	secrets client.Client
+	queue   workqueue.TypedRateLimitingInterface[reconcile.Request]
+	caches  int
+	healthy atomic.Bool
+}
+
+func newTestTracker(t *testing.T, synced bool) *testTracker {
+	secrets := fake.NewClientBuilder().WithObjects(kubeconfigSecret("token")).Build()
+
+	clusterTracker, err := NewClusterTracker(ClusterTrackerConfig{
+		SecretReader: secrets,
+		Namespaces:   []string{"kube-system"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(clusterTracker.cancel)
+
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	t.Cleanup(queue.ShutDown)
+	if err := clusterTracker.Source().Start(context.Background(), queue); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tt := &testTracker{ClusterTracker: clusterTracker, secrets: secrets, queue: queue}
+	tt.healthy.Store(true)
+
+	tt.newCache = func(*rest.Config, cache.Options) (cache.Cache, error) {
+		tt.caches++
+		return &informertest.FakeInformers{Scheme: Scheme, Synced: &synced}, nil
+	}
+	tt.newHealthCheck = func(*rest.Config) (func(context.Context) error, error) {
+		return func(context.Context) error {
+			if !tt.healthy.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		}, nil
+	}
+
+	return tt
+}
+
+// waitForRequest returns the next request of the work queue or false if
+// none is added within the timeout.
+func (tt *testTracker) waitForRequest(timeout time.Duration) (reconcile.Request, bool) {
+	deadline := time.Now().Add(timeout)
+	for tt.queue.Len() == 0 {
+		if time.Now().After(deadline) {
+			return reconcile.Request{}, false
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	req, _ := tt.queue.Get()
+	tt.queue.Done(req)
+	return req, true
+}
+
+func testCluster() *capi.Cluster {
+	return &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "org-giantswarm", Name: "test"}}
+}
+
+func service(serviceType corev1.ServiceType, ip string) *corev1.Service {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "ingress-nginx-controller"},
+		Spec:       corev1.ServiceSpec{Type: serviceType},
+	}
+	if ip != "" {
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: ip}}
+	}
+	return svc
+}
+
+func gateway(generation int64, address string) *gatewayv1.Gateway {
+	gw := &gatewayv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gateway", Generation: generation},
+	}
+	if address != "" {
+		gw.Status.Addresses = []gatewayv1.GatewayStatusAddress{{Value: address}}
+	}
+	return gw
+}
+
+func Test_ClusterTracker_Events(t *testing.T) {
+	testCases := []struct {
+		name        string
+		object      client.Object
+		event       func(informer *controllertest.FakeInformer)
+		expectEvent bool
+	}{
+		{
+			name: "case 0: load balancer service added",
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Add(service(corev1.ServiceTypeLoadBalancer, ""))
+			},
+			expectEvent: true,
+		},
+		{
+			name: "case 1: cluster IP service added",
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Add(service(corev1.ServiceTypeClusterIP, ""))
+			},
+		},
+		{
+			name: "case 2: load balancer address assigned",
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Update(service(corev1.ServiceTypeLoadBalancer, ""), service(corev1.ServiceTypeLoadBalancer, "10.0.0.1"))
+			},
+			expectEvent: true,
+		},
+		{
+			name: "case 3: resync without changes",
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Update(service(corev1.ServiceTypeLoadBalancer, "10.0.0.1"), service(corev1.ServiceTypeLoadBalancer, "10.0.0.1"))
+			},
+		},
+		{
+			name: "case 4: load balancer service changed to cluster IP",
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Update(service(corev1.ServiceTypeLoadBalancer, "10.0.0.1"), service(corev1.ServiceTypeClusterIP, ""))
+			},
+			expectEvent: true,
+		},
+		{
+			name: "case 5: load balancer service deleted",
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Delete(service(corev1.ServiceTypeLoadBalancer, "10.0.0.1"))
+			},
+			expectEvent: true,
+		},
+		{
+			name:   "case 6: gateway added",
+			object: &gatewayv1.Gateway{},
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Add(gateway(1, ""))
+			},
+			expectEvent: true,
+		},
+		{
+			name:   "case 7: gateway address assigned",
+			object: &gatewayv1.Gateway{},
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Update(gateway(1, ""), gateway(1, "10.0.0.1"))
+			},
+			expectEvent: true,
+		},
+		{
+			name:   "case 8: gateway listeners changed",
+			object: &gatewayv1.Gateway{},
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Update(gateway(1, "10.0.0.1"), gateway(2, "10.0.0.1"))
+			},
+			expectEvent: true,
+		},
+		{
+			name:   "case 9: gateway resync without changes",
+			object: &gatewayv1.Gateway{},
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Update(gateway(1, "10.0.0.1"), gateway(1, "10.0.0.1"))
+			},
+		},
+		{
+			name:   "case 10: HTTPRoute deleted",
+			object: &gatewayv1.HTTPRoute{},
+			event: func(informer *controllertest.FakeInformer) {
+				informer.Delete(&gatewayv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "route"}})
+			},
+			expectEvent: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newTestTracker(t, true)
+
+			var clusterCache *informertest.FakeInformers
+			newCache := tracker.newCache
+			tracker.newCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+				c, err := newCache(config, opts)
+				clusterCache = c.(*informertest.FakeInformers)
+				return c, err
+			}
+
+			if _, err := tracker.GetClient(context.Background(), testCluster()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			object := tc.object
+			if object == nil {
+				object = &corev1.Service{}
+			}
+			informer, err := clusterCache.FakeInformerFor(context.Background(), object)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			tc.event(informer)
+
+			req, ok := tracker.waitForRequest(0)
+			if ok && !tc.expectEvent {
+				t.Fatalf("unexpected event for %s", req.NamespacedName)
+			}
+			if !ok && tc.expectEvent {
+				t.Fatalf("expected event")
+			}
+			if ok && req.NamespacedName != client.ObjectKeyFromObject(testCluster()) {
+				t.Fatalf("expected event for the owning cluster, got %s", req.NamespacedName)
+			}
+		})
+	}
+}
+
+func Test_ClusterTracker_EventsDoNotBlock(t *testing.T) {
+	tracker := newTestTracker(t, true)
+
+	var clusterCache *informertest.FakeInformers
+	newCache := tracker.newCache
+	tracker.newCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		c, err := newCache(config, opts)
+		clusterCache = c.(*informertest.FakeInformers)
+		return c, err
+	}
+
+	if _, err := tracker.GetClient(context.Background(), testCluster()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	informer, err := clusterCache.FakeInformerFor(context.Background(), &corev1.Service{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 5000 {
+			informer.Add(service(corev1.ServiceTypeLoadBalancer, fmt.Sprintf("10.0.%d.%d", i/256, i%256)))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected events to be added without blocking the informer")
+	}
+
+	if tracker.queue.Len() != 1 {
+		t.Fatalf("expected the events of the cluster to be coalesced, got %d requests", tracker.queue.Len())
+	}
+}
+
+func Test_ClusterTracker_GetClient(t *testing.T) {
+	ctx := context.Background()
+	cluster := testCluster()
+	key := client.ObjectKeyFromObject(cluster)
+
+	tracker := newTestTracker(t, true)
+
+	for range 2 {
+		if _, err := tracker.GetClient(ctx, cluster); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if tracker.caches != 1 {
+		t.Fatalf("expected the client to be shared, got %d caches", tracker.caches)
+	}
+
+	tracker.Stop(key)
+	if tracker.Tracking(key) {
+		t.Fatalf("expected the cluster not to be tracked after stopping")
+	}
+
+	if _, err := tracker.GetClient(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tracker.caches != 2 {
+		t.Fatalf("expected a new client after stopping, got %d caches", tracker.caches)
+	}
+}
+
+func Test_ClusterTracker_KubeconfigChanged(t *testing.T) {
+	ctx := context.Background()
+	cluster := testCluster()
+
+	tracker := newTestTracker(t, true)
+
+	if _, err := tracker.GetClient(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tracker.secrets.Update(ctx, kubeconfigSecret("rotated")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tracker.GetClient(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tracker.caches != 2 {
+		t.Fatalf("expected the client to be rebuilt after the kubeconfig changed, got %d caches", tracker.caches)
+	}
+}
+
+func Test_ClusterTracker_NamespacesChanged(t *testing.T) {
+	ctx := context.Background()
+	cluster := testCluster()
+
+	tracker := newTestTracker(t, true)
+	tracker.clusterNamespaces = func(cluster *capi.Cluster) []string {
+		return strings.Split(cluster.Annotations["namespaces"], ",")
+	}
+	var namespaces []string
+	newCache := tracker.newCache
+	tracker.newCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		namespaces = slices.Sorted(maps.Keys(opts.DefaultNamespaces))
+		return newCache(config, opts)
+	}
+
+	cluster.Annotations = map[string]string{"namespaces": "traefik,kube-system"}
+	for range 2 {
+		if _, err := tracker.GetClient(ctx, cluster); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if tracker.caches != 1 || !slices.Equal(namespaces, []string{"kube-system", "traefik"}) {
+		t.Fatalf("expected one cache of kube-system and traefik, got %d caches of %v", tracker.caches, namespaces)
+	}
+
+	cluster.Annotations = map[string]string{"namespaces": "traefik"}
+	if _, err := tracker.GetClient(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tracker.caches != 2 || !slices.Equal(namespaces, []string{"traefik"}) {
+		t.Fatalf("expected the client to be rebuilt for traefik, got %d caches of %v", tracker.caches, namespaces)
+	}
+}
+
+func Test_ClusterTracker_Unhealthy(t *testing.T) {
+	cluster := testCluster()
+	key := client.ObjectKeyFromObject(cluster)
+
+	tracker := newTestTracker(t, true)
+	tracker.healthCheckInterval = time.Millisecond
+	tracker.healthy.Store(false)
+
+	if _, err := tracker.GetClient(context.Background(), cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, ok := tracker.waitForRequest(5 * time.Second)
+	if !ok {
+		t.Fatalf("expected event after failed health checks")
+	}
+	if req.NamespacedName != key {
+		t.Fatalf("expected event for the owning cluster, got %s", req.NamespacedName)
+	}
+
+	if tracker.Tracking(key) {
+		t.Fatalf("expected the client of the unhealthy cluster to be invalidated")
+	}
+}
+
+func Test_ClusterTracker_NotSynced(t *testing.T) {
+	cluster := testCluster()
+	tracker := newTestTracker(t, false)
+
+	_, err := tracker.GetClient(context.Background(), cluster)
+	if !IsCacheNotSynced(err) {
+		t.Fatalf("expected cache not synced error, got %v", err)
+	}
+	if tracker.Tracking(client.ObjectKeyFromObject(cluster)) {
+		t.Fatalf("expected unsynced cache not to be kept")
+	}
+}
+
+// blockingCache is a cache whose sync blocks until release is closed.
+type blockingCache struct {
+	*informertest.FakeInformers
+	syncing chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingCache) WaitForCacheSync(ctx context.Context) bool {
+	close(c.syncing)
+	select {
+	case <-c.release:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func Test_ClusterTracker_SyncDoesNotBlockOtherClusters(t *testing.T) {
+	ctx := context.Background()
+	blocked := testCluster()
+	other := &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "org-giantswarm", Name: "other"}}
+
+	tracker := newTestTracker(t, true)
+	otherSecret := kubeconfigSecret("token")
+	otherSecret.Name = "other-kubeconfig"
+	if err := tracker.secrets.Create(ctx, otherSecret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	synced := true
+	blocking := &blockingCache{
+		FakeInformers: &informertest.FakeInformers{Scheme: Scheme, Synced: &synced},
+		syncing:       make(chan struct{}),
+		release:       make(chan struct{}),
+	}
+	newCache := tracker.newCache
+	var caches atomic.Int32
+	tracker.newCache = func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		if caches.Add(1) == 1 {
+			return blocking, nil
+		}
+		return newCache(config, opts)
+	}
+
+	blockedErr := make(chan error, 1)
+	go func() {
+		_, err := tracker.GetClient(ctx, blocked)
+		blockedErr <- err
+	}()
+	<-blocking.syncing
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tracker.GetClient(ctx, other)
+		tracker.Stop(client.ObjectKeyFromObject(blocked))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the syncing cluster not to block other clusters")
+	}
+
+	// The cluster was stopped while its cache was syncing, so its client is not tracked.
+	close(blocking.release)
+	if err := <-blockedErr; err == nil {
+		t.Fatalf("expected error for the stopped cluster")
+	}
+	if tracker.Tracking(client.ObjectKeyFromObject(blocked)) {
+		t.Fatalf("expected the stopped cluster not to be tracked")
+	}
+	if !tracker.Tracking(client.ObjectKeyFromObject(other)) {
+		t.Fatalf("expected the other cluster to be tracked")
+	}
+}